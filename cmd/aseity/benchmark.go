@@ -56,14 +56,20 @@ func cmdBenchmark(datasetPath string, judgeModel string) {
 	fmt.Printf("  Judge: %s/%s\n\n", judgeProvName, judgeModel)
 
 	// Initialize Provider for Agent
-	agentProv, err := makeProvider(cfg, agentProvName, agentModelName)
+	agentProv, err := newSessionProvider(cfg, agentProvName, agentModelName)
 	if err != nil {
 		fatal("failed to create agent provider: %s", err)
 	}
 	agentProv = provider.WithRetry(agentProv, 3)
 
 	// Initialize Provider for Judge
-	judgeProv, err := makeProvider(cfg, judgeProvName, judgeModel)
+	var judgeProv provider.Provider
+	if replayPath != "" {
+		// The judge's verdicts are in the same cassette as the agent's turns.
+		judgeProv, err = replayProvider()
+	} else {
+		judgeProv, err = makeProvider(cfg, judgeProvName, judgeModel)
+	}
 	if err != nil {
 		// Fallback to agent provider if judge model not found specifically?
 		// Or try to use same provider with different model name?
@@ -75,21 +81,24 @@ func cmdBenchmark(datasetPath string, judgeModel string) {
 		// For simplicity, assume judge uses same provider type/credentials as agent default, just different model name.
 		// Refactoring makeProvider to override model name would be cleaner, but we can do it manually here.
 		pcfg, _ := cfg.ProviderFor(judgeProvName)
-		if pcfg.Type == "ollama" || pcfg.Type == "openai" {
-			// Ollama uses OpenAI-compatible API
-			baseURL := pcfg.BaseURL
-			if baseURL == "" && pcfg.Type == "ollama" {
-				baseURL = "http://localhost:11434/v1"
-			}
-			judgeProv = provider.NewOpenAI(judgeProvName, baseURL, pcfg.APIKey, judgeModel)
+		if pcfg.Type == "ollama" {
+			judgeProv = provider.NewOllama(judgeProvName, pcfg.BaseURL, judgeModel, provider.OllamaOptions{
+				NumCtx:    pcfg.NumCtx,
+				KeepAlive: pcfg.KeepAlive,
+			})
+		} else if pcfg.Type == "openai" {
+			judgeProv = provider.NewOpenAIWithOptions(judgeProvName, pcfg.BaseURL, pcfg.APIKey, judgeModel, openAIOptions(pcfg))
 		} else if pcfg.Type == "anthropic" {
-			judgeProv = provider.NewAnthropic(pcfg.APIKey, judgeModel)
+			judgeProv = provider.NewAnthropic(pcfg.APIKey, judgeModel, anthropicOptions(pcfg))
 		} else if pcfg.Type == "google" {
-			judgeProv = provider.NewGoogle(pcfg.APIKey, judgeModel)
+			judgeProv = provider.NewGoogle(pcfg.APIKey, judgeModel, googleOptions(pcfg))
 		} else {
 			fatal("Unsupported provider for judge fallback")
 		}
 	}
+	if judgeProv, err = recordProvider(judgeProv); err != nil {
+		fatal("%s", err)
+	}
 
 	// Setup Tools
 	toolReg := tools.NewRegistry(nil, true) // Auto-approve all for benchmark
@@ -216,13 +225,16 @@ Output JSON only:
   "reasoning": "Short explanation"
 }`, query, trajectory, finalResponse)
 
-	ch, err := prov.Chat(ctx, []provider.Message{{Role: "user", Content: prompt}}, nil)
+	ch, err := prov.Chat(ctx, []provider.Message{{Role: "user", Content: prompt}}, nil, provider.WithTemperature(0))
 	if err != nil {
 		return "N/A", "Judge Error: " + err.Error()
 	}
 
 	var builder strings.Builder
 	for chunk := range ch {
+		if chunk.Restart != nil {
+			builder.Reset()
+		}
 		builder.WriteString(chunk.Delta)
 	}
 
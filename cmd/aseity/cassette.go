@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jeanpaul/aseity/internal/config"
+	"github.com/jeanpaul/aseity/internal/provider"
+)
+
+// Record/replay settings from --record, --replay and --replay-mode.
+var (
+	recordPath string
+	replayPath string
+	replayMode string
+
+	cassetteWriter *provider.CassetteWriter
+	cassetteReplay *provider.Replayer
+)
+
+// newSessionProvider builds the provider for a session, replaying from or
+// recording to a cassette when requested. Replay needs no configured backend.
+func newSessionProvider(cfg *config.Config, name, modelName string) (provider.Provider, error) {
+	if replayPath != "" {
+		return replayProvider()
+	}
+	prov, err := makeRoutedProvider(cfg, name, modelName)
+	if err != nil {
+		return nil, err
+	}
+	return recordProvider(prov)
+}
+
+// replayProvider returns the replayer for --replay. It is shared so that the
+// agent and judge in a benchmark run draw from the same cassette.
+func replayProvider() (provider.Provider, error) {
+	if cassetteReplay != nil {
+		return cassetteReplay, nil
+	}
+	mode := provider.MatchLenient
+	switch replayMode {
+	case "", "lenient":
+	case "strict":
+		mode = provider.MatchStrict
+	default:
+		return nil, fmt.Errorf("unknown replay mode %q (must be strict or lenient)", replayMode)
+	}
+	r, err := provider.LoadCassette(replayPath, mode)
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	cassetteReplay = r
+	return r, nil
+}
+
+// recordProvider wraps prov with a Recorder when --record is set.
+func recordProvider(prov provider.Provider) (provider.Provider, error) {
+	if recordPath == "" {
+		return prov, nil
+	}
+	if cassetteWriter == nil {
+		w, err := provider.CreateCassette(recordPath)
+		if err != nil {
+			return nil, fmt.Errorf("record: %w", err)
+		}
+		cassetteWriter = w
+	}
+	return provider.NewRecorder(prov, cassetteWriter), nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jeanpaul/aseity/internal/checkpoint"
+	"github.com/jeanpaul/aseity/internal/tui"
+)
+
+// cmdCheckpoints lists the sessions with file checkpoints, lists one
+// session's turns, or restores a session's files to before a turn:
+//
+//	aseity checkpoints
+//	aseity checkpoints <session|last>
+//	aseity checkpoints <session|last> restore <turn>
+func cmdCheckpoints(args []string) {
+	sessions, err := checkpoint.Sessions()
+	if err != nil {
+		fatal("%s", err)
+	}
+	if len(sessions) == 0 {
+		fatal("no checkpoints yet; they are taken when the agent changes files")
+	}
+	if len(args) == 0 {
+		listCheckpointSessions(sessions)
+		return
+	}
+
+	id := args[0]
+	if id == "last" {
+		id = sessions[0].ID
+	}
+	store := checkpoint.Open(filepath.Base(id))
+	turns, err := store.Turns()
+	if err != nil {
+		fatal("%s", err)
+	}
+	if len(turns) == 0 {
+		fatal("no checkpoints for session %q (run 'aseity checkpoints' to list them)", id)
+	}
+
+	if len(args) == 1 {
+		listCheckpointTurns(id, turns)
+		return
+	}
+	if len(args) != 3 || args[1] != "restore" {
+		fatal("usage: aseity checkpoints <session|last> restore <turn>")
+	}
+	turn, err := strconv.Atoi(args[2])
+	if err != nil {
+		fatal("turn must be a number: %s", args[2])
+	}
+	paths, err := store.Restore(turn)
+	if err != nil {
+		fatal("%s", err)
+	}
+	fmt.Println(tui.BannerStyle.Render(fmt.Sprintf("  ✓ Restored %d file(s) to before turn %d", len(paths), turn)))
+	for _, p := range paths {
+		fmt.Printf("    %s\n", p)
+	}
+}
+
+func listCheckpointSessions(sessions []checkpoint.Session) {
+	const shown = 20
+	if len(sessions) > shown {
+		sessions = sessions[:shown]
+	}
+	fmt.Println(tui.BannerStyle.Render("  File Checkpoints"))
+	fmt.Println()
+	for _, s := range sessions {
+		turns, _ := checkpoint.Open(s.ID).Turns()
+		files := map[string]bool{}
+		for _, t := range turns {
+			for _, f := range t.Files {
+				files[f] = true
+			}
+		}
+		fmt.Printf("  %s  %s  %s\n",
+			tui.UserLabelStyle.Render(s.ID),
+			s.Time.Format("2006-01-02 15:04"),
+			tui.HelpStyle.Render(fmt.Sprintf("%d turn(s), %d file(s)", len(turns), len(files))),
+		)
+	}
+	fmt.Println()
+	fmt.Println(tui.HelpStyle.Render("  aseity checkpoints <session|last> to show its turns"))
+}
+
+func listCheckpointTurns(id string, turns []checkpoint.Turn) {
+	fmt.Println(tui.BannerStyle.Render("  Checkpoints for session " + id))
+	fmt.Println()
+	for _, t := range turns {
+		fmt.Printf("  %s  %s  %s\n",
+			tui.UserLabelStyle.Render(fmt.Sprintf("turn %d", t.N)),
+			t.Time.Format("15:04:05"),
+			tui.HelpStyle.Render(fmt.Sprintf("%d file(s)", len(t.Files))),
+		)
+		for _, f := range t.Files {
+			fmt.Printf("    %s\n", tui.ToolCallStyle.Render(shortPath(f)))
+		}
+	}
+	fmt.Println()
+	fmt.Println(tui.HelpStyle.Render("  aseity checkpoints " + id + " restore <turn> puts files back as they were before that turn"))
+}
+
+// shortPath shows p relative to the working directory when it is inside it.
+func shortPath(p string) string {
+	cwd, _ := os.Getwd()
+	if rel, err := filepath.Rel(cwd, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return p
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net"
+	"net/url"
+
+	"github.com/jeanpaul/aseity/internal/config"
+	"github.com/jeanpaul/aseity/internal/pricing"
+)
+
+// sessionCosts tracks the usage and cost of every model call in this run.
+var sessionCosts *pricing.Tracker
+
+// newPriceRegistry builds the price table: built-in list prices, local
+// providers free, then the pricing entries from config.
+func newPriceRegistry(cfg *config.Config) *pricing.Registry {
+	reg := pricing.NewRegistry()
+	for name, pc := range cfg.Providers {
+		if pc.Type == "ollama" || isLocalURL(pc.BaseURL) {
+			reg.SetProvider(name, pricing.Price{})
+		}
+	}
+	for _, pc := range cfg.Pricing {
+		price := pricing.Price{Input: pc.Input, CachedInput: pc.CachedInput, CacheWrite: pc.CacheWrite, Output: pc.Output}
+		if pc.Model == "" {
+			reg.SetProvider(pc.Provider, price)
+		} else {
+			reg.SetModel(pc.Provider, pc.Model, price)
+		}
+	}
+	return reg
+}
+
+func isLocalURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeanpaul/aseity/internal/config"
+	"github.com/jeanpaul/aseity/internal/provider"
+	"github.com/jeanpaul/aseity/internal/tui"
+)
+
+// debugProvider is set by --debug-provider.
+var debugProvider bool
+
+// newDebugLog builds the provider debug log for a session. It redacts every
+// configured API key and header value as well as the configured patterns,
+// and starts enabled when asked for by flag or config; /debug toggles it.
+func newDebugLog(cfg *config.Config) *provider.DebugLog {
+	secrets := []string{os.Getenv("ASEITY_API_KEY")}
+	for _, p := range cfg.Providers {
+		secrets = append(secrets, p.APIKey)
+		for _, v := range p.Headers {
+			secrets = append(secrets, v)
+		}
+	}
+	var patterns []*regexp.Regexp
+	for _, pat := range cfg.Debug.RedactPatterns {
+		patterns = append(patterns, regexp.MustCompile(pat)) // Checked by config.Validate
+	}
+	log := provider.NewDebugLog(provider.DebugLogOptions{Secrets: secrets, Patterns: patterns})
+	log.SetEnabled(debugProvider || cfg.Debug.ProviderLog)
+	return log
+}
+
+// cmdLogs lists recent debug log entries, or pretty-prints one: by full ID,
+// by its number in the latest session, or "last".
+func cmdLogs(args []string) {
+	full := false
+	var ref string
+	for _, a := range args {
+		if a == "--full" {
+			full = true
+		} else {
+			ref = a
+		}
+	}
+
+	entries, err := provider.ReadDebugLog(provider.DefaultDebugLogDir())
+	if err != nil {
+		fatal("%s", err)
+	}
+	if len(entries) == 0 {
+		fatal("the provider debug log is empty")
+	}
+	if ref == "" {
+		listDebugEntries(entries)
+		return
+	}
+	e, ok := findDebugEntry(entries, ref)
+	if !ok {
+		fatal("no logged call %q (run 'aseity logs' to list them)", ref)
+	}
+	printDebugEntry(e, full)
+}
+
+func findDebugEntry(entries []provider.DebugEntry, ref string) (provider.DebugEntry, bool) {
+	last := entries[len(entries)-1]
+	if ref == "last" {
+		return last, true
+	}
+	// A bare number refers to a call in the most recent session.
+	if _, err := strconv.Atoi(ref); err == nil {
+		session := last.ID[:strings.LastIndex(last.ID, "-")]
+		ref = session + "-" + ref
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == ref {
+			return entries[i], true
+		}
+	}
+	return provider.DebugEntry{}, false
+}
+
+func listDebugEntries(entries []provider.DebugEntry) {
+	const shown = 20
+	if len(entries) > shown {
+		entries = entries[len(entries)-shown:]
+	}
+	fmt.Println(tui.BannerStyle.Render("  Provider Debug Log"))
+	fmt.Println()
+	for _, e := range entries {
+		status := tui.SuccessStyle.Render("ok")
+		if e.Error != "" {
+			status = tui.ErrorStyle.Render("error")
+		}
+		fmt.Printf("  %s  %s  %s  %s  %s  %s\n",
+			tui.UserLabelStyle.Render(e.ID),
+			e.Time.Format("15:04:05"),
+			tui.ToolCallStyle.Render(e.Provider+"/"+e.Model),
+			formatMillis(e.Latency),
+			status,
+			tui.HelpStyle.Render(oneLine(lastUserMessage(e.Messages), 50)),
+		)
+	}
+	fmt.Println()
+	fmt.Println(tui.HelpStyle.Render("  aseity logs <id|n|last> [--full] to show a call"))
+}
+
+func printDebugEntry(e provider.DebugEntry, full bool) {
+	limit := 400
+	if full {
+		limit = -1
+	}
+	fmt.Printf("%s  %s\n", tui.BannerStyle.Render("  Call "+e.ID), e.Time.Format("2006-01-02 15:04:05"))
+	source := e.Source
+	if source == "" {
+		source = "agent"
+	}
+	fmt.Printf("  %s  %s\n", tui.ToolCallStyle.Render(e.Provider+" / "+e.Model), tui.HelpStyle.Render("from "+source))
+	fmt.Printf("  latency %s, first chunk %s", formatMillis(e.Latency), formatMillis(e.FirstByte))
+	if u := e.Usage; u != nil {
+		fmt.Printf(", tokens in %d (cached %d) out %d", u.InputTokens, u.CachedInputTokens, u.OutputTokens)
+	}
+	fmt.Println()
+	if opts := describeOptions(e.Options); opts != "" {
+		fmt.Println("  " + tui.HelpStyle.Render(opts))
+	}
+
+	fmt.Printf("\n%s\n", tui.UserLabelStyle.Render(fmt.Sprintf("  Request: %d messages, %d tools", len(e.Messages), len(e.Tools))))
+	for _, m := range e.Messages {
+		label := string(m.Role)
+		if m.ToolCallID != "" {
+			label += " " + m.ToolCallID
+		}
+		fmt.Printf("  %s %s\n", tui.ToolLabelStyle.Render("["+label+"]"), indent(clip(m.Content, limit)))
+		for _, tc := range m.ToolCalls {
+			fmt.Printf("    → %s %s\n", tc.Name, clip(tc.Args, limit))
+		}
+	}
+
+	fmt.Printf("\n%s\n", tui.UserLabelStyle.Render(fmt.Sprintf("  Response: %d chunks", len(e.Chunks))))
+	for _, span := range debugTimeline(e.Chunks) {
+		fmt.Printf("  %s  %s\n", tui.HelpStyle.Render(fmt.Sprintf("%8s", "+"+formatMillis(span.at))), indent(clip(span.text, limit)))
+	}
+	if e.Error != "" {
+		fmt.Printf("\n  %s\n", tui.ErrorStyle.Render("✗ "+e.Error))
+	}
+}
+
+// timelineSpan is a run of consecutive chunks of one kind, shown as a line.
+type timelineSpan struct {
+	at   int64
+	kind string
+	text string
+}
+
+// debugTimeline merges runs of text, thinking and tool-argument fragments so
+// a response reads as a handful of lines rather than one per token.
+func debugTimeline(chunks []provider.DebugChunk) []timelineSpan {
+	var spans []timelineSpan
+	add := func(at int64, kind, text string, merge bool) {
+		if n := len(spans); merge && n > 0 && spans[n-1].kind == kind {
+			spans[n-1].text += text
+			return
+		}
+		spans = append(spans, timelineSpan{at: at, kind: kind, text: text})
+	}
+	for _, c := range chunks {
+		if c.Route != nil {
+			add(c.At, "route", "routed to "+c.Route.String(), false)
+		}
+		if c.Restart != "" {
+			add(c.At, "restart", "restarted, "+c.Restart, false)
+		}
+		if c.Thinking != "" {
+			add(c.At, "thinking", c.Thinking, true)
+		}
+		if c.Delta != "" {
+			add(c.At, "text", c.Delta, true)
+		}
+		if d := c.ToolCallDelta; d != nil {
+			if d.Name != "" {
+				add(c.At, "tool", "tool call "+d.Name+" ", false)
+			}
+			add(c.At, "tool", d.Args, true)
+		}
+		if c.Error != "" {
+			add(c.At, "error", "error: "+c.Error, false)
+		}
+		if c.Done {
+			text := "done"
+			for _, tc := range c.ToolCalls {
+				text += fmt.Sprintf("\n→ %s %s", tc.Name, tc.Args)
+			}
+			add(c.At, "done", text, false)
+		}
+	}
+	for i := range spans {
+		switch spans[i].kind {
+		case "thinking":
+			spans[i].text = "thinking: " + spans[i].text
+		case "text":
+			spans[i].text = "text: " + spans[i].text
+		}
+	}
+	return spans
+}
+
+func describeOptions(o provider.ChatOptions) string {
+	var parts []string
+	if o.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature=%g", *o.Temperature))
+	}
+	if o.TopP != nil {
+		parts = append(parts, fmt.Sprintf("top_p=%g", *o.TopP))
+	}
+	if o.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("max_tokens=%d", o.MaxTokens))
+	}
+	if o.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed=%d", *o.Seed))
+	}
+	if len(o.Stop) > 0 {
+		parts = append(parts, fmt.Sprintf("stop=%q", o.Stop))
+	}
+	if o.ResponseFormat != nil {
+		parts = append(parts, "response_format="+o.ResponseFormat.Name)
+	}
+	return strings.Join(parts, " ")
+}
+
+func lastUserMessage(msgs []provider.Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == provider.RoleUser {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
+
+func formatMillis(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// clip shortens s to n bytes; n < 0 keeps it whole.
+func clip(s string, n int) string {
+	if n < 0 || len(s) <= n {
+		return s
+	}
+	return s[:n] + fmt.Sprintf("… (%d more bytes)", len(s)-n)
+}
+
+func oneLine(s string, n int) string {
+	return clip(strings.Join(strings.Fields(s), " "), n)
+}
+
+func indent(s string) string {
+	return strings.ReplaceAll(s, "\n", "\n      ")
+}
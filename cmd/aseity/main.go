@@ -23,6 +23,7 @@ import (
 	"github.com/jeanpaul/aseity/internal/headless"
 	"github.com/jeanpaul/aseity/internal/health"
 	"github.com/jeanpaul/aseity/internal/model"
+	"github.com/jeanpaul/aseity/internal/pricing"
 	"github.com/jeanpaul/aseity/internal/provider"
 	"github.com/jeanpaul/aseity/internal/setup"
 	"github.com/jeanpaul/aseity/internal/tools"
@@ -52,9 +53,19 @@ func main() {
 	parallelFlag := flag.Bool("parallel", false, "Enable parallel execution in orchestrator")
 	deepResearchFlag := flag.Bool("deep-research", false, "Enable Deep Research mode (forces deep analysis)")
 
+	// Record/replay flags
+	flag.StringVar(&recordPath, "record", "", "Record every model request and response to a cassette file")
+	flag.StringVar(&replayPath, "replay", "", "Serve model responses from a recorded cassette instead of a provider")
+	flag.StringVar(&replayMode, "replay-mode", "lenient", "Replay matching: strict (exact requests) or lenient")
+	flag.BoolVar(&debugProvider, "debug-provider", false, "Log every model request and response to ~/.config/aseity/logs")
+
 	flag.Usage = showHelp
 	flag.Parse()
 
+	if recordPath != "" && replayPath != "" {
+		fatal("--record and --replay cannot be used together")
+	}
+
 	if *helpFlag {
 		showHelp()
 		os.Exit(0)
@@ -117,6 +128,19 @@ func main() {
 		case "doctor":
 			cmdDoctor()
 			return
+		case "logs":
+			cmdLogs(args[1:])
+			return
+		case "checkpoints":
+			cmdCheckpoints(args[1:])
+			return
+		case "probe":
+			model := *modelFlag
+			if len(args) > 1 {
+				model = args[1]
+			}
+			cmdProbe(*providerFlag, model)
+			return
 		case "setup":
 			docker := len(args) > 1 && args[1] == "--docker"
 			cmdSetup(docker)
@@ -179,7 +203,7 @@ func main() {
 	// Check if model is available (for ollama provider)
 	// We do this BEFORE headless check so it works for orchestrator/headless modes too
 	pcfg, _ := cfg.ProviderFor(provName)
-	if provName == "ollama" || (pcfg.Type == "openai" && strings.Contains(pcfg.BaseURL, "11434")) {
+	if replayPath == "" && (provName == "ollama" || pcfg.Type == "ollama" || (pcfg.Type == "openai" && strings.Contains(pcfg.BaseURL, "11434"))) {
 		if !setup.IsModelAvailable(modelName) {
 			fmt.Printf("  %s\n", tui.SpinnerStyle.Render("● Model "+modelName+" not found, pulling..."))
 			if err := setup.PullModel(modelName); err != nil {
@@ -198,11 +222,14 @@ func main() {
 
 		// ... (Health check logic, same as before) ...
 		fmt.Printf("  %s", tui.SpinnerStyle.Render("● Checking provider connectivity..."))
-		status := health.Check(context.Background(), pcfg.Type, pcfg.BaseURL, pcfg.APIKey)
+		status := health.Status{Reachable: true} // Replay needs no backend
+		if replayPath == "" {
+			status = checkProvider(pcfg)
+		}
 		if !status.Reachable {
 			fmt.Printf("\r  %s\n", tui.ErrorStyle.Render("✗ "+status.Error))
 			if setup.RunSetup(provName, modelName) {
-				status = health.Check(context.Background(), pcfg.Type, pcfg.BaseURL, pcfg.APIKey)
+				status = checkProvider(pcfg)
 			}
 			if !status.Reachable {
 				fmt.Printf("  %s\n\n", tui.HelpStyle.Render("Run 'aseity doctor' for diagnostics"))
@@ -215,6 +242,8 @@ func main() {
 
 		// (Model check removed from here as it's done above)
 
+		autoProbe(cfg, provName, modelName)
+
 		// ... End TUI Health Checks
 		fmt.Println()
 
@@ -237,6 +266,33 @@ func makeProvider(cfg *config.Config, name, modelName string) (provider.Provider
 		return nil, fmt.Errorf("unknown provider %q — configure it in ~/.config/aseity/config.yaml", name)
 	}
 
+	prov, err := newProvider(name, pcfg, modelName)
+	if err != nil {
+		return nil, err
+	}
+	// Limits are keyed by provider name, so every agent, sub-agent and
+	// helper built for this provider shares one budget.
+	limit := provider.RateLimit{
+		RequestsPerMinute: pcfg.RequestsPerMinute,
+		TokensPerMinute:   pcfg.TokensPerMinute,
+		MaxInFlight:       pcfg.MaxInFlight,
+	}
+	if limit.Enabled() {
+		prov = provider.WithRateLimit(prov, provider.SharedLimiter(name, limit))
+	}
+	// Outside the limiter, so calls to a dead backend fail fast instead of
+	// queueing for a slot first.
+	if !cfg.CircuitBreaker.Disabled {
+		prov = provider.WithBreaker(prov, provider.SharedBreaker(name, provider.BreakerConfig{
+			Failures: cfg.CircuitBreaker.Failures,
+			Cooldown: cfg.CircuitBreaker.Cooldown,
+		}))
+	}
+	return prov, nil
+}
+
+// newProvider builds the backend for one provider config entry.
+func newProvider(name string, pcfg config.ProviderConfig, modelName string) (provider.Provider, error) {
 	model := modelName
 	if model == "" {
 		model = pcfg.Model
@@ -244,22 +300,100 @@ func makeProvider(cfg *config.Config, name, modelName string) (provider.Provider
 
 	switch pcfg.Type {
 	case "openai":
-		return provider.NewOpenAI(name, pcfg.BaseURL, pcfg.APIKey, model), nil
+		return provider.NewOpenAIWithOptions(name, pcfg.BaseURL, pcfg.APIKey, model, openAIOptions(pcfg)), nil
+	case "ollama":
+		return provider.NewOllama(name, pcfg.BaseURL, model, provider.OllamaOptions{
+			NumCtx:      pcfg.NumCtx,
+			Temperature: pcfg.Temperature,
+			KeepAlive:   pcfg.KeepAlive,
+			Think:       pcfg.Think,
+
+			EmbeddingModel: pcfg.EmbeddingModel,
+		}), nil
 	case "anthropic":
 		if pcfg.APIKey == "" {
 			return nil, fmt.Errorf("anthropic requires api_key (set ANTHROPIC_API_KEY)")
 		}
-		return provider.NewAnthropic(pcfg.APIKey, model), nil
+		return provider.NewAnthropic(pcfg.APIKey, model, anthropicOptions(pcfg)), nil
 	case "google":
 		if pcfg.APIKey == "" {
 			return nil, fmt.Errorf("google requires api_key (set GEMINI_API_KEY)")
 		}
-		return provider.NewGoogle(pcfg.APIKey, model), nil
+		return provider.NewGoogle(pcfg.APIKey, model, googleOptions(pcfg)), nil
 	default:
 		return nil, fmt.Errorf("unknown provider type %q", pcfg.Type)
 	}
 }
 
+// checkProvider checks that a provider's endpoint is up. OpenAI-compatible
+// variants and servers needing custom headers are checked through the
+// provider itself, which knows their URLs and authentication.
+func checkProvider(pcfg config.ProviderConfig) health.Status {
+	if pcfg.Type == "openai" && (pcfg.Variant != "" || len(pcfg.Headers) > 0 || len(pcfg.Query) > 0) {
+		p := provider.NewOpenAIWithOptions("health", pcfg.BaseURL, pcfg.APIKey, pcfg.Model, openAIOptions(pcfg))
+		return health.CheckLister(context.Background(), pcfg.BaseURL, p.Models)
+	}
+	return health.Check(context.Background(), pcfg.Type, pcfg.BaseURL, pcfg.APIKey)
+}
+
+func openAIOptions(pcfg config.ProviderConfig) provider.OpenAIOptions {
+	return provider.OpenAIOptions{
+		Variant:    pcfg.Variant,
+		APIVersion: pcfg.APIVersion,
+		Deployment: pcfg.Deployment,
+		Headers:    pcfg.Headers,
+		Query:      pcfg.Query,
+		Extra:      pcfg.Extra,
+
+		EmbeddingModel: pcfg.EmbeddingModel,
+	}
+}
+
+func anthropicOptions(pcfg config.ProviderConfig) provider.AnthropicOptions {
+	return provider.AnthropicOptions{
+		ThinkingBudget:     pcfg.ThinkingBudget,
+		DisablePromptCache: pcfg.PromptCache != nil && !*pcfg.PromptCache,
+		BaseURL:            pcfg.BaseURL,
+	}
+}
+
+func googleOptions(pcfg config.ProviderConfig) provider.GoogleOptions {
+	return provider.GoogleOptions{BaseURL: pcfg.BaseURL, EmbeddingModel: pcfg.EmbeddingModel, ThinkingBudget: pcfg.ThinkingBudget}
+}
+
+// makeRoutedProvider builds the provider for name, wrapped in a RouterProvider
+// when the config declares a fallback chain. The requested provider is always
+// tried first; the model override only applies to it.
+func makeRoutedProvider(cfg *config.Config, name, modelName string) (provider.Provider, error) {
+	primary, err := makeProvider(cfg, name, modelName)
+	if err != nil || len(cfg.Fallback) == 0 || os.Getenv("ASEITY_BASE_URL") != "" {
+		return primary, err
+	}
+
+	routes := []provider.Route{routeFor(cfg, name, primary)}
+	for _, fb := range cfg.Fallback {
+		if fb == name {
+			continue
+		}
+		p, err := makeProvider(cfg, fb, "")
+		if err != nil {
+			return nil, fmt.Errorf("fallback provider %q: %w", fb, err)
+		}
+		routes = append(routes, routeFor(cfg, fb, p))
+	}
+	return provider.NewRouter(routes, cfg.MaxCostTier), nil
+}
+
+func routeFor(cfg *config.Config, name string, p provider.Provider) provider.Route {
+	pcfg, _ := cfg.ProviderFor(name)
+	return provider.Route{
+		Provider:   p,
+		MaxContext: pcfg.MaxContext,
+		NoTools:    pcfg.NoTools,
+		CostTier:   pcfg.CostTier,
+	}
+}
+
 func cmdModels() {
 	cfg, _ := config.Load()
 	ollamaURL := "http://localhost:11434"
@@ -362,9 +496,42 @@ func cmdProviders() {
 			tui.ToolCallStyle.Render(p.Type),
 			tui.HelpStyle.Foreground(status).Render(label),
 		)
+
+		prov, err := makeProvider(cfg, name, "")
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		models, err := provider.ListModels(ctx, prov)
+		cancel()
+		if err != nil {
+			fmt.Println(tui.HelpStyle.Render("      models unavailable: " + err.Error()))
+			continue
+		}
+		for _, m := range models {
+			fmt.Printf("      %s  %s\n", m.ID, tui.HelpStyle.Render(describeModel(m)))
+		}
 	}
 }
 
+// describeModel summarizes a model's reported limits and capabilities.
+func describeModel(m provider.ModelInfo) string {
+	var parts []string
+	if m.ContextWindow > 0 {
+		parts = append(parts, fmt.Sprintf("%dk context", m.ContextWindow/1000))
+	}
+	if m.Vision {
+		parts = append(parts, "vision")
+	}
+	if m.Tools {
+		parts = append(parts, "tools")
+	}
+	if m.Thinking {
+		parts = append(parts, "thinking")
+	}
+	return strings.Join(parts, ", ")
+}
+
 func cmdDoctor() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -377,6 +544,7 @@ func cmdDoctor() {
 
 	defaultOk := true
 	otherIssues := 0
+	breakers, _ := provider.LoadBreakerStatuses()
 
 	for name, pcfg := range cfg.Providers {
 		isDefault := name == cfg.DefaultProvider
@@ -389,7 +557,7 @@ func cmdDoctor() {
 			tui.ToolCallStyle.Render("●"),
 			tui.UserLabelStyle.Render(label),
 		)
-		status := health.Check(context.Background(), pcfg.Type, pcfg.BaseURL, pcfg.APIKey)
+		status := checkProvider(pcfg)
 		if status.Reachable {
 			modelCount := ""
 			if len(status.Models) > 0 {
@@ -409,6 +577,9 @@ func cmdDoctor() {
 				fmt.Printf("%s\n", tui.HelpStyle.Render("- "+status.Error+" (optional)"))
 			}
 		}
+		if b, ok := breakers[name]; ok {
+			printBreakerStatus(b)
+		}
 	}
 
 	// Check Docker
@@ -444,6 +615,24 @@ func cmdDoctor() {
 	}
 }
 
+// printBreakerStatus reports what the circuit breaker for a provider saw in
+// the most recent session.
+func printBreakerStatus(b provider.BreakerStatus) {
+	line := fmt.Sprintf("circuit %s since %s", b.State, b.Since.Format("Jan 2 15:04:05"))
+	if b.Latency > 0 {
+		line += fmt.Sprintf(", avg first chunk %s", b.Latency.Round(time.Millisecond))
+	}
+	if b.State == provider.BreakerClosed {
+		fmt.Println("      " + tui.HelpStyle.Render(line))
+		return
+	}
+	line += fmt.Sprintf(", %d consecutive failures", b.Failures)
+	fmt.Println("      " + tui.ErrorStyle.Render(line))
+	if b.LastError != "" {
+		fmt.Println("      " + tui.HelpStyle.Render("last error: "+oneLine(b.LastError, 100)))
+	}
+}
+
 func cmdTools() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -523,14 +712,22 @@ func launchHeadless(cfg *config.Config, provName, modelName string, allowAll boo
 }
 
 func setupAgentEnv(cfg *config.Config, provName, modelName string, allowAll bool, qualityGate bool) (provider.Provider, *tools.Registry, *agent.AgentManager, error) {
-	prov, err := makeProvider(cfg, provName, modelName)
+	prov, err := newSessionProvider(cfg, provName, modelName)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 	prov = provider.WithRetry(prov, 3)
+	prov = provider.WithDebugLog(prov, newDebugLog(cfg))
+	sessionCosts = pricing.NewTracker(newPriceRegistry(cfg))
+	prov = pricing.Meter(prov, sessionCosts)
 
 	toolReg := tools.NewRegistry(cfg.Tools.AutoApprove, allowAll)
 	tools.RegisterDefaults(toolReg, cfg.Tools.AllowedCommands, cfg.Tools.DisallowedCommands)
+	workspace, err := tools.NewWorkspace(cfg.Tools.Workspace)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	toolReg.SetWorkspace(workspace)
 
 	agentMgr := agent.NewAgentManager(prov, toolReg, 3, qualityGate)
 	toolReg.Register(tools.NewSpawnAgentTool(agentMgr))
@@ -592,7 +789,7 @@ func launchTUI(cfg *config.Config, provName, modelName string, allowAll bool, in
 		}
 	}
 
-	m := tui.NewModel(prov, toolReg, provName, modelName, conv, qualityGate, orchConfig)
+	m := tui.NewModel(prov, toolReg, provName, modelName, conv, qualityGate, orchConfig, sessionCosts)
 
 	// Create program with appropriate options based on terminal capabilities
 	var opts []tea.ProgramOption
@@ -641,9 +838,13 @@ func showHelp() {
   remove <model>              Remove a downloaded model
   search <query>              Search HuggingFace for GGUF models
   search <query>              Search HuggingFace for GGUF models
-  providers                   List configured providers
+  providers                   List configured providers and their models
   tools                       List available tools
   doctor                      Check health of all services
+  logs [id|n|last] [--full]   List or show calls from the provider debug log
+  probe [model]               Measure a model's tool calling and save its profile
+  checkpoints [session] [restore <turn>]
+                              List file checkpoints, or restore files to before a turn
   setup [--docker]            Run first-time setup wizard
   help                        Show this help
 
@@ -654,6 +855,10 @@ func showHelp() {
   --update                    Update to latest version from GitHub
   --version                   Show version
   --yes, -y                   Auto-approve all tool execution (dangerous)
+  --record <file>             Record model traffic to a cassette (for bug reports)
+  --replay <file>             Replay a cassette instead of calling a model
+  --replay-mode <mode>        Cassette matching: lenient (default) or strict
+  --debug-provider            Log model requests and responses (see 'aseity logs')
   --help, -h                  Show this help
 
 ` + tui.UserLabelStyle.Render("EXAMPLES:") + `
@@ -662,6 +867,7 @@ func showHelp() {
   aseity --provider openai    Use OpenAI (requires OPENAI_API_KEY)
   aseity pull deepseek-r1     Download the deepseek-r1 model
   aseity doctor               Check if services are running
+  aseity --replay bug.jsonl   Reproduce a recorded session offline
 
 ` + tui.UserLabelStyle.Render("CHAT COMMANDS:") + `
   /help                       Show available chat commands
@@ -669,6 +875,9 @@ func showHelp() {
   /compact                    Compress conversation to save tokens
   /save [path]                Export conversation to markdown
   /tokens                     Show estimated token usage
+  /debug [on|off]             Toggle the provider debug log
+  /undo                       Restore the files changed by the last turn
+  /rewind [turn]              List turns, or go back to before one (files and chat)
   /quit                       Exit aseity
 
 ` + tui.UserLabelStyle.Render("KEYBOARD SHORTCUTS:") + `
@@ -19,7 +19,7 @@ func launchOrchestrator(cfg *config.Config, provName, modelName, query string, d
 	}
 
 	// Create provider
-	prov, err := makeProvider(cfg, provName, modelName)
+	prov, err := newSessionProvider(cfg, provName, modelName)
 	if err != nil {
 		fatal("failed to create provider: %s", err)
 	}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jeanpaul/aseity/internal/agent/skillsets"
+	"github.com/jeanpaul/aseity/internal/config"
+	"github.com/jeanpaul/aseity/internal/provider"
+	"github.com/jeanpaul/aseity/internal/tui"
+)
+
+// cmdProbe measures a model's tool-calling, JSON and long-context handling
+// and saves the calibrated profile, so sessions with it pick the right
+// prompt strategy.
+func cmdProbe(provName, modelName string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("config error: %s", err)
+	}
+	if provName == "" {
+		provName = cfg.DefaultProvider
+	}
+	if modelName == "" {
+		modelName = cfg.DefaultModel
+	}
+
+	fmt.Println(tui.BannerStyle.Render("  Capability Probe"))
+	fmt.Printf("  %s\n\n", tui.ToolCallStyle.Render(provName+" / "+modelName))
+	fmt.Printf("  %s", tui.SpinnerStyle.Render("● Probing..."))
+	base, o, err := probeModel(cfg, provName, modelName)
+	if err != nil {
+		fmt.Printf("\r  %s\n", tui.ErrorStyle.Render("✗ Probe failed: "+err.Error()))
+		os.Exit(1)
+	}
+	fmt.Printf("\r  %s\n\n", tui.BannerStyle.Render("✓ Probe complete"))
+
+	r := o.Probe
+	row := func(label, value string) {
+		fmt.Printf("  %-22s %s\n", tui.UserLabelStyle.Render(label), value)
+	}
+	row("native tool calls", percent(r.NativeTools))
+	row("text tool calls", percent(r.TextTools))
+	row("JSON output", percent(r.JSON))
+	recall := fmt.Sprintf("recalled at %d tokens", r.Context)
+	if r.RecallFail > 0 {
+		recall += fmt.Sprintf(", missed at %d", r.RecallFail)
+	}
+	if r.ContextFail > 0 {
+		recall += fmt.Sprintf(", rejected as too long at %d", r.ContextFail)
+	}
+	row("long context", recall)
+
+	fmt.Println()
+	row("tier", fmt.Sprintf("%d → %d", base.Tier, *o.Tier))
+	row("prompt strategy", fmt.Sprintf("%s → %s", base.PromptStrategy, *o.PromptStrategy))
+	row("native FC", fmt.Sprintf("%v → %v", base.SupportsNativeFC, *o.SupportsNativeFC))
+	if o.MaxTokens != nil {
+		row("max tokens", fmt.Sprintf("%d → %d", base.MaxTokens, *o.MaxTokens))
+	}
+	fmt.Printf("\n  %s\n", tui.HelpStyle.Render("Saved to "+skillsets.GetConfigPath()))
+}
+
+// autoProbe offers to probe a model the first time it is used when nothing
+// is known about it. Only local providers are probed, since the prompts run
+// to about 40k tokens. A declined or failed probe is saved so it isn't
+// offered again; the session goes ahead on the name-based guess.
+func autoProbe(cfg *config.Config, provName, modelName string) {
+	if cfg.Probe.Auto == "never" || replayPath != "" || modelName == "" || !isLocalProvider(cfg, provName) {
+		return
+	}
+	userConfig, err := skillsets.LoadUserConfig()
+	if err != nil || !skillsets.NeedsProbe(userConfig, modelName) {
+		return
+	}
+	if cfg.Probe.Auto != "always" {
+		fmt.Printf("  %s ", tui.HelpStyle.Render("New model: probe its tool calling now? It takes a minute or two. (y/N):"))
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" {
+			skillsets.SaveProbeFailure(modelName, provName, "declined; run 'aseity probe' to calibrate")
+			return
+		}
+	}
+	fmt.Printf("  %s", tui.SpinnerStyle.Render("● Probing "+modelName+"..."))
+	_, o, err := probeModel(cfg, provName, modelName)
+	if err != nil {
+		skillsets.SaveProbeFailure(modelName, provName, err.Error())
+		fmt.Printf("\r  %s\n", tui.HelpStyle.Render("- Capability probe failed ("+err.Error()+"); using defaults"))
+		return
+	}
+	fmt.Printf("\r  %s\n", tui.BannerStyle.Render(fmt.Sprintf("✓ Calibrated: tier %d, %s prompts, native tools %v",
+		*o.Tier, *o.PromptStrategy, *o.SupportsNativeFC)))
+}
+
+// isLocalProvider reports whether provName runs on this machine or network,
+// where probing costs nothing.
+func isLocalProvider(cfg *config.Config, provName string) bool {
+	if baseURL := os.Getenv("ASEITY_BASE_URL"); baseURL != "" {
+		return isLocalURL(baseURL)
+	}
+	pcfg, ok := cfg.ProviderFor(provName)
+	return ok && (pcfg.Type == "ollama" || isLocalURL(pcfg.BaseURL))
+}
+
+// probeModel runs the probe against a fresh provider and saves the result.
+// It returns the profile the model had before and the saved override.
+func probeModel(cfg *config.Config, provName, modelName string) (skillsets.ModelProfile, skillsets.ProfileOverride, error) {
+	prov, err := makeProvider(cfg, provName, modelName)
+	if err != nil {
+		return skillsets.ModelProfile{}, skillsets.ProfileOverride{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	base := skillsets.DetectModelProfile(modelName)
+	if info, ok := provider.DescribeModel(ctx, prov, modelName); ok {
+		base = skillsets.DetectModelProfileWithInfo(info)
+	}
+	r, err := skillsets.ProbeModel(ctx, prov, base.MaxTokens)
+	if err != nil {
+		return base, skillsets.ProfileOverride{}, err
+	}
+	o := r.Calibrate(base)
+	if err := skillsets.SaveProbe(modelName, o); err != nil {
+		return base, o, err
+	}
+	return base, o, nil
+}
+
+func percent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+}
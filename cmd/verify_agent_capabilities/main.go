@@ -24,7 +24,7 @@ func (m *MockProvider) Models(ctx context.Context) ([]string, error) {
 	return []string{"mock-model"}, nil
 }
 
-func (m *MockProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 
 	go func() {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
 	"strings"
@@ -11,14 +12,19 @@ import (
 	"time"
 
 	"github.com/jeanpaul/aseity/internal/agent/skillsets"
+	"github.com/jeanpaul/aseity/internal/checkpoint"
 	"github.com/jeanpaul/aseity/internal/memory"
 	"github.com/jeanpaul/aseity/internal/provider"
+	"github.com/jeanpaul/aseity/internal/tokenizer"
 	"github.com/jeanpaul/aseity/internal/tools"
 )
 
 const MaxTurns = 50             // prevent infinite agent loops
 const MaxQualityGateRetries = 3 // prevent quality gate retry exhaustion
 
+// DefaultChatOptions are the generation settings for the main conversational loop.
+var DefaultChatOptions = []provider.ChatOption{provider.WithTemperature(0.7)}
+
 type Event struct {
 	Type     EventType
 	Text     string
@@ -29,7 +35,14 @@ type Event struct {
 	Data     any // Structured data from tool result
 	Error    string
 	Done     bool
-	Usage    *provider.Usage // Token usage for the response
+	Usage    *provider.Usage     // Token usage for the response
+	Route    *provider.RouteInfo // Backend that served the turn (EventRoute)
+	Outside  []string            // Paths outside the workspace the call needs approved (EventConfirmRequest)
+
+	// ToolIndex is the call's position among the response's tool calls
+	// (EventToolCallDelta, EventToolCall, and the EventError rejecting a
+	// call). Unlike ToolID it is known from the first fragment.
+	ToolIndex int
 }
 
 type EventType int
@@ -45,6 +58,15 @@ const (
 	EventDone
 	EventError
 	EventJudgeCall // new event for quality gate evaluation
+	EventRoute     // sent when a router picks the backend for a turn
+	EventRetry     // sent when a stream failed mid-way and the turn is being reissued
+
+	// EventToolCallDelta carries a fragment of a tool call's arguments while
+	// the model is still writing them. ToolIndex identifies the call on
+	// every fragment, with ToolID and ToolName once the provider has sent
+	// them; ToolArgs holds only the new text. The complete call
+	// follows as EventToolCall once the response ends.
+	EventToolCallDelta
 )
 
 // Agent drives the think-act-observe loop.
@@ -60,6 +82,8 @@ type Agent struct {
 	QualityGateEnabled bool   // Enforce strict judge check before completion
 	OriginalGoal       string // Track the initial user request for judging
 
+	ChatOptions []provider.ChatOption // Generation settings for the main loop
+
 	validator *Validator // New Validator component
 
 	// Skillset framework
@@ -74,6 +98,8 @@ type Agent struct {
 	// Memory Systems (Claude Code Replication)
 	projectContext *memory.ProjectContext
 	autoMemory     memory.Store
+
+	checkpoints *checkpoint.Store // Pre-images of the files each turn changed
 }
 
 // OrchestratorConfig holds orchestrator settings
@@ -107,16 +133,7 @@ func New(prov provider.Provider, registry *tools.Registry, systemPrompt string)
 
 	// Detect model capabilities
 	modelName := prov.ModelName()
-	defaultProfiles := skillsets.DefaultProfiles()
-	mergedProfiles := skillsets.MergeProfiles(defaultProfiles, userConfig)
-
-	var profile skillsets.ModelProfile
-	if p, ok := mergedProfiles[modelName]; ok {
-		profile = p
-	} else {
-		// Fallback to detection
-		profile = skillsets.DetectModelProfile(modelName)
-	}
+	profile := resolveProfile(prov, userConfig, modelName)
 
 	conv := NewConversation()
 	if systemPrompt == "" {
@@ -145,6 +162,7 @@ func New(prov provider.Provider, registry *tools.Registry, systemPrompt string)
 	cwd, _ := os.Getwd()
 	projCtx, _ := memory.LoadProjectContext(cwd) // Pre-load to checking existence logic or initial state
 
+	conv.SetTokenizer(tokenizer.ForModel(modelName))
 	conv.AddSystem(systemPrompt)
 
 	// CRITICAL: Set the conversation context limit to match the model's capabilities.
@@ -165,7 +183,47 @@ func New(prov provider.Provider, registry *tools.Registry, systemPrompt string)
 		userConfig:     userConfig,
 		projectContext: projCtx,
 		autoMemory:     autoMem,
+		ChatOptions:    DefaultChatOptions,
+		checkpoints:    checkpoint.Open(conv.SessionID()),
+	}
+}
+
+// detectedProfiles holds detection results by provider and model, so the
+// provider is asked about a model once per process rather than on every
+// agent construction (sub-agents, the TUI's rebuild after Ctrl+C).
+var (
+	detectedProfilesMu sync.Mutex
+	detectedProfiles   = map[string]skillsets.ModelProfile{}
+)
+
+// detectProfile guesses a profile from the model name, refined by what the
+// provider reports about the model when it can describe its models.
+func detectProfile(prov provider.Provider, modelName string) skillsets.ModelProfile {
+	key := prov.Name() + "/" + modelName
+	detectedProfilesMu.Lock()
+	defer detectedProfilesMu.Unlock()
+	profile, ok := detectedProfiles[key]
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if info, found := provider.DescribeModel(ctx, prov, modelName); found {
+			profile = skillsets.DetectModelProfileWithInfo(info)
+		} else {
+			profile = skillsets.DetectModelProfile(modelName)
+		}
+		detectedProfiles[key] = profile
 	}
+	// Overrides are applied to the returned copy's skill map.
+	profile.Skillsets = maps.Clone(profile.Skillsets)
+	return profile
+}
+
+// resolveProfile picks the profile for modelName, falling back to detection
+// for models without a profile; a probe-calibrated override applies to both.
+func resolveProfile(prov provider.Provider, userConfig *skillsets.UserConfig, modelName string) skillsets.ModelProfile {
+	return skillsets.ResolveProfile(userConfig, modelName, func(name string) skillsets.ModelProfile {
+		return detectProfile(prov, name)
+	})
 }
 
 // NewWithConversation creates an agent using an existing conversation history.
@@ -182,21 +240,13 @@ func NewWithConversation(prov provider.Provider, registry *tools.Registry, conv
 
 	// Detect model capabilities (same as New())
 	modelName := prov.ModelName()
-	defaultProfiles := skillsets.DefaultProfiles()
-	mergedProfiles := skillsets.MergeProfiles(defaultProfiles, userConfig)
-
-	var profile skillsets.ModelProfile
-	if p, ok := mergedProfiles[modelName]; ok {
-		profile = p
-	} else {
-		// Fallback to detection
-		profile = skillsets.DetectModelProfile(modelName)
-	}
+	profile := resolveProfile(prov, userConfig, modelName)
 
 	// CRITICAL: Update the conversation context limit if we are attaching to an existing one.
 	if profile.MaxTokens > 0 {
 		conv.SetMaxTokens(profile.MaxTokens)
 	}
+	conv.SetTokenizer(tokenizer.ForModel(modelName))
 
 	// Load memory for struct (even if not injecting into prompt as conv is existing)
 	cwd, _ := os.Getwd()
@@ -215,6 +265,8 @@ func NewWithConversation(prov provider.Provider, registry *tools.Registry, conv
 		userConfig:     userConfig,
 		projectContext: projCtx,
 		autoMemory:     autoMem,
+		ChatOptions:    DefaultChatOptions,
+		checkpoints:    checkpoint.Open(conv.SessionID()),
 	}
 }
 
@@ -294,7 +346,12 @@ func (a *Agent) Send(ctx context.Context, userMsg string, events chan<- Event) {
 	}
 
 	// Add user message to conversation
-	a.conv.AddUser(userMsg)
+	turn := a.conv.AddUserTurn(userMsg)
+	// A sub-agent's edits belong to the parent's turn, whose store is
+	// already in ctx.
+	if _, _, ok := checkpoint.FromContext(ctx); !ok {
+		ctx = checkpoint.WithTurn(ctx, a.checkpoints, turn)
+	}
 	a.runLoop(ctx, events, contextPrompt)
 }
 
@@ -343,7 +400,8 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 		reminder := fmt.Sprintf("Turn %d/%d. Review the history. If you just ran a command, did it work? If it failed, try a DIFFERENT approach. Do not repeat mistakes.", turn+1, MaxTurns)
 		msgs = append(msgs, provider.Message{Role: provider.RoleSystem, Content: reminder})
 
-		stream, err := a.prov.Chat(ctx, msgs, a.tools.ToolDefs())
+		toolDefs := a.tools.ToolDefs()
+		stream, err := a.prov.Chat(ctx, msgs, toolDefs, a.ChatOptions...)
 		if err != nil {
 			events <- Event{Type: EventError, Error: err.Error(), Done: true}
 			return
@@ -351,7 +409,11 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 
 		var textBuf strings.Builder
 		var toolCalls []provider.ToolCall
+		var thinking []provider.ThinkingBlock
 		var usage *provider.Usage // Capture usage from final chunk
+		// Identity of tool calls still streaming, by index; later fragments
+		// may carry only arguments.
+		pending := map[int]provider.ToolCallDelta{}
 
 		for chunk := range stream {
 
@@ -359,6 +421,20 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 				events <- Event{Type: EventError, Error: chunk.Error.Error(), Done: true}
 				return
 			}
+			if chunk.Route != nil {
+				events <- Event{Type: EventRoute, Text: chunk.Route.String(), Route: chunk.Route}
+			}
+			if chunk.Restart != nil {
+				// The provider is reissuing the request; drop the partial response.
+				textBuf.Reset()
+				toolCalls, thinking, usage = nil, nil, nil
+				clear(pending)
+				events <- Event{
+					Type:  EventRetry,
+					Text:  fmt.Sprintf("Stream interrupted (%v), retrying (attempt %d)...", chunk.Restart.Reason, chunk.Restart.Attempt),
+					Error: chunk.Restart.Reason.Error(),
+				}
+			}
 			if chunk.Thinking != "" {
 				events <- Event{Type: EventThinking, Text: chunk.Thinking}
 			}
@@ -366,12 +442,31 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 				textBuf.WriteString(chunk.Delta)
 				events <- Event{Type: EventDelta, Text: chunk.Delta}
 			}
+			if d := chunk.ToolCallDelta; d != nil {
+				call := pending[d.Index]
+				if d.ID != "" {
+					call.ID = d.ID
+				}
+				if d.Name != "" {
+					call.Name = d.Name
+				}
+				pending[d.Index] = call
+				events <- Event{Type: EventToolCallDelta, ToolID: call.ID, ToolName: call.Name, ToolIndex: d.Index, ToolArgs: d.Args}
+			}
 			if chunk.Done {
 				toolCalls = chunk.ToolCalls
+				thinking = chunk.ThinkingBlocks
 				usage = chunk.Usage // Capture usage from final chunk
 			}
 		}
 
+		// A stream cut short by cancellation may still hand back the tool
+		// calls parsed so far; never run a call the user stopped mid-write.
+		if err := ctx.Err(); err != nil {
+			events <- Event{Type: EventError, Error: err.Error(), Done: true}
+			return
+		}
+
 		assistantText := textBuf.String()
 
 		// CoT Parsing: Extract <thought> tags for event emission (for models without native support)
@@ -386,7 +481,8 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 			}
 		}
 
-		a.conv.AddAssistant(assistantText, toolCalls)
+		a.conv.Calibrate(msgs, toolDefs, usage)
+		a.conv.AddAssistantWithThinking(assistantText, toolCalls, thinking)
 
 		// FALLBACK 1: Check for raw JSON format `{"name": "tool", "arguments": {...}}`
 		// Some models (like Qwen 2.5 Coder) prefer this over the [TOOL:...] format despite instructions.
@@ -523,6 +619,13 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 			return
 		}
 
+		// Position of each call in the response, which the TUI uses to match
+		// the complete call to its streamed draft.
+		callIndex := make(map[provider.ToolCall]int, len(toolCalls))
+		for i := len(toolCalls) - 1; i >= 0; i-- {
+			callIndex[toolCalls[i]] = i
+		}
+
 		// --- VALIDATION STEP ---
 		// Check for hallucinations/unsafe actions before parallel grouping
 		if a.validator != nil {
@@ -537,9 +640,10 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 					a.conv.AddSystem(rejectionMsg)
 
 					events <- Event{
-						Type:     EventError,
-						Error:    fmt.Sprintf("Validation Failed: %s", reason),
-						ToolName: tc.Name,
+						Type:      EventError,
+						Error:     fmt.Sprintf("Validation Failed: %s", reason),
+						ToolName:  tc.Name,
+						ToolIndex: callIndex[tc],
 					}
 					// Do not add to validTools
 					continue
@@ -554,7 +658,8 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 		var sequentialGroup []provider.ToolCall
 
 		for _, tc := range toolCalls {
-			if IsSafeToParallelize(tc.Name) {
+			// Calls leaving the workspace wait their turn for approval
+			if IsSafeToParallelize(tc.Name) && len(a.tools.OutsidePaths(tc.Name, tc.Args)) == 0 {
 				parallelGroup = append(parallelGroup, tc)
 			} else {
 				sequentialGroup = append(sequentialGroup, tc)
@@ -573,7 +678,7 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 					prettyArgs := formatToolArgs(tc.Name, tc.Args)
 					events <- Event{
 						Type: EventToolCall, ToolName: tc.Name,
-						ToolArgs: prettyArgs, ToolID: tc.ID,
+						ToolArgs: prettyArgs, ToolID: tc.ID, ToolIndex: callIndex[tc],
 					}
 
 					res, err := a.tools.Execute(ctx, tc.Name, tc.Args, nil)
@@ -594,7 +699,8 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 						}
 					}
 
-					a.conv.AddToolResult(tc.ID, res.Output)
+					output, images := a.toolImages(res)
+					a.conv.AddToolResultWithParts(tc.ID, output, images)
 					events <- Event{
 						Type:     EventToolResult,
 						ToolID:   tc.ID,
@@ -633,14 +739,23 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 
 			events <- Event{
 				Type: EventToolCall, ToolName: tc.Name,
-				ToolArgs: prettyArgs, ToolID: tc.ID,
+				ToolArgs: prettyArgs, ToolID: tc.ID, ToolIndex: callIndex[tc],
 			}
 
-			// If confirmation needed, ask the TUI and block
-			if a.tools.NeedsConfirmation(tc.Name) {
+			// If confirmation needed, ask the TUI and block. Leaving the
+			// workspace always needs it, except in sub-agents, which have no
+			// one to ask; the registry refuses those calls.
+			toolCtx := ctx
+			var outside []string
+			if a.depth == 0 {
+				outside = a.tools.OutsidePaths(tc.Name, tc.Args)
+			}
+			escalate := len(outside) > 0
+			if escalate || a.tools.NeedsConfirmation(tc.Name) {
 				events <- Event{
 					Type: EventConfirmRequest, ToolName: tc.Name,
 					ToolArgs: prettyArgs, ToolID: tc.ID,
+					Outside: outside,
 				}
 
 				select {
@@ -651,6 +766,9 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 						events <- Event{Type: EventToolResult, ToolID: tc.ID, ToolName: tc.Name, Result: result}
 						continue
 					}
+					if escalate {
+						toolCtx = tools.ApproveOutside(ctx)
+					}
 				case <-ctx.Done():
 					return
 				}
@@ -697,10 +815,9 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 				}
 			}
 
-			res, err := a.tools.Execute(ctx, tc.Name, tc.Args, streamCallback)
+			res, err := a.tools.Execute(toolCtx, tc.Name, tc.Args, streamCallback)
 			if err != nil {
 				errMsg := err.Error()
-				fmt.Printf("DEBUG: System error: '%s'\n", errMsg) // DEBUG
 				// Nudge: Check for common model mistakes
 				if strings.Contains(errMsg, "unknown tool") {
 					suggestions := make([]string, 0)
@@ -735,14 +852,15 @@ func (a *Agent) runLoop(ctx context.Context, events chan<- Event, tempSystemProm
 			// Nudge logic for Result errors (like unknown tool returned by Registry)
 			if res.Error != "" {
 				errMsg := res.Error
-				fmt.Printf("DEBUG: Nudging result error: '%s'\n", errMsg) // DEBUG
 				// If it's a system error (e.g., tool not found), provide a nudge
 				if strings.Contains(errMsg, "unknown tool") || strings.Contains(errMsg, "not found") {
 					errMsg += "\n\n💡 Hint: Check the tool name. Available tools are listed in the system prompt."
 				}
 				output += "\nError: " + errMsg
 			}
-			a.conv.AddToolResult(tc.ID, output)
+			res.Output = output
+			output, images := a.toolImages(res)
+			a.conv.AddToolResultWithParts(tc.ID, output, images)
 
 			// For Tier 2/3 models, inject ReAct prompt to force observation and reflection
 			if a.profile.Tier >= 2 && len(output) > 50 {
@@ -807,14 +925,41 @@ func formatToolArgs(toolName, rawArgs string) string {
 		}
 	case "file_read", "file_write":
 		if p, ok := parsed["path"]; ok {
+			if edits, ok := parsed["edits"].([]any); ok && len(edits) > 1 {
+				return fmt.Sprintf("%v (%d edits)", p, len(edits))
+			}
 			return fmt.Sprintf("%v", p)
 		}
+	case "apply_patch":
+		if patch, ok := parsed["patch"].(string); ok {
+			if files := tools.PatchFiles(patch); len(files) > 0 {
+				return strings.Join(files, ", ")
+			}
+		}
 	case "file_search":
+		var parts []string
+		if g, ok := parsed["grep"]; ok {
+			parts = append(parts, fmt.Sprintf("grep=%v", g))
+		}
 		if p, ok := parsed["pattern"]; ok {
-			return fmt.Sprintf("pattern=%v", p)
+			parts = append(parts, fmt.Sprintf("pattern=%v", p))
 		}
-		if g, ok := parsed["grep"]; ok {
-			return fmt.Sprintf("grep=%v", g)
+		if dir, ok := parsed["path"]; ok {
+			parts = append(parts, fmt.Sprintf("in %v", dir))
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, " ")
+		}
+	case "code_symbols":
+		action, _ := parsed["action"].(string)
+		if name, ok := parsed["name"].(string); ok && name != "" {
+			return action + " " + name
+		}
+		if p, ok := parsed["path"].(string); ok && p != "" {
+			return action + " " + p
+		}
+		if action != "" {
+			return action
 		}
 	case "web_search":
 		if q, ok := parsed["query"]; ok {
@@ -832,3 +977,16 @@ func formatToolArgs(toolName, rawArgs string) string {
 	}
 	return s
 }
+
+// toolImages returns the tool output and the images to attach for the model.
+// Text-only models get a note instead, so they don't pretend to see the image.
+func (a *Agent) toolImages(res tools.Result) (string, []provider.ContentPart) {
+	if len(res.Images) == 0 {
+		return res.Output, nil
+	}
+	if a.profile.SupportsVision {
+		return res.Output, res.Images
+	}
+	note := fmt.Sprintf("\n[%d image(s) omitted: model %s does not support vision]", len(res.Images), a.profile.Name)
+	return res.Output + note, nil
+}
@@ -13,7 +13,7 @@ type MockProviderBehavior struct {
 	CapturedResponse string
 }
 
-func (m *MockProviderBehavior) Chat(ctx context.Context, msgs []provider.Message, defs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderBehavior) Chat(ctx context.Context, msgs []provider.Message, defs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 
 	go func() {
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jeanpaul/aseity/internal/checkpoint"
+)
+
+// Checkpoints returns the store holding the files this session's turns
+// changed.
+func (a *Agent) Checkpoints() *checkpoint.Store { return a.checkpoints }
+
+// Undo restores the files changed by the most recent turn that changed any,
+// and tells the model so it doesn't build on edits that are gone. It
+// returns the turn and the restored paths.
+func (a *Agent) Undo() (int, []string, error) {
+	if a.checkpoints == nil {
+		return 0, nil, fmt.Errorf("checkpoints are not enabled for this session")
+	}
+	turn, err := a.checkpoints.LastTurn()
+	if err != nil {
+		return 0, nil, err
+	}
+	if turn == 0 {
+		return 0, nil, fmt.Errorf("no file changes to undo")
+	}
+	paths, err := a.checkpoints.Restore(turn)
+	if err != nil {
+		return turn, nil, err
+	}
+	a.conv.AddSystem(fmt.Sprintf("The user undid the file changes made in turn %d. These files are back to how they were before it: %s. Re-read them before editing.",
+		turn, strings.Join(paths, ", ")))
+	return turn, paths, nil
+}
+
+// Rewind returns the session to just before turn: files changed since are
+// restored and the conversation is cut back to that point. It returns the
+// restored paths.
+func (a *Agent) Rewind(turn int) ([]string, error) {
+	// Check first so files aren't restored under a conversation that can't
+	// follow them back.
+	if err := a.conv.CanRewind(turn); err != nil {
+		return nil, err
+	}
+	var paths []string
+	if a.checkpoints != nil {
+		last, err := a.checkpoints.LastTurn()
+		if err != nil {
+			return nil, err
+		}
+		if last >= turn {
+			if paths, err = a.checkpoints.Restore(turn); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paths, a.conv.Rewind(turn)
+}
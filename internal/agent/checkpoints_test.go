@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+	"github.com/jeanpaul/aseity/internal/tools"
+)
+
+// MockProviderWriter answers each user message by writing it to path.
+type MockProviderWriter struct {
+	path string
+}
+
+func (m *MockProviderWriter) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
+	ch := make(chan provider.StreamChunk, 1)
+	defer close(ch)
+	var last provider.Message
+	for _, msg := range messages {
+		if msg.Role != provider.RoleSystem {
+			last = msg
+		}
+	}
+	switch {
+	case toolDefs == nil:
+		ch <- provider.StreamChunk{Delta: "VALID", Done: true}
+	case last.Role == provider.RoleTool:
+		ch <- provider.StreamChunk{Delta: "Done", Done: true}
+	default:
+		args, _ := json.Marshal(map[string]string{"path": m.path, "content": last.Content})
+		ch <- provider.StreamChunk{ToolCalls: []provider.ToolCall{{ID: "w", Name: "file_write", Args: string(args)}}, Done: true}
+	}
+	return ch, nil
+}
+
+func (m *MockProviderWriter) Name() string      { return "mock" }
+func (m *MockProviderWriter) ModelName() string { return "test-model" }
+func (m *MockProviderWriter) Models(ctx context.Context) ([]string, error) {
+	return []string{"mock"}, nil
+}
+
+func TestAgent_UndoAndRewind(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("original"), 0644)
+
+	reg := tools.NewRegistry(nil, true)
+	reg.Register(&tools.FileWriteTool{})
+	agent := New(&MockProviderWriter{path: path}, reg, "")
+	send := func(msg string) {
+		events := make(chan Event, 100)
+		go agent.Send(context.Background(), msg, events)
+		for evt := range events {
+			if evt.Done {
+				break
+			}
+		}
+	}
+	read := func() string {
+		b, _ := os.ReadFile(path)
+		return string(b)
+	}
+
+	send("first")
+	send("second")
+	send("third")
+	if read() != "third" {
+		t.Fatalf("file = %q after three turns", read())
+	}
+
+	turn, paths, err := agent.Undo()
+	if err != nil || turn != 3 || len(paths) != 1 {
+		t.Fatalf("Undo = %d, %v, %v", turn, paths, err)
+	}
+	if read() != "second" {
+		t.Errorf("after undo, file = %q", read())
+	}
+
+	if _, err := agent.Rewind(2); err != nil {
+		t.Fatalf("Rewind: %v", err)
+	}
+	if read() != "first" {
+		t.Errorf("after rewind, file = %q", read())
+	}
+	if agent.Conversation().Turn() != 1 {
+		t.Errorf("turn = %d after rewinding to before turn 2", agent.Conversation().Turn())
+	}
+
+	send("again")
+	if read() != "again" {
+		t.Errorf("file = %q", read())
+	}
+	if turns, _ := agent.Checkpoints().Turns(); len(turns) != 2 || turns[1].N != 2 {
+		t.Errorf("checkpoint turns = %+v", turns)
+	}
+}
+
+func TestAgent_RewindAfterResume(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("original"), 0644)
+
+	reg := tools.NewRegistry(nil, true)
+	reg.Register(&tools.FileWriteTool{})
+	prov := &MockProviderWriter{path: path}
+	agent := New(prov, reg, "")
+	for _, msg := range []string{"first", "second"} {
+		events := make(chan Event, 100)
+		go agent.Send(context.Background(), msg, events)
+		for evt := range events {
+			if evt.Done {
+				break
+			}
+		}
+	}
+	saved, err := agent.Conversation().Save()
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	conv, err := LoadConversation(saved)
+	if err != nil {
+		t.Fatalf("LoadConversation: %v", err)
+	}
+	if conv.SessionID() != agent.Conversation().SessionID() {
+		t.Errorf("session ID = %q after loading, want %q", conv.SessionID(), agent.Conversation().SessionID())
+	}
+	resumed := NewWithConversation(prov, reg, conv)
+	paths, err := resumed.Rewind(2)
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("Rewind after resume = %v, %v", paths, err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "first" {
+		t.Errorf("after rewind, file = %q", b)
+	}
+}
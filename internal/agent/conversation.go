@@ -9,16 +9,35 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jeanpaul/aseity/internal/pricing"
 	"github.com/jeanpaul/aseity/internal/provider"
+	"github.com/jeanpaul/aseity/internal/tokenizer"
 )
 
+// imageTokenEstimate is the rough prompt cost of one image part.
+const imageTokenEstimate = 1000
+
 type Conversation struct {
 	mu          sync.Mutex
 	messages    []provider.Message
 	maxTokens   int // approximate context window limit
 	totalTokens int // running estimate
+	overhead    int // prompt tokens outside the history (tool schemas, injected context), from the last usage report
+	tok         tokenizer.Tokenizer
+	costs       *pricing.Tracker
+	savedCost   *pricing.Summary // Cost loaded from a session file, until a tracker takes it over
 	sessionID   string
 	sessionDir  string
+	turns       []int // Index of each turn's user message; -1 once compacted or cleared away
+}
+
+// sessionFile is the on-disk form of a saved session. Older sessions are a
+// bare array of messages.
+type sessionFile struct {
+	Messages []provider.Message `json:"messages"`
+	Cost     *pricing.Summary   `json:"cost,omitempty"`
+	Turns    []int              `json:"turns,omitempty"`
+	Session  string             `json:"session,omitempty"` // Keys the session's checkpoints
 }
 
 func NewConversation() *Conversation {
@@ -26,6 +45,7 @@ func NewConversation() *Conversation {
 	sessionDir := filepath.Join(home, ".config", "aseity", "sessions")
 	return &Conversation{
 		maxTokens:  100000, // conservative default
+		tok:        tokenizer.NewCalibrating(),
 		sessionID:  fmt.Sprintf("%d", time.Now().UnixNano()),
 		sessionDir: sessionDir,
 	}
@@ -39,34 +59,176 @@ func (c *Conversation) SetMaxTokens(max int) {
 	}
 }
 
+// SetTokenizer switches the tokenizer used for accounting and recounts the
+// history with it.
+func (c *Conversation) SetTokenizer(t tokenizer.Tokenizer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tok = t
+	c.recalcTokens()
+}
+
+// SetCostTracker attaches the session's cost tracker so that saved sessions
+// include the spend so far. Cost restored from a loaded session is carried
+// over into the tracker.
+func (c *Conversation) SetCostTracker(t *pricing.Tracker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t != nil && c.savedCost != nil {
+		t.Restore(*c.savedCost)
+		c.savedCost = nil
+	}
+	c.costs = t
+}
+
+func (c *Conversation) CostTracker() *pricing.Tracker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.costs
+}
+
+func (c *Conversation) Tokenizer() tokenizer.Tokenizer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tok
+}
+
+// Calibrate compares the prompt that was sent with the input token count
+// the provider reported for it. A Calibrator tokenizer learns from the
+// difference, and whatever the history doesn't account for (tool schemas,
+// per-turn context) is kept as overhead for the compaction threshold.
+func (c *Conversation) Calibrate(sent []provider.Message, tools []provider.ToolDef, usage *provider.Usage) {
+	if usage == nil || usage.InputTokens <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cal, ok := c.tok.(tokenizer.Calibrator); ok {
+		chars, images := promptSize(sent, tools)
+		cal.Observe(chars, usage.InputTokens-images*imageTokenEstimate)
+		c.recalcTokens()
+	}
+	c.overhead = max(0, usage.InputTokens-c.totalTokens)
+}
+
 func (c *Conversation) AddSystem(content string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.messages = append(c.messages, provider.Message{Role: provider.RoleSystem, Content: content})
-	c.totalTokens += estimateTokens(content)
+	c.totalTokens += c.countTokens(content)
 }
 
 func (c *Conversation) AddUser(content string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.messages = append(c.messages, provider.Message{Role: provider.RoleUser, Content: content})
-	c.totalTokens += estimateTokens(content)
+	c.totalTokens += c.countTokens(content)
+	c.compactIfNeeded()
+}
+
+// AddUserTurn adds a user message that starts a new turn and returns the
+// turn's number, counting from 1.
+func (c *Conversation) AddUserTurn(content string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.turns = append(c.turns, len(c.messages))
+	turn := len(c.turns)
+	c.messages = append(c.messages, provider.Message{Role: provider.RoleUser, Content: content})
+	c.totalTokens += c.countTokens(content)
 	c.compactIfNeeded()
+	return turn
+}
+
+// Turn is the number of the latest turn, or 0 before the first.
+func (c *Conversation) Turn() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.turns)
+}
+
+// CanRewind reports why the conversation can't go back to just before
+// turn, or nil if it can.
+func (c *Conversation) CanRewind(turn int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.checkRewind(turn)
+}
+
+func (c *Conversation) checkRewind(turn int) error {
+	if turn < 1 || turn > len(c.turns) {
+		return fmt.Errorf("no turn %d; this conversation has %d", turn, len(c.turns))
+	}
+	if c.turns[turn-1] < 0 {
+		return fmt.Errorf("turn %d is no longer in the conversation (compacted or cleared)", turn)
+	}
+	return nil
+}
+
+// Rewind drops turn and everything after it, so the next turn takes its
+// number.
+func (c *Conversation) Rewind(turn int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.checkRewind(turn); err != nil {
+		return err
+	}
+	c.messages = c.messages[:c.turns[turn-1]]
+	c.turns = c.turns[:turn-1]
+	c.recalcTokens()
+	return nil
+}
+
+// TurnPrompt returns the user message that started turn, if the turn is
+// still in the conversation.
+func (c *Conversation) TurnPrompt(turn int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checkRewind(turn) != nil {
+		return "", false
+	}
+	return c.messages[c.turns[turn-1]].Content, true
 }
 
+// TurnAt returns the turn started by the message at index i of Messages, or
+// 0 if that message didn't start one.
+func (c *Conversation) TurnAt(i int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, idx := range c.turns {
+		if idx == i {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+func (c *Conversation) SessionID() string { return c.sessionID }
+
 func (c *Conversation) AddAssistant(content string, toolCalls []provider.ToolCall) {
+	c.AddAssistantWithThinking(content, toolCalls, nil)
+}
+
+// AddAssistantWithThinking adds an assistant turn along with the signed
+// reasoning the provider returned for it, which is replayed on later requests.
+func (c *Conversation) AddAssistantWithThinking(content string, toolCalls []provider.ToolCall, thinking []provider.ThinkingBlock) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.messages = append(c.messages, provider.Message{
-		Role: provider.RoleAssistant, Content: content, ToolCalls: toolCalls,
+		Role: provider.RoleAssistant, Content: content, ToolCalls: toolCalls, ThinkingBlocks: thinking,
 	})
-	c.totalTokens += estimateTokens(content)
+	c.totalTokens += c.countTokens(content)
 	for _, tc := range toolCalls {
-		c.totalTokens += estimateTokens(tc.Args)
+		c.totalTokens += c.countTokens(tc.Args)
 	}
 }
 
 func (c *Conversation) AddToolResult(toolCallID, content string) {
+	c.AddToolResultWithParts(toolCallID, content, nil)
+}
+
+// AddToolResultWithParts records a tool result that carries extra content
+// parts, such as images for vision models.
+func (c *Conversation) AddToolResultWithParts(toolCallID, content string, parts []provider.ContentPart) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	// Truncate very large tool results
@@ -74,9 +236,9 @@ func (c *Conversation) AddToolResult(toolCallID, content string) {
 		content = content[:30000] + "\n... [truncated]"
 	}
 	c.messages = append(c.messages, provider.Message{
-		Role: provider.RoleTool, Content: content, ToolCallID: toolCallID,
+		Role: provider.RoleTool, Content: content, ToolCallID: toolCallID, Parts: parts,
 	})
-	c.totalTokens += estimateTokens(content)
+	c.totalTokens += c.countTokens(content) + c.countPartTokens(parts)
 	c.compactIfNeeded()
 }
 
@@ -104,7 +266,7 @@ func (c *Conversation) EstimatedTokens() int {
 // compactIfNeeded removes old messages if we're approaching the context limit.
 // Keeps: system prompt, last N user/assistant exchanges.
 func (c *Conversation) compactIfNeeded() {
-	if c.totalTokens < c.maxTokens*80/100 {
+	if c.totalTokens+c.overhead < c.maxTokens*80/100 {
 		return
 	}
 	c.Compact()
@@ -160,6 +322,16 @@ func (c *Conversation) Compact() {
 	})
 	newMsgs = append(newMsgs, remaining[cutoff:]...)
 
+	// Turns that began in the summarized part are gone; the rest moved.
+	shift := len(c.messages) - len(newMsgs)
+	for i, idx := range c.turns {
+		if idx < start+cutoff {
+			c.turns[i] = -1
+		} else {
+			c.turns[i] = idx - shift
+		}
+	}
+
 	c.messages = newMsgs
 	c.recalcTokens()
 }
@@ -173,15 +345,20 @@ func (c *Conversation) Clear() {
 		}
 	}
 	c.messages = newMsgs
+	// Keep the count so turn numbers, and the checkpoints keyed by them,
+	// stay unique for the session.
+	for i := range c.turns {
+		c.turns[i] = -1
+	}
 	c.recalcTokens()
 }
 
 func (c *Conversation) recalcTokens() {
 	c.totalTokens = 0
 	for _, m := range c.messages {
-		c.totalTokens += estimateTokens(m.Content)
+		c.totalTokens += c.countTokens(m.Content) + c.countPartTokens(m.Parts)
 		for _, tc := range m.ToolCalls {
-			c.totalTokens += estimateTokens(tc.Args)
+			c.totalTokens += c.countTokens(tc.Args)
 		}
 	}
 }
@@ -192,7 +369,12 @@ func (c *Conversation) Save() (string, error) {
 		return "", err
 	}
 	path := filepath.Join(c.sessionDir, c.sessionID+".json")
-	data, err := json.MarshalIndent(c.messages, "", "  ")
+	file := sessionFile{Messages: c.messages, Cost: c.savedCost, Turns: c.turns, Session: c.sessionID}
+	if c.costs != nil {
+		summary := c.costs.Summary()
+		file.Cost = &summary
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
 	if err != nil {
 		return "", err
 	}
@@ -227,19 +409,70 @@ func LoadConversation(path string) (*Conversation, error) {
 	if err != nil {
 		return nil, err
 	}
-	var msgs []provider.Message
-	if err := json.Unmarshal(data, &msgs); err != nil {
-		return nil, err
+	var file sessionFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		// Sessions saved before cost tracking are a plain message array
+		if err := json.Unmarshal(data, &file.Messages); err != nil {
+			return nil, err
+		}
 	}
 	conv := NewConversation()
-	conv.messages = msgs
+	conv.messages = file.Messages
+	conv.savedCost = file.Cost
+	conv.turns = file.Turns
+	// Keep the session ID so the resumed session finds its checkpoints.
+	// Sessions saved without one are named after it.
+	id := file.Session
+	if id == "" {
+		id = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if id != "" && id == filepath.Base(id) && id != "." && id != ".." {
+		conv.sessionID = id
+	}
 	conv.recalcTokens()
 	return conv, nil
 }
 
-// estimateTokens gives a rough count (~4 chars per token).
-func estimateTokens(s string) int {
-	return len(s) / 4
+func (c *Conversation) countTokens(s string) int {
+	return c.tok.Count(s)
+}
+
+// countPartTokens counts content parts; images cost roughly a fixed
+// budget regardless of file size on most vision APIs.
+func (c *Conversation) countPartTokens(parts []provider.ContentPart) int {
+	n := 0
+	for _, p := range parts {
+		switch p.Type {
+		case provider.PartImage:
+			n += imageTokenEstimate
+		case provider.PartText:
+			n += c.countTokens(p.Text)
+		}
+	}
+	return n
+}
+
+// promptSize measures the text of a request as sent, for calibration.
+// Images are counted separately since their cost doesn't scale with length.
+func promptSize(msgs []provider.Message, tools []provider.ToolDef) (chars, images int) {
+	for _, m := range msgs {
+		chars += len(m.Content)
+		for _, tc := range m.ToolCalls {
+			chars += len(tc.Name) + len(tc.Args)
+		}
+		for _, p := range m.Parts {
+			if p.Type == provider.PartImage {
+				images++
+			} else {
+				chars += len(p.Text)
+			}
+		}
+	}
+	for _, t := range tools {
+		schema, _ := json.Marshal(t.Parameters)
+		chars += len(t.Name) + len(t.Description) + len(schema)
+	}
+	return chars, images
 }
 
 func truncateText(s string, n int) string {
@@ -1,9 +1,12 @@
 package agent
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/jeanpaul/aseity/internal/pricing"
 	"github.com/jeanpaul/aseity/internal/provider"
 )
 
@@ -64,3 +67,118 @@ func TestConversation_TokenEstimation(t *testing.T) {
 		t.Error("Estimated tokens should be > 0")
 	}
 }
+
+func TestConversation_Calibrate(t *testing.T) {
+	conv := NewConversation()
+	conv.AddUser(strings.Repeat("x", 4000)) // ~1000 tokens at the default ratio
+	sent := conv.Messages()
+
+	// The provider counted twice as many tokens as the default ratio predicts
+	conv.Calibrate(sent, nil, &provider.Usage{InputTokens: 2000})
+	if est := conv.EstimatedTokens(); est < 1900 || est > 2100 {
+		t.Errorf("after calibration EstimatedTokens = %d, want ~2000", est)
+	}
+
+	// Tool schemas aren't in the history but count against the window
+	conv.SetMaxTokens(5000)
+	conv.Calibrate(sent, nil, &provider.Usage{InputTokens: 5000})
+	for i := 0; i < 8; i++ {
+		conv.AddUser("next")
+		conv.AddAssistant("ok", nil)
+	}
+	if msgs := conv.Messages(); !strings.Contains(msgs[0].Content, "[Conversation summary]") {
+		t.Error("prompt overhead should have triggered compaction")
+	}
+}
+
+func TestConversation_SaveRestoresCost(t *testing.T) {
+	dir := t.TempDir()
+	tracker := pricing.NewTracker(pricing.NewRegistry())
+	tracker.Record("validator", "openai", "gpt-4o", provider.Usage{InputTokens: 1000, OutputTokens: 10, TotalTokens: 1010})
+
+	conv := NewConversation()
+	conv.sessionDir = dir
+	conv.AddUser("hello")
+	conv.SetCostTracker(tracker)
+	path, err := conv.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConversation(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 1 {
+		t.Errorf("loaded %d messages, want 1", loaded.Len())
+	}
+	resumed := pricing.NewTracker(pricing.NewRegistry())
+	loaded.SetCostTracker(resumed)
+	if got, want := resumed.Summary().Cost, tracker.Summary().Cost; got != want || got == 0 {
+		t.Errorf("resumed cost = %v, want %v", got, want)
+	}
+
+	// Sessions saved before cost tracking are a bare message array
+	legacy := filepath.Join(dir, "legacy.json")
+	os.WriteFile(legacy, []byte(`[{"role":"user","content":"hi"}]`), 0644)
+	if c, err := LoadConversation(legacy); err != nil || c.Len() != 1 {
+		t.Errorf("legacy session: %v", err)
+	}
+}
+
+func TestConversation_RewindTurns(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystem("System Prompt")
+	for i := 1; i <= 3; i++ {
+		if turn := conv.AddUserTurn(strings.Repeat("x", i)); turn != i {
+			t.Fatalf("turn = %d, want %d", turn, i)
+		}
+		conv.AddAssistant("ok", nil)
+	}
+
+	if err := conv.Rewind(2); err != nil {
+		t.Fatalf("Rewind: %v", err)
+	}
+	if conv.Len() != 3 || conv.Turn() != 1 {
+		t.Errorf("after rewind: %d messages, turn %d; want 3 and 1", conv.Len(), conv.Turn())
+	}
+	if turn := conv.AddUserTurn("again"); turn != 2 {
+		t.Errorf("next turn = %d, want 2", turn)
+	}
+	if err := conv.Rewind(5); err == nil {
+		t.Error("rewinding to a turn that never happened should fail")
+	}
+}
+
+func TestConversation_CompactMovesTurns(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystem("System Prompt")
+	for i := 1; i <= 6; i++ {
+		conv.AddUserTurn(strings.Repeat("u", i))
+		conv.AddAssistant("ok", nil)
+	}
+	conv.Compact()
+
+	if _, ok := conv.TurnPrompt(1); ok {
+		t.Error("a summarized turn should no longer be reachable")
+	}
+	if err := conv.CanRewind(2); err == nil {
+		t.Error("rewinding into the summary should fail")
+	}
+	prompt, ok := conv.TurnPrompt(5)
+	if !ok || prompt != "uuuuu" {
+		t.Fatalf("turn 5 prompt = %q, %v", prompt, ok)
+	}
+	if err := conv.Rewind(5); err != nil {
+		t.Fatalf("Rewind: %v", err)
+	}
+	msgs := conv.Messages()
+	if last := msgs[len(msgs)-1]; last.Content != "ok" || conv.Turn() != 4 {
+		t.Errorf("after rewind: last %+v, turn %d", last, conv.Turn())
+	}
+
+	conv.Clear()
+	if conv.Turn() != 4 || conv.CanRewind(4) == nil {
+		t.Error("Clear should keep turn numbers but make them unreachable")
+	}
+}
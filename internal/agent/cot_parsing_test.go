@@ -107,7 +107,7 @@ func TestJSONFallbackParsing(t *testing.T) {
 // MockProviderCoT simulates a model returning <thought> blocks
 type MockProviderCoT struct{}
 
-func (m *MockProviderCoT) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderCoT) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	go func() {
 		defer close(ch)
@@ -135,7 +135,7 @@ func (m *MockProviderCoT) Models(ctx context.Context) ([]string, error) {
 // MockProviderJSON simulates a model returning raw JSON
 type MockProviderJSON struct{}
 
-func (m *MockProviderJSON) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderJSON) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	go func() {
 		defer close(ch)
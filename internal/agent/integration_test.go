@@ -74,7 +74,7 @@ type MockProviderParallelCancel struct {
 	called int
 }
 
-func (m *MockProviderParallelCancel) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderParallelCancel) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	go func() {
 		defer close(ch)
@@ -300,7 +300,7 @@ type MockProviderMixed struct {
 	called int
 }
 
-func (m *MockProviderMixed) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderMixed) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	go func() {
 		defer close(ch)
@@ -329,3 +329,127 @@ func (m *MockProviderMixed) ModelName() string { return "test-model" }
 func (m *MockProviderMixed) Models(ctx context.Context) ([]string, error) {
 	return []string{"mock"}, nil
 }
+
+// MockProviderStreamingTool streams a bash call's arguments in fragments.
+// With waitForCancel set it stalls mid-call until the context is cancelled
+// and then, like a provider flushing its state, still reports the call.
+type MockProviderStreamingTool struct {
+	called        int
+	waitForCancel bool
+}
+
+func (m *MockProviderStreamingTool) Chat(ctx context.Context, messages []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
+	ch := make(chan provider.StreamChunk)
+	m.called++
+	call := m.called
+	go func() {
+		defer close(ch)
+		if call > 1 {
+			ch <- provider.StreamChunk{Delta: "Done", Done: true}
+			return
+		}
+		ch <- provider.StreamChunk{ToolCallDelta: &provider.ToolCallDelta{ID: "c1", Name: "run_command", Args: `{"command":`}}
+		if m.waitForCancel {
+			<-ctx.Done()
+		}
+		ch <- provider.StreamChunk{ToolCallDelta: &provider.ToolCallDelta{Args: `"rm -rf build"}`}}
+		ch <- provider.StreamChunk{
+			ToolCalls: []provider.ToolCall{{ID: "c1", Name: "run_command", Args: `{"command":"rm -rf build"}`}},
+			Done:      true,
+		}
+	}()
+	return ch, nil
+}
+
+func (m *MockProviderStreamingTool) Name() string { return "mock" }
+
+func (m *MockProviderStreamingTool) ModelName() string { return "test-model" }
+
+func (m *MockProviderStreamingTool) Models(ctx context.Context) ([]string, error) {
+	return []string{"mock"}, nil
+}
+
+func TestToolCallDeltaEvents(t *testing.T) {
+	reg := tools.NewRegistry(nil, true)
+	reg.Register(&MockFastTool{name: "run_command"})
+	agent := New(&MockProviderStreamingTool{}, reg, "")
+
+	events := make(chan Event, 100)
+	go agent.Send(context.Background(), "clean up", events)
+
+	var args string
+	sawCall := false
+	for evt := range events {
+		switch evt.Type {
+		case EventToolCallDelta:
+			if sawCall {
+				t.Error("delta after the complete tool call")
+			}
+			if evt.ToolID != "c1" || evt.ToolName != "run_command" {
+				t.Errorf("delta identity = %q/%q, want every fragment labelled", evt.ToolID, evt.ToolName)
+			}
+			args += evt.ToolArgs
+		case EventToolCall:
+			sawCall = true
+		}
+		if evt.Done {
+			break
+		}
+	}
+	if args != `{"command":"rm -rf build"}` {
+		t.Errorf("streamed args = %q", args)
+	}
+	if !sawCall {
+		t.Error("tool call never executed")
+	}
+}
+
+func TestToolCallCancelledMidStream(t *testing.T) {
+	reg := tools.NewRegistry(nil, true)
+	reg.Register(&MockFastTool{name: "run_command"})
+	agent := New(&MockProviderStreamingTool{waitForCancel: true}, reg, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 100)
+	go agent.Send(ctx, "clean up", events)
+
+	for evt := range events {
+		switch evt.Type {
+		case EventToolCallDelta:
+			cancel()
+		case EventToolCall:
+			t.Fatal("a tool call cancelled while being written must not run")
+		}
+		if evt.Done {
+			break
+		}
+	}
+}
+
+// listingProvider describes its models and counts how often it is asked.
+type listingProvider struct {
+	MockProvider
+	lists int
+}
+
+func (l *listingProvider) Name() string { return "listing" }
+
+func (l *listingProvider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	l.lists++
+	return []provider.ModelInfo{{ID: "mock-model", ContextWindow: 65536}}, nil
+}
+
+func TestAgent_ProfileDetectedOnce(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prov := &listingProvider{}
+	reg := tools.NewRegistry(nil, false)
+	first := New(prov, reg, "")
+	second := NewWithConversation(prov, reg, first.Conversation())
+	if prov.lists != 1 {
+		t.Errorf("model listing fetched %d times, want once", prov.lists)
+	}
+	if second.GetProfile().MaxTokens != 65536 {
+		t.Errorf("MaxTokens = %d, want the listed context window", second.GetProfile().MaxTokens)
+	}
+}
@@ -50,8 +50,13 @@ If nothing worth saving is found, return { "learnings": [] }.
 		Content: prompt,
 	})
 
-	// Use a basic tool-less call
-	stream, err := a.prov.Chat(ctx, msgs, nil)
+	// Use a basic tool-less, low-temperature call: we want extraction, not invention.
+	ctx = provider.WithCallSource(ctx, "learnings")
+	stream, err := a.prov.Chat(ctx, msgs, nil,
+		provider.WithTemperature(0.1),
+		provider.WithMaxTokens(1024),
+		provider.WithResponseSchema("learnings", learningsSchema),
+	)
 	if err != nil {
 		return fmt.Errorf("extraction chat failed: %w", err)
 	}
@@ -61,6 +66,9 @@ If nothing worth saving is found, return { "learnings": [] }.
 		if chunk.Error != nil {
 			return chunk.Error
 		}
+		if chunk.Restart != nil {
+			textBuf.Reset()
+		}
 		textBuf.WriteString(chunk.Delta)
 	}
 	response := textBuf.String()
@@ -93,6 +101,24 @@ If nothing worth saving is found, return { "learnings": [] }.
 	return nil
 }
 
+var learningsSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"learnings": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"category": map[string]any{"type": "string"},
+					"content":  map[string]any{"type": "string"},
+				},
+				"required": []string{"category", "content"},
+			},
+		},
+	},
+	"required": []string{"learnings"},
+}
+
 // cleanJSON helper to strip markdown code blocks
 func cleanJSON(s string) string {
 	s = strings.TrimSpace(s)
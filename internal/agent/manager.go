@@ -87,6 +87,10 @@ func (am *AgentManager) Spawn(ctx context.Context, task string, contextFiles []s
 	}
 
 	id := int(am.nextID.Add(1))
+	if provider.CallSource(ctx) == "" {
+		// Callers like the judge tool label their own sub-agents.
+		ctx = provider.WithCallSource(ctx, "subagent")
+	}
 	subCtx, cancel := context.WithCancel(ctx)
 
 	info := &subAgentInfo{
@@ -237,6 +241,26 @@ func (am *AgentManager) Get(id int) (types.AgentInfo, bool) {
 	}, true
 }
 
+// Complete implements types.Completer: one call to the provider with no
+// tools, taking its chat options from ctx.
+func (am *AgentManager) Complete(ctx context.Context, prompt string) (string, error) {
+	stream, err := am.prov.Chat(ctx, []provider.Message{{Role: provider.RoleUser, Content: prompt}}, nil)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for chunk := range stream {
+		if chunk.Error != nil {
+			return "", chunk.Error
+		}
+		if chunk.Restart != nil {
+			sb.Reset()
+		}
+		sb.WriteString(chunk.Delta)
+	}
+	return sb.String(), nil
+}
+
 // Cleanup removes completed/failed/cancelled agents older than the given duration.
 func (am *AgentManager) Cleanup(maxAge time.Duration) int {
 	am.mu.Lock()
@@ -16,7 +16,7 @@ type MockProviderGate struct {
 	index     int
 }
 
-func (m *MockProviderGate) Chat(ctx context.Context, msgs []provider.Message, toolDefs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderGate) Chat(ctx context.Context, msgs []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	go func() {
 		if m.index < len(m.responses) {
@@ -16,7 +16,7 @@ type MockRecProvider struct {
 	callCount int
 }
 
-func (m *MockRecProvider) Chat(ctx context.Context, history []provider.Message, tools []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockRecProvider) Chat(ctx context.Context, history []provider.Message, tools []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 
 	if m.callCount >= len(m.responses) {
@@ -21,7 +21,13 @@ type ProfileOverride struct {
 	Tier            *int               `yaml:"tier,omitempty"`
 	PromptStrategy  *string            `yaml:"prompt_strategy,omitempty"`
 	ValidationLevel *ValidationLevel   `yaml:"validation_level,omitempty"`
+	SupportsVision  *bool              `yaml:"supports_vision,omitempty"`
 	Skillsets       map[string]float64 `yaml:"skillsets,omitempty"`
+
+	// Calibrated by a capability probe; see ProbeModel.
+	SupportsNativeFC *bool        `yaml:"supports_native_fc,omitempty"`
+	MaxTokens        *int         `yaml:"max_tokens,omitempty"`
+	Probe            *ProbeResult `yaml:"probe,omitempty"` // How the values were measured
 }
 
 // GlobalSettings contains global configuration
@@ -138,6 +144,33 @@ func MergeProfiles(defaults map[string]ModelProfile, config *UserConfig) map[str
 	return merged
 }
 
+// ResolveProfile returns the profile for modelName: the default or custom
+// profile when there is one, otherwise detect's guess, with the user's
+// override for the model applied either way.
+func ResolveProfile(config *UserConfig, modelName string, detect func(string) ModelProfile) ModelProfile {
+	if profile, ok := MergeProfiles(DefaultProfiles(), config)[modelName]; ok {
+		return profile
+	}
+	profile := detect(modelName)
+	if override, ok := config.Overrides[modelName]; ok {
+		profile = applyOverride(profile, override)
+	}
+	return profile
+}
+
+// NeedsProbe reports whether nothing is known about modelName beyond the
+// generic guess: it has no predefined or custom profile and no override.
+func NeedsProbe(config *UserConfig, modelName string) bool {
+	if _, ok := config.Models[modelName]; ok {
+		return false
+	}
+	if _, ok := config.Overrides[modelName]; ok {
+		return false
+	}
+	_, known := matchDefaultProfile(modelName)
+	return !known
+}
+
 // applyOverride applies an override to a profile
 func applyOverride(profile ModelProfile, override ProfileOverride) ModelProfile {
 	if override.Tier != nil {
@@ -149,6 +182,15 @@ func applyOverride(profile ModelProfile, override ProfileOverride) ModelProfile
 	if override.ValidationLevel != nil {
 		profile.ValidationLevel = *override.ValidationLevel
 	}
+	if override.SupportsVision != nil {
+		profile.SupportsVision = *override.SupportsVision
+	}
+	if override.SupportsNativeFC != nil {
+		profile.SupportsNativeFC = *override.SupportsNativeFC
+	}
+	if override.MaxTokens != nil {
+		profile.MaxTokens = *override.MaxTokens
+	}
 	if override.Skillsets != nil {
 		for skill, proficiency := range override.Skillsets {
 			profile.Skillsets[skill] = proficiency
@@ -0,0 +1,328 @@
+package skillsets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+)
+
+// ProbeResult is what a capability probe measured. Rates are the fraction
+// of prompts answered correctly.
+type ProbeResult struct {
+	Provider    string    `yaml:"provider"`
+	Time        time.Time `yaml:"time"`
+	NativeTools float64   `yaml:"native_tools"`           // Correct native tool calls
+	TextTools   float64   `yaml:"text_tools"`             // Correct [TOOL:...] or JSON calls written as text
+	JSON        float64   `yaml:"json"`                   // Bare JSON objects matching the requested shape
+	Context     int       `yaml:"context"`                // Longest prompt, in tokens, whose buried fact was recalled
+	RecallFail  int       `yaml:"recall_fail,omitempty"`  // Shortest prompt whose fact was missed; 0 if none
+	ContextFail int       `yaml:"context_fail,omitempty"` // Shortest prompt rejected as too long; 0 if none
+
+	// Error is why an automatic probe didn't run to the end; the rates are
+	// then unset. It keeps the model from being probed on every launch.
+	Error string `yaml:"error,omitempty"`
+}
+
+// probeTool is a canned native tool-calling prompt.
+type probeTool struct {
+	prompt string
+	tool   provider.ToolDef
+	arg    string // Argument that must be set ...
+	want   string // ... to a value containing this, case-insensitively
+}
+
+var probeTools = []probeTool{
+	{
+		prompt: "What is the weather in Paris right now? Use the get_weather tool.",
+		tool: provider.ToolDef{
+			Name:        "get_weather",
+			Description: "Get the current weather for a city",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string", "description": "City name"}},
+				"required":   []string{"city"},
+			},
+		},
+		arg: "city", want: "paris",
+	},
+	{
+		prompt: "Show me the contents of /etc/hostname.",
+		tool: provider.ToolDef{
+			Name:        "file_read",
+			Description: "Read a file from disk",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"path": map[string]any{"type": "string", "description": "Absolute file path"}},
+				"required":   []string{"path"},
+			},
+		},
+		arg: "path", want: "/etc/hostname",
+	},
+}
+
+// probeTextSystem teaches the text tool format the agent falls back to.
+const probeTextSystem = "You can call tools by writing [TOOL:<name>|<json_args>] on its own line and nothing else. " +
+	"Available tools:\n- get_weather: {\"city\": string}\n- file_read: {\"path\": string}"
+
+// probeJSON is a canned structured-output prompt and the keys the reply
+// must have.
+var probeJSON = []struct {
+	prompt string
+	keys   []string
+}{
+	{`Reply with only a JSON object with keys "name" (string) and "age" (number) describing a fictional 30 year old called Ada. No prose, no code fences.`, []string{"name", "age"}},
+	{`Reply with only a JSON object with keys "files" (array of strings) and "count" (number) listing three made-up Go file names. No prose, no code fences.`, []string{"files", "count"}},
+}
+
+// probeContextSizes are the long-context prompt lengths tried, in tokens.
+var probeContextSizes = []int{2000, 8000, 30000}
+
+// ProbeModel measures how prov's model handles native tool calls, text tool
+// calls, JSON output and long prompts. maxTokens, the model's context window
+// as far as is known, bounds the long-context prompts. Only an unusable
+// backend is an error; a model that gets everything wrong is a result.
+func ProbeModel(ctx context.Context, prov provider.Provider, maxTokens int) (*ProbeResult, error) {
+	ctx = provider.WithCallSource(ctx, "probe")
+	ctx = provider.WithChatOptions(ctx, provider.WithTemperature(0))
+	r := &ProbeResult{Provider: prov.Name(), Time: time.Now()}
+
+	// Plain prompts go first: if they fail, the backend is the problem.
+	passed := 0
+	for _, p := range probeJSON {
+		text, _, err := probeChat(ctx, prov, []provider.Message{{Role: provider.RoleUser, Content: p.prompt}}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if jsonHasKeys(text, p.keys) {
+			passed++
+		}
+	}
+	r.JSON = float64(passed) / float64(len(probeJSON))
+
+	native, text := 0, 0
+	for _, p := range probeTools {
+		// Some servers reject tools outright for models without a tool
+		// template; that is a failed probe, not a broken backend.
+		_, calls, err := probeChat(ctx, prov, []provider.Message{{Role: provider.RoleUser, Content: p.prompt}}, []provider.ToolDef{p.tool})
+		if err == nil && len(calls) > 0 && p.matches(calls[0].Name, calls[0].Args) {
+			native++
+		}
+
+		msgs := []provider.Message{
+			{Role: provider.RoleSystem, Content: probeTextSystem},
+			{Role: provider.RoleUser, Content: p.prompt},
+		}
+		reply, _, err := probeChat(ctx, prov, msgs, nil)
+		if err != nil {
+			return nil, err
+		}
+		if name, args, ok := parseTextToolCall(reply); ok && p.matches(name, args) {
+			text++
+		}
+	}
+	r.NativeTools = float64(native) / float64(len(probeTools))
+	r.TextTools = float64(text) / float64(len(probeTools))
+
+	for _, size := range probeContextSizes {
+		if maxTokens > 0 && size > maxTokens*3/4 {
+			break
+		}
+		code := fmt.Sprintf("%06d", rand.Intn(1000000))
+		msgs := []provider.Message{{Role: provider.RoleUser, Content: haystack(size, code)}}
+		reply, _, err := probeChat(ctx, prov, msgs, nil)
+		if provider.IsContextLengthError(err) {
+			r.ContextFail = size
+			break
+		}
+		if err != nil {
+			// A timeout or outage says nothing about the window.
+			break
+		}
+		if !strings.Contains(reply, code) {
+			r.RecallFail = size
+			break
+		}
+		r.Context = size
+	}
+	return r, nil
+}
+
+func (p probeTool) matches(name, args string) bool {
+	if name != p.tool.Name {
+		return false
+	}
+	var parsed map[string]any
+	if json.Unmarshal([]byte(args), &parsed) != nil {
+		return false
+	}
+	v, _ := parsed[p.arg].(string)
+	return strings.Contains(strings.ToLower(v), p.want)
+}
+
+// probeChat sends one request and collects the reply.
+func probeChat(ctx context.Context, prov provider.Provider, msgs []provider.Message, tools []provider.ToolDef) (string, []provider.ToolCall, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	ch, err := prov.Chat(ctx, msgs, tools)
+	if err != nil {
+		return "", nil, err
+	}
+	var text strings.Builder
+	var calls []provider.ToolCall
+	for chunk := range ch {
+		if chunk.Error != nil {
+			err = chunk.Error
+		}
+		if chunk.Restart != nil {
+			text.Reset()
+			calls = nil
+		}
+		text.WriteString(chunk.Delta)
+		calls = append(calls, chunk.ToolCalls...)
+	}
+	return text.String(), calls, err
+}
+
+var textToolRe = regexp.MustCompile(`\[TOOL:(\w+)\|(.+?)\]`)
+
+// parseTextToolCall finds a tool call written as text, in either of the
+// forms the agent accepts: [TOOL:name|args] or {"name": ..., "arguments": ...}.
+func parseTextToolCall(text string) (name, args string, ok bool) {
+	if m := textToolRe.FindStringSubmatch(text); m != nil {
+		return m[1], m[2], true
+	}
+	if i := strings.Index(text, "{"); i >= 0 {
+		var raw struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if json.NewDecoder(strings.NewReader(text[i:])).Decode(&raw) == nil && raw.Name != "" {
+			args := string(raw.Arguments)
+			// Arguments may be an object or a JSON-encoded string.
+			var s string
+			if json.Unmarshal(raw.Arguments, &s) == nil {
+				args = s
+			}
+			return raw.Name, args, true
+		}
+	}
+	return "", "", false
+}
+
+// jsonHasKeys reports whether text is a bare JSON object with every key.
+// Surrounding whitespace is allowed; prose and code fences are not, since
+// the agent's parsers would choke on them too.
+func jsonHasKeys(text string, keys []string) bool {
+	var obj map[string]any
+	if json.Unmarshal([]byte(strings.TrimSpace(text)), &obj) != nil {
+		return false
+	}
+	for _, k := range keys {
+		if _, ok := obj[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// haystack builds a prompt of about size tokens with code buried in the
+// middle, asking for it back.
+func haystack(size int, code string) string {
+	const filler = "Ledger %d of the harbour office records routine cargo, fair weather and no incidents. "
+	var b strings.Builder
+	b.WriteString("Read the following records carefully.\n\n")
+	lines := size * 4 / len(filler) // About four characters per token
+	for i := 0; i < lines; i++ {
+		if i == lines/2 {
+			fmt.Fprintf(&b, "The vault code is %s. ", code)
+		}
+		fmt.Fprintf(&b, filler, i+1)
+	}
+	b.WriteString("\n\nWhat is the vault code? Reply with the number only.")
+	return b.String()
+}
+
+// Calibrate turns a probe result into an override of base, the profile the
+// model would otherwise get.
+func (r *ProbeResult) Calibrate(base ModelProfile) ProfileOverride {
+	native := r.NativeTools >= 0.5
+	tools := max(r.NativeTools, r.TextTools)
+	score := (2*tools + r.JSON) / 3
+
+	// A probe this small can't tell a tier 1 model from a tier 2 one, so it
+	// never promotes past 2 but keeps a predefined 1.
+	tier := 4
+	switch {
+	case score >= 0.9 && native:
+		tier = max(min(base.Tier, 2), 1)
+	case score >= 0.6:
+		tier = 3
+	}
+	strategy := map[int]string{1: "minimal", 2: "react", 3: "guided", 4: "template"}[tier]
+
+	o := ProfileOverride{
+		Tier:             &tier,
+		PromptStrategy:   &strategy,
+		SupportsNativeFC: &native,
+		Probe:            r,
+	}
+	// Only a prompt the backend rejected as too long says anything about
+	// the usable context: a missed recall may be the model, not the window.
+	if r.ContextFail > 0 && r.Context > 0 && (base.MaxTokens == 0 || r.Context < base.MaxTokens) {
+		maxTokens := r.Context
+		o.MaxTokens = &maxTokens
+	}
+	return o
+}
+
+// SaveProbe stores the calibrated override for modelName in the user
+// config. Fields the probe doesn't measure, such as skillset proficiencies,
+// keep whatever the user set.
+func SaveProbe(modelName string, o ProfileOverride) error {
+	if modelName == "" {
+		return fmt.Errorf("no model name to save the probe under")
+	}
+	config, err := LoadUserConfig()
+	if err != nil {
+		return err
+	}
+	if config.Overrides == nil {
+		config.Overrides = make(map[string]ProfileOverride)
+	}
+	merged := config.Overrides[modelName]
+	merged.Tier = o.Tier
+	merged.PromptStrategy = o.PromptStrategy
+	merged.SupportsNativeFC = o.SupportsNativeFC
+	if o.MaxTokens != nil {
+		merged.MaxTokens = o.MaxTokens
+	}
+	merged.Probe = o.Probe
+	config.Overrides[modelName] = merged
+	return SaveUserConfig(config)
+}
+
+// SaveProbeFailure records that the automatic probe of modelName failed or
+// was declined, so it isn't tried again on every launch. The profile is left
+// as it was; `aseity probe` can still calibrate it.
+func SaveProbeFailure(modelName, providerName, reason string) error {
+	if modelName == "" {
+		return fmt.Errorf("no model name to save the probe under")
+	}
+	config, err := LoadUserConfig()
+	if err != nil {
+		return err
+	}
+	if config.Overrides == nil {
+		config.Overrides = make(map[string]ProfileOverride)
+	}
+	merged := config.Overrides[modelName]
+	merged.Probe = &ProbeResult{Provider: providerName, Time: time.Now(), Error: reason}
+	config.Overrides[modelName] = merged
+	return SaveUserConfig(config)
+}
@@ -0,0 +1,221 @@
+package skillsets
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+)
+
+// scriptedModel answers probe prompts the way a model with the given
+// abilities would.
+type scriptedModel struct {
+	nativeTools bool
+	textTools   bool
+	json        bool
+	recall      int   // Longest prompt, in characters, it can recall from
+	window      int   // Longest prompt, in characters, the backend accepts; 0 for any
+	longErr     error // Returned for every long-context prompt, if set
+}
+
+var vaultCode = regexp.MustCompile(`vault code is (\d+)`)
+
+func (m *scriptedModel) Name() string      { return "scripted" }
+func (m *scriptedModel) ModelName() string { return "scripted-model" }
+func (m *scriptedModel) Models(ctx context.Context) ([]string, error) {
+	return []string{m.ModelName()}, nil
+}
+
+func (m *scriptedModel) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, opts ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
+	prompt := msgs[len(msgs)-1].Content
+	var chunk provider.StreamChunk
+	switch {
+	case len(tools) > 0:
+		if m.nativeTools {
+			args := `{"city": "Paris"}`
+			if tools[0].Name == "file_read" {
+				args = `{"path": "/etc/hostname"}`
+			}
+			chunk.ToolCalls = []provider.ToolCall{{ID: "1", Name: tools[0].Name, Args: args}}
+		} else {
+			chunk.Delta = "I would check the weather for you."
+		}
+	case msgs[0].Role == provider.RoleSystem:
+		chunk.Delta = "Sure."
+		if m.textTools && strings.Contains(prompt, "Paris") {
+			chunk.Delta = `[TOOL:get_weather|{"city": "Paris"}]`
+		} else if m.textTools {
+			chunk.Delta = `{"name": "file_read", "arguments": {"path": "/etc/hostname"}}`
+		}
+	case strings.Contains(prompt, "JSON object"):
+		chunk.Delta = "```json\n{\"name\": \"Ada\"}\n```"
+		if m.json {
+			chunk.Delta = `{"name": "Ada", "age": 30, "files": ["a.go"], "count": 1}`
+		}
+	case m.longErr != nil:
+		chunk.Error = m.longErr
+	case m.window > 0 && len(prompt) > m.window:
+		chunk.Error = &provider.Error{Provider: "scripted", StatusCode: 400, Code: "context_length_exceeded", Message: "prompt too long"}
+	default:
+		chunk.Delta = "I don't know."
+		if match := vaultCode.FindStringSubmatch(prompt); match != nil && len(prompt) <= m.recall {
+			chunk.Delta = match[1]
+		}
+	}
+	ch := make(chan provider.StreamChunk, 2)
+	ch <- chunk
+	ch <- provider.StreamChunk{Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func TestProbeModel_Capable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	model := &scriptedModel{nativeTools: true, textTools: true, json: true, recall: 1 << 30}
+	r, err := ProbeModel(context.Background(), model, 128000)
+	if err != nil {
+		t.Fatalf("ProbeModel: %v", err)
+	}
+	if r.NativeTools != 1 || r.TextTools != 1 || r.JSON != 1 || r.Context != 30000 || r.ContextFail != 0 {
+		t.Errorf("result = %+v", r)
+	}
+
+	o := r.Calibrate(DetectModelProfile("mystery-model"))
+	if *o.Tier != 2 || *o.PromptStrategy != "react" || !*o.SupportsNativeFC || o.MaxTokens != nil {
+		t.Errorf("override: tier %d, strategy %s, native %v, max tokens %v", *o.Tier, *o.PromptStrategy, *o.SupportsNativeFC, o.MaxTokens)
+	}
+
+	if err := SaveProbe("mystery-model", o); err != nil {
+		t.Fatalf("SaveProbe: %v", err)
+	}
+	config, err := LoadUserConfig()
+	if err != nil {
+		t.Fatalf("LoadUserConfig: %v", err)
+	}
+	if NeedsProbe(config, "mystery-model") {
+		t.Error("a probed model should not be probed again")
+	}
+	profile := ResolveProfile(config, "mystery-model", DetectModelProfile)
+	if profile.Tier != 2 || profile.PromptStrategy != "react" || !profile.SupportsNativeFC {
+		t.Errorf("resolved profile = %+v", profile)
+	}
+}
+
+func TestProbeModel_Weak(t *testing.T) {
+	// Text tool calls only, JSON in code fences, and lost beyond ~2k tokens.
+	model := &scriptedModel{textTools: true, recall: 10000}
+	r, err := ProbeModel(context.Background(), model, 32768)
+	if err != nil {
+		t.Fatalf("ProbeModel: %v", err)
+	}
+	if r.NativeTools != 0 || r.TextTools != 1 || r.JSON != 0 || r.Context != 2000 || r.RecallFail != 8000 || r.ContextFail != 0 {
+		t.Errorf("result = %+v", r)
+	}
+
+	o := r.Calibrate(DetectModelProfile("mystery-model"))
+	if *o.Tier != 3 || *o.PromptStrategy != "guided" || *o.SupportsNativeFC {
+		t.Errorf("override: tier %d, strategy %s, native %v", *o.Tier, *o.PromptStrategy, *o.SupportsNativeFC)
+	}
+	if o.MaxTokens != nil {
+		t.Errorf("max tokens = %d; a missed recall should not lower the window", *o.MaxTokens)
+	}
+}
+
+func TestProbeModel_ContextErrors(t *testing.T) {
+	// Rejected as too long beyond ~2k tokens: the window is lowered.
+	model := &scriptedModel{json: true, recall: 1 << 30, window: 10000}
+	r, err := ProbeModel(context.Background(), model, 128000)
+	if err != nil {
+		t.Fatalf("ProbeModel: %v", err)
+	}
+	if r.Context != 2000 || r.ContextFail != 8000 {
+		t.Errorf("context %d, fail %d", r.Context, r.ContextFail)
+	}
+	if o := r.Calibrate(DetectModelProfile("mystery-model")); o.MaxTokens == nil || *o.MaxTokens != 2000 {
+		t.Errorf("max tokens = %v, want 2000", o.MaxTokens)
+	}
+
+	// Any other error says nothing about the window.
+	model = &scriptedModel{json: true, longErr: &provider.Error{Provider: "scripted", Message: "deadline exceeded", Retryable: true}}
+	if r, err = ProbeModel(context.Background(), model, 128000); err != nil {
+		t.Fatalf("ProbeModel: %v", err)
+	}
+	if r.ContextFail != 0 || r.RecallFail != 0 {
+		t.Errorf("a timeout was taken as a context failure: %+v", r)
+	}
+	if o := r.Calibrate(DetectModelProfile("mystery-model")); o.MaxTokens != nil {
+		t.Errorf("max tokens = %d after a timeout", *o.MaxTokens)
+	}
+}
+
+func TestSaveProbeFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SaveProbeFailure("", "ollama", "boom"); err == nil {
+		t.Error("saving under an empty model name should fail")
+	}
+	if err := SaveProbeFailure("mystery-model", "ollama", "connection refused"); err != nil {
+		t.Fatalf("SaveProbeFailure: %v", err)
+	}
+	config, err := LoadUserConfig()
+	if err != nil {
+		t.Fatalf("LoadUserConfig: %v", err)
+	}
+	if NeedsProbe(config, "mystery-model") {
+		t.Error("a failed probe should not be retried on the next launch")
+	}
+	if p, want := ResolveProfile(config, "mystery-model", DetectModelProfile), DetectModelProfile("mystery-model"); p.Tier != want.Tier || p.MaxTokens != want.MaxTokens || p.SupportsNativeFC != want.SupportsNativeFC {
+		t.Errorf("a failed probe changed the profile: %+v", p)
+	}
+}
+
+func TestSaveProbe_KeepsMaxTokens(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := DefaultUserConfig()
+	maxTokens := 16384
+	config.Overrides["mystery-model"] = ProfileOverride{MaxTokens: &maxTokens}
+	if err := SaveUserConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	// A probe without a context-length error doesn't measure the window.
+	tier := 2
+	if err := SaveProbe("mystery-model", ProfileOverride{Tier: &tier}); err != nil {
+		t.Fatalf("SaveProbe: %v", err)
+	}
+	loaded, err := LoadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := loaded.Overrides["mystery-model"]
+	if o.MaxTokens == nil || *o.MaxTokens != 16384 || o.Tier == nil || *o.Tier != 2 {
+		t.Errorf("override = %+v, want the user's max_tokens kept", o)
+	}
+}
+
+func TestProbeModel_ContextBoundedByWindow(t *testing.T) {
+	model := &scriptedModel{nativeTools: true, json: true, recall: 1 << 30}
+	r, err := ProbeModel(context.Background(), model, 8192)
+	if err != nil {
+		t.Fatalf("ProbeModel: %v", err)
+	}
+	if r.Context != 2000 || r.ContextFail != 0 {
+		t.Errorf("context %d, fail %d: prompts should stop below the window", r.Context, r.ContextFail)
+	}
+}
+
+func TestNeedsProbe(t *testing.T) {
+	config := DefaultUserConfig()
+	if NeedsProbe(&config, "gpt-4o-mini") {
+		t.Error("a predefined family should not need a probe")
+	}
+	if !NeedsProbe(&config, "mystery-model") {
+		t.Error("an unknown model should need a probe")
+	}
+	tier := 2
+	config.Overrides["mystery-model"] = ProfileOverride{Tier: &tier}
+	if NeedsProbe(&config, "mystery-model") {
+		t.Error("a model with an override should not need a probe")
+	}
+}
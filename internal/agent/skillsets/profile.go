@@ -1,5 +1,11 @@
 package skillsets
 
+import (
+	"strings"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+)
+
 // ModelProfile defines capabilities and configuration for a specific model
 type ModelProfile struct {
 	Name             string             `yaml:"name"`
@@ -9,6 +15,7 @@ type ModelProfile struct {
 	ValidationLevel  ValidationLevel    `yaml:"validation_level"`
 	MaxTokens        int                `yaml:"max_tokens"`
 	SupportsNativeFC bool               `yaml:"supports_native_fc"` // Native function calling
+	SupportsVision   bool               `yaml:"supports_vision"`    // Accepts image content parts
 }
 
 // ValidationLevel determines how strictly to validate tool calls
@@ -88,6 +95,7 @@ func DefaultProfiles() map[string]ModelProfile {
 			ValidationLevel:  ValidationLight,
 			MaxTokens:        128000,
 			SupportsNativeFC: true,
+			SupportsVision:   true,
 			Skillsets: map[string]float64{
 				SkillToolSelection:      0.99,
 				SkillParameterConstruct: 0.99,
@@ -110,6 +118,7 @@ func DefaultProfiles() map[string]ModelProfile {
 			ValidationLevel:  ValidationLight,
 			MaxTokens:        200000,
 			SupportsNativeFC: true,
+			SupportsVision:   true,
 			Skillsets: map[string]float64{
 				SkillToolSelection:      0.98,
 				SkillParameterConstruct: 0.99,
@@ -268,20 +277,10 @@ func DefaultProfiles() map[string]ModelProfile {
 
 // DetectModelProfile returns a profile for the given model name
 func DetectModelProfile(modelName string) ModelProfile {
-	profiles := DefaultProfiles()
-
-	// Exact match
-	if profile, ok := profiles[modelName]; ok {
+	if profile, ok := matchDefaultProfile(modelName); ok {
 		return profile
 	}
 
-	// Fuzzy match (e.g., "gpt-4-turbo" -> "gpt-4")
-	for key, profile := range profiles {
-		if len(modelName) >= len(key) && modelName[:len(key)] == key {
-			return profile
-		}
-	}
-
 	// Default to Tier 3 (Basic) for unknown models
 	return ModelProfile{
 		Name:             modelName,
@@ -290,6 +289,7 @@ func DetectModelProfile(modelName string) ModelProfile {
 		ValidationLevel:  ValidationMedium,
 		MaxTokens:        8192,
 		SupportsNativeFC: false,
+		SupportsVision:   isVisionModel(modelName),
 		Skillsets: map[string]float64{
 			SkillToolSelection:      0.70,
 			SkillParameterConstruct: 0.75,
@@ -307,6 +307,58 @@ func DetectModelProfile(modelName string) ModelProfile {
 	}
 }
 
+// matchDefaultProfile finds the predefined profile for a model name, exactly
+// or by prefix (e.g., "gpt-4-turbo" -> "gpt-4").
+func matchDefaultProfile(modelName string) (ModelProfile, bool) {
+	profiles := DefaultProfiles()
+
+	// Exact match
+	if profile, ok := profiles[modelName]; ok {
+		return profile, true
+	}
+
+	// Fuzzy match
+	for key, profile := range profiles {
+		if len(modelName) >= len(key) && modelName[:len(key)] == key {
+			profile.SupportsVision = profile.SupportsVision || isVisionModel(modelName)
+			return profile, true
+		}
+	}
+	return ModelProfile{}, false
+}
+
+// DetectModelProfileWithInfo detects a profile by name, then refines the
+// name-based guesses with what the provider reports: the context window,
+// image input and native tool calling. ModelInfo's zero values mean the
+// provider didn't say, so they leave the guesses alone.
+func DetectModelProfileWithInfo(info provider.ModelInfo) ModelProfile {
+	profile := DetectModelProfile(info.ID)
+	if info.ContextWindow > 0 {
+		profile.MaxTokens = info.ContextWindow
+	}
+	profile.SupportsVision = profile.SupportsVision || info.Vision
+	profile.SupportsNativeFC = profile.SupportsNativeFC || info.Tools
+	return profile
+}
+
+// visionModelMarkers are name fragments of model families that accept images.
+var visionModelMarkers = []string{
+	"vision", "llava", "bakllava", "moondream", "minicpm-v", "-vl",
+	"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-5", "claude-3", "claude-sonnet-4", "claude-opus-4",
+	"gemini", "pixtral", "gemma3",
+}
+
+// isVisionModel guesses from the model name whether it accepts image input.
+func isVisionModel(modelName string) bool {
+	name := strings.ToLower(modelName)
+	for _, marker := range visionModelMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetWeakSkillsets returns skillsets with proficiency below threshold
 func (p *ModelProfile) GetWeakSkillsets(threshold float64) []string {
 	weak := []string{}
@@ -2,6 +2,8 @@ package skillsets
 
 import (
 	"testing"
+
+	"github.com/jeanpaul/aseity/internal/provider"
 )
 
 func TestDetectModelProfile(t *testing.T) {
@@ -40,6 +42,40 @@ func TestDetectModelProfile(t *testing.T) {
 	}
 }
 
+func TestDetectModelProfile_Vision(t *testing.T) {
+	tests := map[string]bool{
+		"gpt-4o":              true,
+		"gpt-4o-mini":         true,
+		"claude-3.5-sonnet":   true,
+		"llama3.2-vision:11b": true,
+		"qwen2.5-vl:7b":       true,
+		"gpt-4":               false,
+		"qwen2.5-coder:7b":    false,
+	}
+	for name, want := range tests {
+		if got := DetectModelProfile(name).SupportsVision; got != want {
+			t.Errorf("%s: SupportsVision = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestDetectModelProfileWithInfo(t *testing.T) {
+	// A model newer than any name heuristic: the listing is trusted instead.
+	p := DetectModelProfileWithInfo(provider.ModelInfo{ID: "gemini-3-pro", ContextWindow: 1048576, Vision: true, Tools: true})
+	if p.MaxTokens != 1048576 || !p.SupportsVision || !p.SupportsNativeFC {
+		t.Errorf("profile = %+v, want context window and capabilities from the listing", p)
+	}
+	if p.Tier != DetectModelProfile("gemini-3-pro").Tier {
+		t.Error("tier should still come from name detection")
+	}
+
+	// A listing that doesn't report image input keeps the name-based guess.
+	p = DetectModelProfileWithInfo(provider.ModelInfo{ID: "llava:13b", ContextWindow: 4096})
+	if !p.SupportsVision {
+		t.Error("an unreported capability overrode the name-based vision guess")
+	}
+}
+
 func TestGetWeakSkillsets(t *testing.T) {
 	profile := DetectModelProfile("qwen2.5:14b")
 	weak := profile.GetWeakSkillsets(0.80)
@@ -81,8 +81,25 @@ func validateParameters(call provider.ToolCall) error {
 		if _, ok := params["path"]; !ok {
 			return fmt.Errorf("file_write tool requires 'path' parameter")
 		}
-		if _, ok := params["content"]; !ok {
-			return fmt.Errorf("file_write tool requires 'content' parameter")
+		_, hasContent := params["content"]
+		_, hasOld := params["old_string"]
+		_, hasEdits := params["edits"]
+		if !hasContent && !hasOld && !hasEdits {
+			return fmt.Errorf("file_write tool requires 'content', 'old_string' or 'edits' parameter")
+		}
+
+	case "apply_patch":
+		if patch, _ := params["patch"].(string); strings.TrimSpace(patch) == "" {
+			return fmt.Errorf("apply_patch tool requires a non-empty 'patch' parameter")
+		}
+
+	case "code_symbols":
+		action, _ := params["action"].(string)
+		if action == "" {
+			return fmt.Errorf("code_symbols tool requires an 'action' parameter")
+		}
+		if name, _ := params["name"].(string); action != "list" && strings.TrimSpace(name) == "" {
+			return fmt.Errorf("code_symbols %s requires a 'name' parameter", action)
 		}
 
 	case "web_search":
@@ -62,8 +62,10 @@ Examples:
 
 ### File Operations
 - **file_read**: Read file contents with line numbers. Use before editing. Max 10MB, 2000 lines default.
-- **file_write**: Write or edit files. Use old_string/new_string for targeted edits, or content for full overwrite.
-- **file_search**: Search for files (pattern) or search within files (grep).
+- **file_write**: Write or edit files. Use old_string/new_string for targeted edits, edits for several changes to one file at once, or content for full overwrite.
+- **apply_patch**: Apply a unified diff or *** Begin Patch envelope across one or more files. Nothing is written unless every hunk applies.
+- **file_search**: Find files by glob (pattern, e.g. '**/*.go') and/or search within files by regex (grep), with context lines. Skips ignored files; pass the returned cursor to continue a truncated search.
+- **code_symbols**: Navigate code by symbol: list a file's or package's declarations, jump to a definition, get one function's source with its doc comment, or find references. Prefer it to reading whole files when you need one function.
 
 ### Shell / OS Commands
 - **bash**: Execute any operating system command via bash. This is your primary tool for interacting with the local system. Use it for:
@@ -54,7 +54,11 @@ OUTPUT ONLY: "VALID" or "INVALID: [Reason]"
 	}
 
 	// We don't want the validator to call tools, just text.
-	stream, err := v.prov.Chat(ctx, msgs, nil)
+	// A verdict is a single short line, so keep it deterministic. The limit
+	// still leaves room for thinking models, whose reasoning counts against
+	// it before the verdict is written.
+	ctx = provider.WithCallSource(ctx, "validator")
+	stream, err := v.prov.Chat(ctx, msgs, nil, provider.WithTemperature(0), provider.WithMaxTokens(4096))
 	if err != nil {
 		// If validation fails technically, we default to allow (fail open) or block (fail closed).
 		// For now, let's log and allow to avoid blocking valid work due to API errors.
@@ -66,6 +70,9 @@ OUTPUT ONLY: "VALID" or "INVALID: [Reason]"
 		if chunk.Error != nil {
 			return true, ""
 		}
+		if chunk.Restart != nil {
+			response = ""
+		}
 		response += chunk.Delta
 	}
 
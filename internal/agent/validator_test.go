@@ -12,7 +12,7 @@ type MockProvider struct {
 	Response string
 }
 
-func (m *MockProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk, 1)
 	go func() {
 		ch <- provider.StreamChunk{Delta: m.Response, Done: true}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeanpaul/aseity/internal/config"
+	"github.com/jeanpaul/aseity/internal/tools"
+)
+
+func TestAgent_WorkspaceEscalation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	path := filepath.Join(t.TempDir(), "notes.txt")
+
+	reg := tools.NewRegistry(nil, true)
+	reg.Register(&tools.FileWriteTool{})
+	w, err := tools.NewWorkspace(config.WorkspaceConfig{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.SetWorkspace(w)
+	agent := New(&MockProviderWriter{path: path}, reg, "")
+
+	// The registry allows everything, but leaving the workspace still asks.
+	send := func(msg string, approve bool) (asked []string) {
+		events := make(chan Event, 100)
+		go agent.Send(context.Background(), msg, events)
+		for evt := range events {
+			if evt.Type == EventConfirmRequest {
+				asked = evt.Outside
+				agent.ConfirmCh <- approve
+			}
+			if evt.Done {
+				break
+			}
+		}
+		return asked
+	}
+
+	if asked := send("denied", false); len(asked) != 1 {
+		t.Fatalf("confirm request outside = %v", asked)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file written without approval: %v", err)
+	}
+
+	send("approved", true)
+	if b, _ := os.ReadFile(path); string(b) != "approved" {
+		t.Errorf("file = %q after approval", b)
+	}
+}
@@ -0,0 +1,324 @@
+// Package checkpoint keeps the contents a file had before the agent changed
+// it, by conversation turn, so a turn's edits can be undone.
+package checkpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry is the pre-image of one file, taken the first time a turn touched it.
+type Entry struct {
+	Turn    int         `json:"turn"`
+	Path    string      `json:"path"` // Absolute
+	Tool    string      `json:"tool"`
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	Blob    string      `json:"blob,omitempty"` // Content hash, under blobs/
+	Time    time.Time   `json:"time"`
+}
+
+// Turn summarizes the files one turn changed.
+type Turn struct {
+	N     int
+	Time  time.Time
+	Files []string
+}
+
+// Store holds the checkpoints of one session in a directory: an index and
+// the pre-images, stored once per distinct content.
+type Store struct {
+	mu      sync.Mutex
+	dir     string
+	entries []Entry
+	loaded  bool
+}
+
+// DefaultDir is where sessions keep their checkpoints.
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "aseity", "checkpoints")
+}
+
+// Open returns the store for a session. Nothing is written until the first
+// snapshot.
+func Open(session string) *Store {
+	return OpenDir(filepath.Join(DefaultDir(), session))
+}
+
+func OpenDir(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) Dir() string { return s.dir }
+
+// Snapshot saves path as it is now, unless turn already saved it: undoing a
+// turn goes back to how the file was when the turn began. A symlink is
+// recorded as the file it points to, so restoring writes through the link
+// rather than replacing it with a copy.
+func (s *Store) Snapshot(turn int, tool, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	for _, e := range s.entries {
+		if e.Turn == turn && e.Path == abs {
+			return nil
+		}
+	}
+
+	e := Entry{Turn: turn, Path: abs, Tool: tool, Time: time.Now()}
+	info, err := os.Stat(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Undo removes it.
+	case err != nil:
+		return err
+	case info.IsDir():
+		return nil
+	default:
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return err
+		}
+		if e.Blob, err = s.writeBlob(data); err != nil {
+			return err
+		}
+		e.Existed, e.Mode = true, info.Mode().Perm()
+	}
+	s.entries = append(s.entries, e)
+	return s.save()
+}
+
+// Entries returns every checkpoint, oldest first.
+func (s *Store) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return append([]Entry(nil), s.entries...), nil
+}
+
+// Turns lists the turns that changed files, oldest first.
+func (s *Store) Turns() ([]Turn, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var turns []Turn
+	for _, e := range entries {
+		if n := len(turns); n == 0 || turns[n-1].N != e.Turn {
+			turns = append(turns, Turn{N: e.Turn, Time: e.Time})
+		}
+		t := &turns[len(turns)-1]
+		t.Files = append(t.Files, e.Path)
+	}
+	return turns, nil
+}
+
+// LastTurn is the most recent turn that changed files, or 0.
+func (s *Store) LastTurn() (int, error) {
+	turns, err := s.Turns()
+	if err != nil || len(turns) == 0 {
+		return 0, err
+	}
+	return turns[len(turns)-1].N, nil
+}
+
+// Restore puts every file changed in turn or later back the way it was
+// before turn, and forgets those checkpoints. It returns the restored paths.
+func (s *Store) Restore(turn int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// The earliest checkpoint of a file is its state before turn.
+	first := map[string]Entry{}
+	var keep []Entry
+	for _, e := range s.entries {
+		if e.Turn < turn {
+			keep = append(keep, e)
+			continue
+		}
+		if _, ok := first[e.Path]; !ok {
+			first[e.Path] = e
+		}
+	}
+	if len(first) == 0 {
+		return nil, fmt.Errorf("no file changes to restore from turn %d on", turn)
+	}
+
+	var paths []string
+	for path, e := range first {
+		if err := s.restore(e); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	s.entries = keep
+	if err := s.save(); err != nil {
+		return paths, err
+	}
+	s.pruneBlobs()
+	return paths, nil
+}
+
+func (s *Store) restore(e Entry) error {
+	if !e.Existed {
+		if err := os.Remove(e.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, "blobs", e.Blob))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+		return err
+	}
+	tmp := e.Path + ".aseity-restore"
+	if err := os.WriteFile(tmp, data, e.Mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.Path)
+}
+
+func (s *Store) load() error {
+	if s.loaded {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, "index.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.entries); err != nil {
+			return fmt.Errorf("checkpoint index: %w", err)
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *Store) save() error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, "index.json")
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *Store) writeBlob(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])
+	dir := filepath.Join(s.dir, "blobs")
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return name, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return name, os.WriteFile(path, data, 0600)
+}
+
+// pruneBlobs removes pre-images no checkpoint refers to any more.
+func (s *Store) pruneBlobs() {
+	used := map[string]bool{}
+	for _, e := range s.entries {
+		used[e.Blob] = true
+	}
+	dir := filepath.Join(s.dir, "blobs")
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		if !used[f.Name()] {
+			os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+}
+
+// Session is a session with checkpoints on disk.
+type Session struct {
+	ID   string
+	Time time.Time // Last change
+}
+
+// Sessions lists the sessions with checkpoints, newest first.
+func Sessions() ([]Session, error) {
+	dirs, err := os.ReadDir(DefaultDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []Session
+	for _, d := range dirs {
+		info, err := os.Stat(filepath.Join(DefaultDir(), d.Name(), "index.json"))
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		sessions = append(sessions, Session{ID: d.Name(), Time: info.ModTime()})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Time.After(sessions[j].Time) })
+	return sessions, nil
+}
+
+type turnKey struct{}
+
+type turnRef struct {
+	store *Store
+	turn  int
+}
+
+// WithTurn returns a context under which tools record the files they change
+// in store, as part of turn.
+func WithTurn(ctx context.Context, store *Store, turn int) context.Context {
+	return context.WithValue(ctx, turnKey{}, turnRef{store, turn})
+}
+
+// FromContext returns the store and turn set by WithTurn, if any.
+func FromContext(ctx context.Context) (*Store, int, bool) {
+	ref, ok := ctx.Value(turnKey{}).(turnRef)
+	return ref.store, ref.turn, ok
+}
+
+// Save snapshots path before tool changes it. Without a checkpoint store in
+// ctx, as in tests or one-off tool runs, it does nothing.
+func Save(ctx context.Context, tool, path string) error {
+	store, turn, ok := FromContext(ctx)
+	if !ok || store == nil {
+		return nil
+	}
+	return store.Snapshot(turn, tool, path)
+}
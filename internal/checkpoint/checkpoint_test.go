@@ -0,0 +1,90 @@
+package checkpoint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_RestoreTurns(t *testing.T) {
+	dir := t.TempDir()
+	store := OpenDir(filepath.Join(dir, "store"))
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("a0"), 0600)
+
+	// Turn 1 edits a twice; only the first pre-image counts.
+	ctx := WithTurn(context.Background(), store, 1)
+	Save(ctx, "file_write", a)
+	os.WriteFile(a, []byte("a1"), 0600)
+	Save(ctx, "file_write", a)
+	os.WriteFile(a, []byte("a1b"), 0600)
+
+	// Turn 2 edits a again and creates b.
+	ctx = WithTurn(context.Background(), store, 2)
+	Save(ctx, "apply_patch", a)
+	os.WriteFile(a, []byte("a2"), 0600)
+	Save(ctx, "bash", b)
+	os.WriteFile(b, []byte("b2"), 0644)
+
+	turns, err := store.Turns()
+	if err != nil || len(turns) != 2 || len(turns[0].Files) != 1 || len(turns[1].Files) != 2 {
+		t.Fatalf("turns = %+v, %v", turns, err)
+	}
+
+	paths, err := store.Restore(2)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("Restore(2) = %v, %v", paths, err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "a1b" {
+		t.Errorf("a.txt = %q, want its state after turn 1", got)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Error("b.txt did not exist before turn 2 and should be removed")
+	}
+
+	// A fresh store reads the index back from disk.
+	store = OpenDir(filepath.Join(dir, "store"))
+	if last, _ := store.LastTurn(); last != 1 {
+		t.Errorf("last turn = %d, want 1", last)
+	}
+	if _, err := store.Restore(1); err != nil {
+		t.Fatalf("Restore(1): %v", err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "a0" {
+		t.Errorf("a.txt = %q, want a0", got)
+	}
+	if info, _ := os.Stat(a); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if blobs, _ := os.ReadDir(filepath.Join(dir, "store", "blobs")); len(blobs) != 0 {
+		t.Errorf("%d pre-images left after restoring everything", len(blobs))
+	}
+}
+
+func TestStore_RestoreThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	store := OpenDir(filepath.Join(dir, "store"))
+	target, link := filepath.Join(dir, "real.conf"), filepath.Join(dir, "link.conf")
+	os.WriteFile(target, []byte("v0"), 0644)
+	os.Symlink(target, link)
+
+	Save(WithTurn(context.Background(), store, 1), "file_write", link)
+	os.WriteFile(link, []byte("v1"), 0644)
+
+	if _, err := store.Restore(1); err != nil {
+		t.Fatalf("Restore(1): %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("the symlink was replaced by a copy: %v", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "v0" {
+		t.Errorf("target = %q, want v0", got)
+	}
+}
+
+func TestSave_WithoutStore(t *testing.T) {
+	if err := Save(context.Background(), "file_write", "/nonexistent/file"); err != nil {
+		t.Errorf("Save without a store should do nothing: %v", err)
+	}
+}
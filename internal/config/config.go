@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -19,6 +20,59 @@ type Config struct {
 	Theme           string                    `yaml:"theme" mapstructure:"theme"`
 	MaxTurns        int                       `yaml:"max_turns" mapstructure:"max_turns"`
 	MaxTokens       int                       `yaml:"max_tokens" mapstructure:"max_tokens"`
+
+	// Fallback lists providers to try in order when the active one is down,
+	// overloaded or can't fit the context. Empty disables routing.
+	Fallback    []string `yaml:"fallback" mapstructure:"fallback"`
+	MaxCostTier int      `yaml:"max_cost_tier" mapstructure:"max_cost_tier"` // 0 = no cap
+
+	// Pricing overrides for /cost. A list rather than a map because model
+	// names contain dots, which the config loader treats as key separators.
+	Pricing []PriceConfig `yaml:"pricing" mapstructure:"pricing"`
+
+	Debug DebugConfig `yaml:"debug" mapstructure:"debug"`
+
+	CircuitBreaker BreakerConfig `yaml:"circuit_breaker" mapstructure:"circuit_breaker"`
+
+	Probe ProbeConfig `yaml:"probe" mapstructure:"probe"`
+}
+
+// ProbeConfig controls the capability probe offered the first time an
+// unknown model is used with a local provider. Hosted providers, which bill
+// the probe's prompts, and headless runs are never probed automatically;
+// `aseity probe` works either way.
+type ProbeConfig struct {
+	Auto string `yaml:"auto" mapstructure:"auto"` // ask (default), always or never
+}
+
+// BreakerConfig controls the per-provider circuit breaker, which stops
+// sending requests to a backend after repeated failures and probes it again
+// after a cooldown.
+type BreakerConfig struct {
+	Disabled bool          `yaml:"disabled" mapstructure:"disabled"`
+	Failures int           `yaml:"failures" mapstructure:"failures"` // Consecutive failures to open; default 3
+	Cooldown time.Duration `yaml:"cooldown" mapstructure:"cooldown"` // e.g. 30s (the default)
+}
+
+// DebugConfig controls the provider debug log (--debug-provider, /debug).
+type DebugConfig struct {
+	ProviderLog bool `yaml:"provider_log" mapstructure:"provider_log"` // Log from startup
+
+	// RedactPatterns are regular expressions for secrets to mask in the log,
+	// on top of configured API keys and common credential formats.
+	RedactPatterns []string `yaml:"redact_patterns" mapstructure:"redact_patterns"`
+}
+
+// PriceConfig overrides the built-in price of a model, in USD per million
+// tokens. With Model empty it prices every model of Provider; with Provider
+// empty it applies to the model on any provider.
+type PriceConfig struct {
+	Provider    string  `yaml:"provider" mapstructure:"provider"`
+	Model       string  `yaml:"model" mapstructure:"model"`
+	Input       float64 `yaml:"input" mapstructure:"input"`
+	CachedInput float64 `yaml:"cached_input" mapstructure:"cached_input"` // 0 = billed at the input rate
+	CacheWrite  float64 `yaml:"cache_write" mapstructure:"cache_write"`   // 0 = billed at the input rate
+	Output      float64 `yaml:"output" mapstructure:"output"`
 }
 
 type OrchestratorConfig struct {
@@ -35,12 +89,57 @@ type ProviderConfig struct {
 	BaseURL string `yaml:"base_url" mapstructure:"base_url"`
 	APIKey  string `yaml:"api_key" mapstructure:"api_key"`
 	Model   string `yaml:"model" mapstructure:"model"`
+
+	EmbeddingModel string `yaml:"embedding_model" mapstructure:"embedding_model"` // For retrieval; each type has a default
+
+	// Native Ollama options (type: ollama)
+	NumCtx      int      `yaml:"num_ctx" mapstructure:"num_ctx"`
+	Temperature *float64 `yaml:"temperature" mapstructure:"temperature"`
+	KeepAlive   string   `yaml:"keep_alive" mapstructure:"keep_alive"`
+	Think       bool     `yaml:"think" mapstructure:"think"`
+
+	// OpenAI-compatible options (type: openai)
+	Variant    string            `yaml:"variant" mapstructure:"variant"`         // azure, llamacpp or lmstudio; empty = OpenAI API
+	APIVersion string            `yaml:"api_version" mapstructure:"api_version"` // Azure api-version
+	Deployment string            `yaml:"deployment" mapstructure:"deployment"`   // Azure deployment name; defaults to model
+	Headers    map[string]string `yaml:"headers" mapstructure:"headers"`         // Extra HTTP headers
+	Query      map[string]string `yaml:"query" mapstructure:"query"`             // Extra URL query parameters
+	Extra      map[string]any    `yaml:"extra" mapstructure:"extra"`             // Extra chat request fields, e.g. grammar, n_predict
+
+	// Anthropic options (type: anthropic). ThinkingBudget also caps
+	// reasoning for Gemini thinking models, where 0 keeps the model default.
+	ThinkingBudget int   `yaml:"thinking_budget" mapstructure:"thinking_budget"` // Extended thinking tokens per turn; 0 = off
+	PromptCache    *bool `yaml:"prompt_cache" mapstructure:"prompt_cache"`       // Cache the system prompt and tools; default on
+
+	// Client-side limits, shared by every agent and sub-agent using this provider
+	RequestsPerMinute int `yaml:"requests_per_minute" mapstructure:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute" mapstructure:"tokens_per_minute"`
+	MaxInFlight       int `yaml:"max_in_flight" mapstructure:"max_in_flight"` // Concurrent requests
+
+	// Routing rules, used when this provider is part of a fallback chain
+	MaxContext int  `yaml:"max_context" mapstructure:"max_context"` // Skip prompts larger than this (tokens)
+	NoTools    bool `yaml:"no_tools" mapstructure:"no_tools"`       // Skip requests that need tool calls
+	CostTier   int  `yaml:"cost_tier" mapstructure:"cost_tier"`     // 1 = local/free, higher = pricier
 }
 
 type ToolsConfig struct {
 	AutoApprove        []string `yaml:"auto_approve" mapstructure:"auto_approve"`
 	AllowedCommands    []string `yaml:"allowed_commands" mapstructure:"allowed_commands"`
 	DisallowedCommands []string `yaml:"disallowed_commands" mapstructure:"disallowed_commands"`
+
+	Workspace WorkspaceConfig `yaml:"workspace" mapstructure:"workspace"`
+}
+
+// WorkspaceConfig confines the file tools to a directory tree. Paths are
+// resolved through symlinks and ".." before they are checked. Deny globs win
+// over everything, including paths inside Root; Allow globs open up paths
+// outside it. A glob also covers everything below what it matches, so
+// "~/.ssh" denies the whole directory.
+type WorkspaceConfig struct {
+	Root    string   `yaml:"root" mapstructure:"root"`       // Default: the working directory at startup
+	Allow   []string `yaml:"allow" mapstructure:"allow"`     // Extra paths the tools may use
+	Deny    []string `yaml:"deny" mapstructure:"deny"`       // Paths the tools may never use
+	Outside string   `yaml:"outside" mapstructure:"outside"` // confirm (default), block or allow
 }
 
 var envVarRe = regexp.MustCompile(`\$([A-Z_][A-Z0-9_]*)`)
@@ -68,6 +167,10 @@ func DefaultConfig() *Config {
 		},
 		Tools: ToolsConfig{
 			AutoApprove: []string{"file_read", "file_search"},
+			Workspace: WorkspaceConfig{
+				Deny:    []string{"~/.ssh", "~/.aws", "~/.gnupg", "~/.config/aseity/config.yaml"},
+				Outside: "confirm",
+			},
 		},
 	}
 }
@@ -117,6 +220,9 @@ func Load() (*Config, error) {
 	for name, p := range cfg.Providers {
 		p.APIKey = expandEnv(p.APIKey)
 		p.BaseURL = expandEnv(p.BaseURL)
+		for k, v := range p.Headers {
+			p.Headers[k] = expandEnv(v)
+		}
 		cfg.Providers[name] = p
 	}
 
@@ -140,9 +246,9 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("config: default_provider %q not found in providers", c.DefaultProvider)
 	}
 	for name, p := range c.Providers {
-		validTypes := map[string]bool{"openai": true, "anthropic": true, "google": true}
+		validTypes := map[string]bool{"openai": true, "ollama": true, "anthropic": true, "google": true}
 		if !validTypes[p.Type] {
-			return fmt.Errorf("config: provider %q has invalid type %q (must be openai, anthropic, or google)", name, p.Type)
+			return fmt.Errorf("config: provider %q has invalid type %q (must be openai, ollama, anthropic, or google)", name, p.Type)
 		}
 		if p.Type == "openai" && p.BaseURL == "" {
 			return fmt.Errorf("config: provider %q (type openai) requires base_url", name)
@@ -153,6 +259,54 @@ func (c *Config) Validate() error {
 		if p.Type == "google" && p.APIKey == "" {
 			return fmt.Errorf("config: provider %q (type google) requires api_key", name)
 		}
+		switch p.Variant {
+		case "", "azure", "llamacpp", "lmstudio":
+		default:
+			return fmt.Errorf("config: provider %q has invalid variant %q (must be azure, llamacpp or lmstudio)", name, p.Variant)
+		}
+		if p.Variant != "" && p.Type != "openai" {
+			return fmt.Errorf("config: provider %q sets variant, which only applies to type openai", name)
+		}
+		if p.Variant == "azure" && p.Deployment == "" && p.Model == "" {
+			return fmt.Errorf("config: provider %q (azure) requires deployment or model", name)
+		}
+		if p.RequestsPerMinute < 0 || p.TokensPerMinute < 0 || p.MaxInFlight < 0 {
+			return fmt.Errorf("config: provider %q has a negative rate limit", name)
+		}
+		if p.ThinkingBudget < 0 {
+			return fmt.Errorf("config: provider %q has a negative thinking_budget", name)
+		}
+	}
+	for _, name := range c.Fallback {
+		if _, ok := c.Providers[name]; !ok {
+			return fmt.Errorf("config: fallback provider %q not found in providers", name)
+		}
+	}
+	for i, p := range c.Pricing {
+		if p.Provider == "" && p.Model == "" {
+			return fmt.Errorf("config: pricing entry %d needs a provider or model", i+1)
+		}
+		if p.Input < 0 || p.CachedInput < 0 || p.CacheWrite < 0 || p.Output < 0 {
+			return fmt.Errorf("config: pricing entry %d has a negative price", i+1)
+		}
+	}
+	if c.CircuitBreaker.Failures < 0 || c.CircuitBreaker.Cooldown < 0 {
+		return fmt.Errorf("config: circuit_breaker values must not be negative")
+	}
+	for _, pat := range c.Debug.RedactPatterns {
+		if _, err := regexp.Compile(pat); err != nil {
+			return fmt.Errorf("config: debug.redact_patterns: %v", err)
+		}
+	}
+	switch c.Tools.Workspace.Outside {
+	case "", "confirm", "block", "allow":
+	default:
+		return fmt.Errorf("config: tools.workspace.outside has invalid value %q (must be confirm, block or allow)", c.Tools.Workspace.Outside)
+	}
+	switch c.Probe.Auto {
+	case "", "ask", "always", "never":
+	default:
+		return fmt.Errorf("config: probe.auto has invalid value %q (must be ask, always or never)", c.Probe.Auto)
 	}
 	if c.MaxTurns < 1 {
 		c.MaxTurns = 50
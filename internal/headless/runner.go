@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/jeanpaul/aseity/internal/agent"
@@ -62,6 +63,14 @@ func Run(ctx context.Context, prov provider.Provider, toolReg *tools.Registry, p
 			case agent.EventToolCall:
 				fmt.Fprintf(os.Stderr, "\n[Tool Call: %s(%s)]\n", evt.ToolName, evt.ToolArgs)
 
+			case agent.EventRetry:
+				fmt.Fprintf(os.Stderr, "\n[%s]\n", evt.Text)
+
+			case agent.EventRoute:
+				if evt.Route.Fallback() {
+					fmt.Fprintf(os.Stderr, "\n[Served by %s]\n", evt.Text)
+				}
+
 			case agent.EventToolOutput:
 				// Streaming tool output to stderr
 				fmt.Fprint(os.Stderr, evt.Text)
@@ -96,6 +105,13 @@ func Run(ctx context.Context, prov provider.Provider, toolReg *tools.Registry, p
 				// Or fail. Failing is safer for "rm -rf /".
 				// But user probably passed -y.
 
+				// Leaving the workspace is never approved unattended; widen
+				// tools.workspace in the config instead.
+				if len(evt.Outside) > 0 {
+					fmt.Fprintf(os.Stderr, "\n[Denied %s outside the workspace: %s]\n", evt.ToolName, strings.Join(evt.Outside, ", "))
+					agt.ConfirmCh <- false
+					continue
+				}
 				fmt.Fprintf(os.Stderr, "\n[Auto-Approving Tool Use: %s]\n", evt.ToolName)
 				agt.ConfirmCh <- true
 
@@ -20,7 +20,7 @@ type Status struct {
 
 // Check verifies that a provider endpoint is reachable and responding.
 // For OpenAI-compatible endpoints (Ollama, vLLM, OpenAI, HF), it hits /models.
-// For Anthropic/Google, it does a lightweight connectivity test.
+// For Anthropic/Google, it lists models from their native endpoints.
 func Check(ctx context.Context, providerType, baseURL, apiKey string) Status {
 	s := Status{BaseURL: baseURL}
 	start := time.Now()
@@ -31,10 +31,12 @@ func Check(ctx context.Context, providerType, baseURL, apiKey string) Status {
 	switch providerType {
 	case "openai":
 		s = checkOpenAICompat(ctx, baseURL, apiKey)
+	case "ollama":
+		s = checkOllama(ctx, baseURL)
 	case "anthropic":
-		s = checkAnthropic(ctx, apiKey)
+		s = checkAnthropic(ctx, baseURL, apiKey)
 	case "google":
-		s = checkGoogle(ctx, apiKey)
+		s = checkGoogle(ctx, baseURL, apiKey)
 	default:
 		s.Error = fmt.Sprintf("unknown provider type: %s", providerType)
 	}
@@ -44,6 +46,26 @@ func Check(ctx context.Context, providerType, baseURL, apiKey string) Status {
 	return s
 }
 
+// CheckLister checks an endpoint by listing its models through list, for
+// servers whose URLs or authentication the built-in checks don't cover.
+func CheckLister(ctx context.Context, baseURL string, list func(context.Context) ([]string, error)) Status {
+	s := Status{BaseURL: baseURL}
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	models, err := list(ctx)
+	s.Latency = time.Since(start)
+	if err != nil {
+		s.Error = fmt.Sprintf("cannot reach %s: %s", baseURL, friendlyError(err))
+		return s
+	}
+	s.Reachable = true
+	s.Models = models
+	return s
+}
+
 func checkOpenAICompat(ctx context.Context, baseURL, apiKey string) Status {
 	s := Status{}
 	url := strings.TrimRight(baseURL, "/") + "/models"
@@ -90,13 +112,57 @@ func checkOpenAICompat(ctx context.Context, baseURL, apiKey string) Status {
 	return s
 }
 
-func checkAnthropic(ctx context.Context, apiKey string) Status {
-	s := Status{BaseURL: "https://api.anthropic.com"}
+// checkOllama queries the native /api/tags endpoint.
+// It accepts both the bare server URL and the OpenAI-style ".../v1" URL.
+func checkOllama(ctx context.Context, baseURL string) Status {
+	s := Status{}
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	root := strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+	req, err := http.NewRequestWithContext(ctx, "GET", root+"/api/tags", nil)
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.Error = fmt.Sprintf("cannot reach %s: %s", baseURL, friendlyError(err))
+		return s
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		s.Error = fmt.Sprintf("endpoint returned HTTP %d", resp.StatusCode)
+		return s
+	}
+
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	s.Reachable = true
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return s
+	}
+	for _, m := range result.Models {
+		s.Models = append(s.Models, m.Name)
+	}
+	return s
+}
+
+func checkAnthropic(ctx context.Context, baseURL, apiKey string) Status {
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	s := Status{BaseURL: baseURL}
 	if apiKey == "" {
 		s.Error = "no API key configured (set ANTHROPIC_API_KEY)"
 		return s
 	}
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.anthropic.com/v1/models", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(baseURL, "/")+"/v1/models?limit=1000", nil)
 	if err != nil {
 		s.Error = err.Error()
 		return s
@@ -116,21 +182,35 @@ func checkAnthropic(ctx context.Context, apiKey string) Status {
 		return s
 	}
 	s.Reachable = true
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return s
+	}
+	for _, m := range result.Data {
+		s.Models = append(s.Models, m.ID)
+	}
 	return s
 }
 
-func checkGoogle(ctx context.Context, apiKey string) Status {
-	s := Status{BaseURL: "https://generativelanguage.googleapis.com"}
+func checkGoogle(ctx context.Context, baseURL, apiKey string) Status {
+	if baseURL == "" {
+		baseURL = "https://generativelanguage.googleapis.com"
+	}
+	s := Status{BaseURL: baseURL}
 	if apiKey == "" {
 		s.Error = "no API key configured (set GEMINI_API_KEY)"
 		return s
 	}
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models?key=%s&pageSize=1", apiKey)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(baseURL, "/")+"/v1beta/models?pageSize=1000", nil)
 	if err != nil {
 		s.Error = err.Error()
 		return s
 	}
+	req.Header.Set("x-goog-api-key", apiKey)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		s.Error = fmt.Sprintf("cannot reach Google API: %s", friendlyError(err))
@@ -143,15 +223,31 @@ func checkGoogle(ctx context.Context, apiKey string) Status {
 		return s
 	}
 	s.Reachable = true
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return s
+	}
+	for _, m := range result.Models {
+		s.Models = append(s.Models, strings.TrimPrefix(m.Name, "models/"))
+	}
 	return s
 }
 
 // CheckModel verifies that a specific model is available on the provider.
 func CheckModel(ctx context.Context, providerType, baseURL, apiKey, modelName string) error {
-	if providerType != "openai" {
+	var status Status
+	switch providerType {
+	case "openai":
+		status = checkOpenAICompat(ctx, baseURL, apiKey)
+	case "ollama":
+		status = checkOllama(ctx, baseURL)
+	default:
 		return nil // can't easily verify for Anthropic/Google without making a real request
 	}
-	status := checkOpenAICompat(ctx, baseURL, apiKey)
 	if !status.Reachable {
 		return fmt.Errorf("provider not reachable: %s", status.Error)
 	}
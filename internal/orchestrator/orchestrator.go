@@ -8,12 +8,14 @@ import (
 	"time"
 
 	"github.com/jeanpaul/aseity/internal/provider"
+	"github.com/jeanpaul/aseity/internal/tokenizer"
 	"github.com/jeanpaul/aseity/internal/tools"
 )
 
 // Orchestrator coordinates the multi-agent execution
 type Orchestrator struct {
 	provider       provider.Provider
+	tokenizer      tokenizer.Tokenizer
 	registry       *tools.Registry
 	maxRetries     int
 	maxSteps       int
@@ -42,6 +44,7 @@ func NewOrchestrator(prov provider.Provider, reg *tools.Registry, cfg *Config) *
 
 	return &Orchestrator{
 		provider:    prov,
+		tokenizer:   tokenizer.ForModel(prov.ModelName()),
 		registry:    reg,
 		maxRetries:  cfg.MaxRetries,
 		maxSteps:    cfg.MaxSteps,
@@ -149,33 +152,65 @@ func (o *Orchestrator) ProcessQuery(ctx context.Context, query string) (string,
 	return response, state, nil
 }
 
-func (o *Orchestrator) callModel(ctx context.Context, prompt string) (string, int) {
+// Generation settings per phase: structured phases want deterministic JSON,
+// synthesis is allowed a little more freedom in wording.
+var (
+	structuredPhaseOptions = []provider.ChatOption{provider.WithTemperature(0), provider.WithMaxTokens(2048)}
+	synthesisPhaseOptions  = []provider.ChatOption{provider.WithTemperature(0.5)}
+)
+
+// structuredCall returns the options for a JSON phase constrained to schema.
+func structuredCall(name string, schema any) []provider.ChatOption {
+	opts := append([]provider.ChatOption(nil), structuredPhaseOptions...)
+	return append(opts, provider.WithResponseSchema(name, schema))
+}
+
+func (o *Orchestrator) callModel(ctx context.Context, phase, prompt string, opts ...provider.ChatOption) (string, int) {
 	// Use Chat which returns a channel
+	ctx = provider.WithCallSource(ctx, "orchestrator:"+phase)
 	ch, err := o.provider.Chat(ctx, []provider.Message{
 		{Role: "user", Content: prompt},
-	}, nil)
+	}, nil, opts...)
 	if err != nil {
 		return "", 0
 	}
 
 	// Collect all chunks efficiently
 	var builder strings.Builder
-	var tokens int
+	var usage *provider.Usage
 	for chunk := range ch {
 		if chunk.Error != nil {
 			return "", 0
 		}
+		if chunk.Restart != nil {
+			builder.Reset()
+			usage = nil
+		}
 		builder.WriteString(chunk.Delta)
 		if chunk.Done && chunk.Usage != nil {
-			tokens = chunk.Usage.TotalTokens
+			usage = chunk.Usage
+		}
+	}
+	result := builder.String()
+	return result, o.countTokens(prompt, result, usage)
+}
+
+// countTokens returns the tokens a call used: the provider's figure when it
+// reports one (which also calibrates the tokenizer), otherwise the
+// tokenizer's count of the prompt and response.
+func (o *Orchestrator) countTokens(prompt, response string, usage *provider.Usage) int {
+	if usage != nil && usage.TotalTokens > 0 {
+		if cal, ok := o.tokenizer.(tokenizer.Calibrator); ok {
+			cal.Observe(len(prompt), usage.InputTokens)
 		}
+		return usage.TotalTokens
 	}
-	return builder.String(), tokens
+	return o.tokenizer.Count(prompt) + o.tokenizer.Count(response)
 }
 
 func (o *Orchestrator) extractIntent(ctx context.Context, query string, state *AgentState) (*IntentOutput, error) {
 	callModel := func(prompt string) string {
-		result, tokens := o.callModel(ctx, prompt)
+		result, tokens := o.callModel(ctx, "intent", prompt, structuredCall("intent", IntentSchema)...)
 		state.AddTokens(tokens)
 		return result
 	}
@@ -190,7 +225,7 @@ func (o *Orchestrator) createPlan(ctx context.Context, intentOutput *IntentOutpu
 	}
 
 	callModel := func(prompt string) string {
-		result, tokens := o.callModel(ctx, prompt)
+		result, tokens := o.callModel(ctx, "plan", prompt, structuredCall("plan", PlanSchema)...)
 		state.AddTokens(tokens)
 		return result
 	}
@@ -272,7 +307,7 @@ func marshalParams(params map[string]interface{}) (string, error) {
 
 func (o *Orchestrator) validateResults(ctx context.Context, intentOutput *IntentOutput, plan *Plan, results []StepResult, state *AgentState) (*ValidationResult, error) {
 	callModel := func(prompt string) string {
-		result, tokens := o.callModel(ctx, prompt)
+		result, tokens := o.callModel(ctx, "validation", prompt, structuredCall("validation", ValidationSchema)...)
 		state.AddTokens(tokens)
 		return result
 	}
@@ -282,7 +317,7 @@ func (o *Orchestrator) validateResults(ctx context.Context, intentOutput *Intent
 
 func (o *Orchestrator) synthesizeResponse(ctx context.Context, query string, results []StepResult, state *AgentState) string {
 	callModel := func(prompt string) string {
-		result, tokens := o.callModel(ctx, prompt)
+		result, tokens := o.callModel(ctx, "synthesis", prompt, synthesisPhaseOptions...)
 		state.AddTokens(tokens)
 		return result
 	}
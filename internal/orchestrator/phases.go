@@ -74,7 +74,7 @@ func BuildPlannerPrompt(intent *IntentOutput, toolsSection string, maxSteps int)
 	return fmt.Sprintf(`You are a Task Planner. Your goal is to create a valid JSON plan for the user's intent.
 
 INSTRUCTIONS:
-1. First, ANALYZE the request and available tools in a <reasoning> text block.
+1. First, ANALYZE the request and available tools in a <reasoning> text block (if you can only output JSON, use the top-level "reasoning" field instead).
 2. Then, GENERATE the JSON plan based on your reasoning.
 3. The JSON must be valid and adhere to the schema.
 4. "depends_on" field must use 1-BASED step numbers referring to PREVIOUS steps. Never use 0.
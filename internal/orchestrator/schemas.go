@@ -0,0 +1,65 @@
+package orchestrator
+
+import "sort"
+
+// JSON Schemas for the structured phases. They are sent to providers that
+// support constrained decoding; the Validate* functions still run afterwards
+// because not every backend enforces them.
+
+var IntentSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"reasoning":      map[string]any{"type": "string"},
+		"intent_type":    map[string]any{"type": "string", "enum": sortedKeys(ValidIntentTypes)},
+		"requires_tools": map[string]any{"type": "boolean"},
+		"entities":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"complexity":     map[string]any{"type": "string", "enum": sortedKeys(ValidComplexityLevels)},
+	},
+	"required": []string{"reasoning", "intent_type", "requires_tools", "entities", "complexity"},
+}
+
+// PlanSchema carries a top-level "reasoning" field so the planner keeps room
+// to think even when the response must be pure JSON. Plan ignores it.
+var PlanSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"reasoning": map[string]any{"type": "string"},
+		"steps": map[string]any{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"step_number": map[string]any{"type": "integer", "minimum": 1},
+					"action":      map[string]any{"type": "string"},
+					"parameters":  map[string]any{"type": "object"},
+					"reasoning":   map[string]any{"type": "string"},
+					"depends_on":  map[string]any{"type": "array", "items": map[string]any{"type": "integer", "minimum": 1}},
+				},
+				"required": []string{"step_number", "action", "parameters", "reasoning"},
+			},
+		},
+		"expected_outcome": map[string]any{"type": "string"},
+	},
+	"required": []string{"reasoning", "steps", "expected_outcome"},
+}
+
+var ValidationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"intent_fulfilled":    map[string]any{"type": "boolean"},
+		"missing_information": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"confidence":          map[string]any{"type": "integer", "minimum": 0, "maximum": 100},
+		"recommendation":      map[string]any{"type": "string", "enum": sortedKeys(ValidRecommendations)},
+	},
+	"required": []string{"intent_fulfilled", "missing_information", "confidence", "recommendation"},
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"testing"
 
+	"github.com/jeanpaul/aseity/internal/schema"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -174,3 +175,20 @@ func TestCreateDefaultValidation(t *testing.T) {
 	assert.False(t, validation.IntentFulfilled)
 	assert.Equal(t, "retry", validation.Recommendation)
 }
+
+func TestStructuredSchemas_AcceptValidOutputs(t *testing.T) {
+	v := schema.NewValidator()
+
+	intent := `{"reasoning":"find docs","intent_type":"search","requires_tools":true,"entities":["go"],"complexity":"simple"}`
+	assert.NoError(t, v.Validate(IntentSchema, intent))
+	assert.Error(t, v.Validate(IntentSchema, `{"reasoning":"x","intent_type":"bogus","requires_tools":true,"entities":[],"complexity":"simple"}`))
+
+	plan := `{"reasoning":"one step","steps":[{"step_number":1,"action":"web_search","parameters":{"query":"go"},"reasoning":"look it up"}],"expected_outcome":"links"}`
+	assert.NoError(t, v.Validate(PlanSchema, plan))
+	_, err := ValidatePlan(plan, 5)
+	assert.NoError(t, err)
+
+	validation := `{"intent_fulfilled":true,"missing_information":[],"confidence":90,"recommendation":"proceed"}`
+	assert.NoError(t, v.Validate(ValidationSchema, validation))
+	assert.Error(t, v.Validate(ValidationSchema, `{"intent_fulfilled":true,"missing_information":[],"confidence":190,"recommendation":"proceed"}`))
+}
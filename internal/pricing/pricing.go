@@ -0,0 +1,123 @@
+// Package pricing turns provider token usage into cost.
+//
+// A Registry maps provider/model pairs to per-token prices, starting from
+// built-in list prices and overridden from config. A Tracker accumulates the
+// usage of every model call in a session, and Meter wraps a Provider so that
+// each Chat call is recorded automatically.
+package pricing
+
+import (
+	"strings"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+)
+
+// Price is what a model charges, in USD per million tokens.
+type Price struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input,omitempty"` // 0 = billed at the Input rate
+	CacheWrite  float64 `json:"cache_write,omitempty"`  // 0 = billed at the Input rate
+	Output      float64 `json:"output"`
+}
+
+// Cost returns the USD cost of one call's usage.
+func (p Price) Cost(u provider.Usage) float64 {
+	cachedRate, writeRate := p.CachedInput, p.CacheWrite
+	if cachedRate == 0 {
+		cachedRate = p.Input
+	}
+	if writeRate == 0 {
+		writeRate = p.Input
+	}
+	uncached := u.InputTokens - u.CachedInputTokens - u.CacheWriteTokens
+	input := float64(uncached)*p.Input + float64(u.CachedInputTokens)*cachedRate + float64(u.CacheWriteTokens)*writeRate
+	return (input + float64(u.OutputTokens)*p.Output) / 1_000_000
+}
+
+// builtinPrices are list prices keyed by model name prefix. The longest
+// matching prefix wins, so "gpt-4o-mini" beats "gpt-4o".
+var builtinPrices = map[string]Price{
+	// OpenAI
+	"gpt-4o":        {Input: 2.50, CachedInput: 1.25, Output: 10},
+	"gpt-4o-mini":   {Input: 0.15, CachedInput: 0.075, Output: 0.60},
+	"gpt-4.1":       {Input: 2.00, CachedInput: 0.50, Output: 8},
+	"gpt-4.1-mini":  {Input: 0.40, CachedInput: 0.10, Output: 1.60},
+	"gpt-4.1-nano":  {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+	"gpt-4-turbo":   {Input: 10, Output: 30},
+	"gpt-4":         {Input: 30, Output: 60},
+	"gpt-3.5-turbo": {Input: 0.50, Output: 1.50},
+	"o1":            {Input: 15, CachedInput: 7.50, Output: 60},
+	"o1-mini":       {Input: 1.10, CachedInput: 0.55, Output: 4.40},
+	"o3":            {Input: 2.00, CachedInput: 0.50, Output: 8},
+	"o3-mini":       {Input: 1.10, CachedInput: 0.55, Output: 4.40},
+	"o4-mini":       {Input: 1.10, CachedInput: 0.275, Output: 4.40},
+
+	// Anthropic
+	"claude-3-5-sonnet": {Input: 3, CachedInput: 0.30, CacheWrite: 3.75, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, CachedInput: 0.30, CacheWrite: 3.75, Output: 15},
+	"claude-sonnet-4":   {Input: 3, CachedInput: 0.30, CacheWrite: 3.75, Output: 15},
+	"claude-3-5-haiku":  {Input: 0.80, CachedInput: 0.08, CacheWrite: 1, Output: 4},
+	"claude-3-haiku":    {Input: 0.25, CachedInput: 0.03, CacheWrite: 0.30, Output: 1.25},
+	"claude-3-opus":     {Input: 15, CachedInput: 1.50, CacheWrite: 18.75, Output: 75},
+	"claude-opus-4":     {Input: 15, CachedInput: 1.50, CacheWrite: 18.75, Output: 75},
+
+	// Google (prompts up to 128k/200k tokens)
+	"gemini-1.5-flash": {Input: 0.075, CachedInput: 0.01875, Output: 0.30},
+	"gemini-1.5-pro":   {Input: 1.25, CachedInput: 0.3125, Output: 5},
+	"gemini-2.0-flash": {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+	"gemini-2.5-flash": {Input: 0.30, CachedInput: 0.075, Output: 2.50},
+	"gemini-2.5-pro":   {Input: 1.25, CachedInput: 0.31, Output: 10},
+}
+
+// Registry resolves the price of a provider/model pair.
+type Registry struct {
+	models    map[string]Price // "model" or "provider/model", exact match
+	providers map[string]Price // Every model of a provider (e.g. local backends)
+}
+
+// NewRegistry returns a registry holding the built-in prices.
+func NewRegistry() *Registry {
+	return &Registry{models: map[string]Price{}, providers: map[string]Price{}}
+}
+
+// SetModel prices a model exactly. With a non-empty providerName the price
+// only applies to that provider.
+func (r *Registry) SetModel(providerName, model string, p Price) {
+	key := strings.ToLower(model)
+	if providerName != "" {
+		key = providerName + "/" + key
+	}
+	r.models[key] = p
+}
+
+// SetProvider prices every model served by a provider.
+func (r *Registry) SetProvider(name string, p Price) {
+	r.providers[name] = p
+}
+
+// Lookup finds the price for a call. Overrides win over the built-in table:
+// provider/model, then model, then provider-wide, then built-in prefixes.
+func (r *Registry) Lookup(providerName, model string) (Price, bool) {
+	model = strings.ToLower(model)
+	if p, ok := r.models[providerName+"/"+model]; ok {
+		return p, true
+	}
+	if p, ok := r.models[model]; ok {
+		return p, true
+	}
+	if p, ok := r.providers[providerName]; ok {
+		return p, true
+	}
+
+	base := model
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[i+1:]
+	}
+	best, found := "", false
+	for prefix := range builtinPrices {
+		if strings.HasPrefix(base, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return builtinPrices[best], found
+}
@@ -0,0 +1,159 @@
+package pricing
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+)
+
+func TestRegistry_Lookup(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetProvider("ollama", Price{})
+	reg.SetModel("", "my-finetune", Price{Input: 1, Output: 2})
+	reg.SetModel("work", "gpt-4o", Price{Input: 1, Output: 1})
+
+	tests := []struct {
+		provider, model string
+		want            Price
+		found           bool
+	}{
+		{"openai", "gpt-4o-mini-2024-07-18", builtinPrices["gpt-4o-mini"], true},
+		{"openai", "gpt-4o", builtinPrices["gpt-4o"], true},
+		{"work", "gpt-4o", Price{Input: 1, Output: 1}, true},
+		{"anthropic", "claude-3-5-sonnet-20241022", builtinPrices["claude-3-5-sonnet"], true},
+		{"ollama", "qwen2.5:14b", Price{}, true},
+		{"vllm", "My-Finetune", Price{Input: 1, Output: 2}, true},
+		{"vllm", "mystery-model", Price{}, false},
+	}
+	for _, tt := range tests {
+		got, found := reg.Lookup(tt.provider, tt.model)
+		if got != tt.want || found != tt.found {
+			t.Errorf("Lookup(%s, %s) = %+v, %v; want %+v, %v", tt.provider, tt.model, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestPrice_CostWithCachedInput(t *testing.T) {
+	p := Price{Input: 3, CachedInput: 0.30, Output: 15}
+	u := provider.Usage{InputTokens: 1_000_000, CachedInputTokens: 500_000, OutputTokens: 100_000}
+	// 0.5M * $3 + 0.5M * $0.30 + 0.1M * $15
+	if got := p.Cost(u); math.Abs(got-3.15) > 1e-9 {
+		t.Errorf("Cost = %v, want 3.15", got)
+	}
+	// Without a cached rate, cached tokens are billed as input
+	if got := (Price{Input: 2}).Cost(provider.Usage{InputTokens: 1_000_000, CachedInputTokens: 400_000}); math.Abs(got-2) > 1e-9 {
+		t.Errorf("Cost = %v, want 2", got)
+	}
+}
+
+func TestPrice_CostWithCacheWrites(t *testing.T) {
+	p := builtinPrices["claude-sonnet-4"]
+	u := provider.Usage{InputTokens: 1_000_000, CachedInputTokens: 600_000, CacheWriteTokens: 300_000}
+	// 0.1M * $3 + 0.6M * $0.30 + 0.3M * $3.75
+	if got := p.Cost(u); math.Abs(got-1.605) > 1e-9 {
+		t.Errorf("Cost = %v, want 1.605", got)
+	}
+}
+
+// usageProvider streams one reply with fixed usage.
+type usageProvider struct{ usage provider.Usage }
+
+func (u *usageProvider) Name() string                                 { return "anthropic" }
+func (u *usageProvider) ModelName() string                            { return "claude-3-5-haiku" }
+func (u *usageProvider) Models(ctx context.Context) ([]string, error) { return nil, nil }
+func (u *usageProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, opts ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
+	ch := make(chan provider.StreamChunk, 3)
+	ch <- provider.StreamChunk{Route: &provider.RouteInfo{Provider: "openai", Model: "gpt-4o-mini"}}
+	ch <- provider.StreamChunk{Delta: "ok"}
+	ch <- provider.StreamChunk{Done: true, Usage: &u.usage}
+	close(ch)
+	return ch, nil
+}
+
+func TestMeter_RecordsBySourceAndRoute(t *testing.T) {
+	tracker := NewTracker(NewRegistry())
+	p := Meter(&usageProvider{usage: provider.Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000, TotalTokens: 2_000_000}}, tracker)
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		context.Background(),
+		provider.WithCallSource(context.Background(), "validator"),
+	} {
+		ch, err := p.Chat(ctx, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range ch {
+		}
+	}
+	// Usage is recorded before the stream closes
+	s := tracker.Summary()
+
+	if len(s.Entries) != 2 {
+		t.Fatalf("expected agent and validator entries, got %+v", s.Entries)
+	}
+	agent := s.Entries[0]
+	if agent.Source != DefaultSource || agent.Calls != 2 || agent.Model != "gpt-4o-mini" {
+		t.Errorf("agent entry = %+v (should be priced on the routed backend)", agent)
+	}
+	// gpt-4o-mini: $0.15 in + $0.60 out per call
+	if math.Abs(s.Cost-3*0.75) > 1e-9 {
+		t.Errorf("total cost = %v, want 2.25", s.Cost)
+	}
+
+	restored := NewTracker(NewRegistry())
+	restored.Restore(s)
+	if got := restored.Summary(); got.Cost != s.Cost || got.Usage != s.Usage {
+		t.Errorf("restored summary = %+v, want %+v", got, s)
+	}
+}
+
+// floodProvider reports usage up front and then streams more chunks than
+// any buffer holds, ignoring ctx, the way a slow backend might.
+type floodProvider struct {
+	usageProvider
+	finished chan struct{}
+}
+
+func (f *floodProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, opts ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
+	ch := make(chan provider.StreamChunk)
+	go func() {
+		defer close(f.finished)
+		defer close(ch)
+		ch <- provider.StreamChunk{Usage: &f.usage}
+		for i := 0; i < 200; i++ {
+			ch <- provider.StreamChunk{Delta: "x"}
+		}
+	}()
+	return ch, nil
+}
+
+func TestMeter_CallerCancels(t *testing.T) {
+	tracker := NewTracker(NewRegistry())
+	inner := &floodProvider{
+		usageProvider: usageProvider{usage: provider.Usage{InputTokens: 100, OutputTokens: 10, TotalTokens: 110}},
+		finished:      make(chan struct{}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := Meter(inner, tracker).Chat(ctx, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel()
+
+	select {
+	case <-inner.finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the inner stream was left blocked after the caller cancelled")
+	}
+	for range ch {
+	}
+	s := tracker.Summary()
+	if s.Usage.TotalTokens != 110 || len(s.Entries) != 1 || s.Entries[0].Calls != 1 {
+		t.Errorf("summary = %+v, want the usage that arrived before the cancel", s)
+	}
+}
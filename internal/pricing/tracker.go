@@ -0,0 +1,181 @@
+package pricing
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+)
+
+// DefaultSource labels calls made without provider.WithCallSource, which
+// is the main agent loop.
+const DefaultSource = "agent"
+
+// Entry is the accumulated usage of one source on one model.
+type Entry struct {
+	Source   string         `json:"source"`
+	Provider string         `json:"provider"`
+	Model    string         `json:"model"`
+	Calls    int            `json:"calls"`
+	Usage    provider.Usage `json:"usage"`
+	Cost     float64        `json:"cost"`
+	Priced   bool           `json:"priced"` // False when no price is known for the model
+}
+
+// Summary is a snapshot of a session's usage and cost.
+type Summary struct {
+	Usage    provider.Usage `json:"usage"`
+	Cost     float64        `json:"cost"`
+	Unpriced int            `json:"unpriced,omitempty"` // Calls whose model has no known price
+	Entries  []Entry        `json:"entries"`
+}
+
+// Tracker accumulates usage for a session. It is safe for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	reg     *Registry
+	entries map[entryKey]*Entry
+}
+
+type entryKey struct{ source, provider, model string }
+
+func NewTracker(reg *Registry) *Tracker {
+	return &Tracker{reg: reg, entries: map[entryKey]*Entry{}}
+}
+
+// Record adds the usage of one call.
+func (t *Tracker) Record(source, providerName, model string, u provider.Usage) {
+	if source == "" {
+		source = DefaultSource
+	}
+	price, priced := t.reg.Lookup(providerName, model)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := entryKey{source, providerName, model}
+	e, ok := t.entries[key]
+	if !ok {
+		e = &Entry{Source: source, Provider: providerName, Model: model, Priced: priced}
+		t.entries[key] = e
+	}
+	e.Calls++
+	addUsage(&e.Usage, u)
+	e.Cost += price.Cost(u)
+}
+
+// Restore adds the entries of a previously saved summary, so a resumed
+// session keeps its running total.
+func (t *Tracker) Restore(s Summary) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, saved := range s.Entries {
+		key := entryKey{saved.Source, saved.Provider, saved.Model}
+		e, ok := t.entries[key]
+		if !ok {
+			e = &Entry{Source: saved.Source, Provider: saved.Provider, Model: saved.Model, Priced: saved.Priced}
+			t.entries[key] = e
+		}
+		e.Calls += saved.Calls
+		addUsage(&e.Usage, saved.Usage)
+		e.Cost += saved.Cost
+	}
+}
+
+// Summary returns totals and the per-source breakdown, costliest first.
+func (t *Tracker) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var s Summary
+	for _, e := range t.entries {
+		s.Entries = append(s.Entries, *e)
+		addUsage(&s.Usage, e.Usage)
+		s.Cost += e.Cost
+		if !e.Priced {
+			s.Unpriced += e.Calls
+		}
+	}
+	sort.Slice(s.Entries, func(i, j int) bool {
+		a, b := s.Entries[i], s.Entries[j]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Model < b.Model
+	})
+	return s
+}
+
+func addUsage(dst *provider.Usage, u provider.Usage) {
+	dst.InputTokens += u.InputTokens
+	dst.OutputTokens += u.OutputTokens
+	dst.TotalTokens += u.TotalTokens
+	dst.CachedInputTokens += u.CachedInputTokens
+	dst.CacheWriteTokens += u.CacheWriteTokens
+}
+
+// MeteredProvider records the usage of every Chat call in a Tracker. Place it
+// outside any RouterProvider so the backend that actually served each call
+// is the one priced.
+type MeteredProvider struct {
+	inner   provider.Provider
+	tracker *Tracker
+}
+
+// Meter wraps p so that its usage is recorded in t.
+func Meter(p provider.Provider, t *Tracker) *MeteredProvider {
+	return &MeteredProvider{inner: p, tracker: t}
+}
+
+func (m *MeteredProvider) Name() string { return m.inner.Name() }
+
+func (m *MeteredProvider) ModelName() string { return m.inner.ModelName() }
+
+func (m *MeteredProvider) Unwrap() provider.Provider { return m.inner }
+
+func (m *MeteredProvider) Models(ctx context.Context) ([]string, error) { return m.inner.Models(ctx) }
+
+func (m *MeteredProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, opts ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
+	ch, err := m.inner.Chat(ctx, msgs, tools, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	source := provider.CallSource(ctx)
+	out := make(chan provider.StreamChunk, 64)
+	go func() {
+		defer close(out)
+		name, model := m.inner.Name(), m.inner.ModelName()
+		var usage *provider.Usage
+	recv:
+		for chunk := range ch {
+			switch {
+			case chunk.Route != nil:
+				name, model = chunk.Route.Provider, chunk.Route.Model
+			case chunk.Restart != nil:
+				usage = nil
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				go func() {
+					for range ch {
+					}
+				}()
+				break recv
+			}
+		}
+		// Recorded before out closes, so a caller that has drained the
+		// stream sees the call in the tracker. A cancelled call is billed
+		// for whatever usage had arrived.
+		if usage != nil {
+			m.tracker.Record(source, name, model, *usage)
+		}
+	}()
+	return out, nil
+}
@@ -5,55 +5,173 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"strings"
 )
 
 type AnthropicProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey  string
+	model   string
+	baseURL string
+	opts    AnthropicOptions
+	client  *http.Client
 }
 
-func NewAnthropic(apiKey, model string) *AnthropicProvider {
+// AnthropicOptions are the Anthropic-specific settings from a provider's
+// config entry.
+type AnthropicOptions struct {
+	// ThinkingBudget enables extended thinking with this many tokens of
+	// reasoning per turn. 0 disables it; the API minimum is 1024.
+	ThinkingBudget int
+
+	// DisablePromptCache stops cache_control breakpoints being sent.
+	DisablePromptCache bool
+
+	// BaseURL replaces https://api.anthropic.com, e.g. for a proxy or a
+	// local stub.
+	BaseURL string
+}
+
+// anthropicMinThinkingBudget is the smallest budget_tokens the API accepts.
+const anthropicMinThinkingBudget = 1024
+
+func NewAnthropic(apiKey, model string, opts AnthropicOptions) *AnthropicProvider {
 	if model == "" {
 		model = "claude-3-5-sonnet-20240620"
 	}
-	return &AnthropicProvider{apiKey: apiKey, model: model, client: &http.Client{}}
+	if opts.ThinkingBudget > 0 && opts.ThinkingBudget < anthropicMinThinkingBudget {
+		opts.ThinkingBudget = anthropicMinThinkingBudget
+	}
+	baseURL := strings.TrimRight(opts.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	return &AnthropicProvider{apiKey: apiKey, model: model, baseURL: baseURL, opts: opts, client: &http.Client{}}
 }
 
 func (a *AnthropicProvider) Name() string { return "anthropic" }
 
 func (a *AnthropicProvider) ModelName() string { return a.model }
 
-func (a *AnthropicProvider) Models(_ context.Context) ([]string, error) {
-	return []string{
-		"claude-3-5-sonnet-20240620",
-		"claude-3-opus-20240229",
-		"claude-3-haiku-20240307",
-	}, nil
+func (a *AnthropicProvider) Models(ctx context.Context) ([]string, error) {
+	infos, err := a.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return modelIDs(infos), nil
+}
+
+// ListModels fetches the account's models from /v1/models, cached on disk.
+func (a *AnthropicProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return cachedModels("anthropic", a.baseURL, func() ([]ModelInfo, error) {
+		var infos []ModelInfo
+		afterID := ""
+		for {
+			url := a.baseURL + "/v1/models?limit=1000"
+			if afterID != "" {
+				url += "&after_id=" + afterID
+			}
+			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("x-api-key", a.apiKey)
+			req.Header.Set("anthropic-version", "2023-06-01")
+			resp, err := a.client.Do(req)
+			if err != nil {
+				return nil, newTransportError("anthropic", err)
+			}
+			var page struct {
+				Data []struct {
+					ID             string `json:"id"`
+					DisplayName    string `json:"display_name"`
+					MaxInputTokens int    `json:"max_input_tokens"`
+					MaxTokens      int    `json:"max_tokens"`
+				} `json:"data"`
+				HasMore bool   `json:"has_more"`
+				LastID  string `json:"last_id"`
+			}
+			if resp.StatusCode != 200 {
+				b, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				return nil, newHTTPError("anthropic", resp, b)
+			}
+			err = json.NewDecoder(resp.Body).Decode(&page)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range page.Data {
+				info := anthropicModelInfo(m.ID)
+				info.DisplayName = m.DisplayName
+				if m.MaxInputTokens > 0 {
+					info.ContextWindow = m.MaxInputTokens
+				}
+				info.MaxOutputTokens = m.MaxTokens
+				infos = append(infos, info)
+			}
+			if !page.HasMore || page.LastID == "" {
+				return infos, nil
+			}
+			afterID = page.LastID
+		}
+	})
+}
+
+// anthropicModelInfo fills in what the models endpoint may not report. Every
+// Claude 3 and later model has a 200k window and accepts images and tools;
+// extended thinking arrived with Claude 3.7.
+func anthropicModelInfo(id string) ModelInfo {
+	return ModelInfo{
+		ID:            id,
+		ContextWindow: 200000,
+		Vision:        true,
+		Tools:         true,
+		Thinking:      !strings.HasPrefix(id, "claude-3-") || strings.HasPrefix(id, "claude-3-7"),
+	}
 }
 
 type anthropicRequest struct {
-	Model     string          `json:"model"`
-	MaxTokens int             `json:"max_tokens"`
-	System    string          `json:"system,omitempty"`
-	Messages  []anthropicMsg  `json:"messages"`
-	Stream    bool            `json:"stream"`
-	Tools     []anthropicTool `json:"tools,omitempty"`
+	Model         string          `json:"model"`
+	MaxTokens     int             `json:"max_tokens"`
+	System        []anthropicText `json:"system,omitempty"`
+	Messages      []anthropicMsg  `json:"messages"`
+	Stream        bool            `json:"stream"`
+	Tools         []anthropicTool `json:"tools,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
+	TopP          *float64        `json:"top_p,omitempty"`
+	StopSequences []string        `json:"stop_sequences,omitempty"`
+	ToolChoice    map[string]any  `json:"tool_choice,omitempty"`
+	Thinking      map[string]any  `json:"thinking,omitempty"`
+}
+
+// anthropicText is a system prompt text block.
+type anthropicText struct {
+	Type         string         `json:"type"`
+	Text         string         `json:"text"`
+	CacheControl map[string]any `json:"cache_control,omitempty"`
 }
 
+// anthropicCacheBreakpoint marks the end of a prefix Anthropic should cache.
+// Everything up to and including the marked block is cached for five
+// minutes, and reads from it are billed at a tenth of the input rate.
+var anthropicCacheBreakpoint = map[string]any{"type": "ephemeral"}
+
+// anthropicDefaultMaxTokens is used when the caller doesn't set WithMaxTokens;
+// the Messages API requires max_tokens on every request.
+const anthropicDefaultMaxTokens = 8192
+
 type anthropicMsg struct {
 	Role    string `json:"role"`
 	Content any    `json:"content"`
 }
 
 type anthropicTool struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	InputSchema any    `json:"input_schema"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	InputSchema  any            `json:"input_schema"`
+	CacheControl map[string]any `json:"cache_control,omitempty"`
 }
 
 type anthropicEvent struct {
@@ -64,33 +182,83 @@ type anthropicEvent struct {
 		Type string `json:"type"`
 		ID   string `json:"id,omitempty"`
 		Name string `json:"name,omitempty"`
+		Data string `json:"data,omitempty"` // redacted_thinking
 	} `json:"content_block,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"` // message_start
+	Usage *anthropicUsage `json:"usage,omitempty"` // message_delta
 }
 
-func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef) (<-chan StreamChunk, error) {
-	var systemPrompt string
+// anthropicUsage reports input tokens split by cache status; input_tokens
+// covers only the uncached remainder after the last cache breakpoint.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (u anthropicUsage) toUsage() *Usage {
+	input := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return &Usage{
+		InputTokens:       input,
+		OutputTokens:      u.OutputTokens,
+		TotalTokens:       input + u.OutputTokens,
+		CachedInputTokens: u.CacheReadInputTokens,
+		CacheWriteTokens:  u.CacheCreationInputTokens,
+	}
+}
+
+func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	genOpts := ResolveChatOptions(ctx, opts)
+
+	// Anthropic caches request prefixes, so the system prompt is split in
+	// two: the leading system messages, which are stable for a session, end
+	// in a cache breakpoint; system messages injected later in the
+	// conversation change every turn and are sent after it uncached.
+	var system []anthropicText
 	var apiMsgs []anthropicMsg
+	leading, stableSystem := true, false
 	for _, m := range msgs {
 		if m.Role == RoleSystem {
-			if systemPrompt != "" {
-				systemPrompt += "\n\n"
+			if leading && stableSystem {
+				system[0].Text += "\n\n" + m.Content
+			} else {
+				system = append(system, anthropicText{Type: "text", Text: m.Content})
+				stableSystem = stableSystem || leading
 			}
-			systemPrompt += m.Content
 			continue
 		}
+		leading = false
 		if m.Role == RoleTool {
+			var content any = m.Content
+			if len(m.Parts) > 0 {
+				content = anthropicContentBlocks(m.Content, m.Parts)
+			}
 			apiMsgs = append(apiMsgs, anthropicMsg{
 				Role: "user",
 				Content: []map[string]any{{
 					"type":        "tool_result",
 					"tool_use_id": m.ToolCallID,
-					"content":     m.Content,
+					"content":     content,
 				}},
 			})
 			continue
 		}
 		if m.Role == RoleAssistant && len(m.ToolCalls) > 0 {
 			var blocks []map[string]any
+			for _, tb := range m.ThinkingBlocks {
+				if tb.Data != "" {
+					blocks = append(blocks, map[string]any{"type": "redacted_thinking", "data": tb.Data})
+				} else {
+					blocks = append(blocks, map[string]any{"type": "thinking", "thinking": tb.Thinking, "signature": tb.Signature})
+				}
+			}
 			if m.Content != "" {
 				blocks = append(blocks, map[string]any{"type": "text", "text": m.Content})
 			}
@@ -107,6 +275,10 @@ func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []To
 			apiMsgs = append(apiMsgs, anthropicMsg{Role: "assistant", Content: blocks})
 			continue
 		}
+		if len(m.Parts) > 0 {
+			apiMsgs = append(apiMsgs, anthropicMsg{Role: string(m.Role), Content: anthropicContentBlocks(m.Content, m.Parts)})
+			continue
+		}
 		apiMsgs = append(apiMsgs, anthropicMsg{Role: string(m.Role), Content: m.Content})
 	}
 
@@ -114,14 +286,61 @@ func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []To
 	for _, t := range tools {
 		apiTools = append(apiTools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: t.Parameters})
 	}
+	if !a.opts.DisablePromptCache {
+		// Tools come before the system prompt in the cached prefix, so a
+		// breakpoint on the last tool caches all of them.
+		if len(apiTools) > 0 {
+			apiTools[len(apiTools)-1].CacheControl = anthropicCacheBreakpoint
+		}
+		if stableSystem {
+			system[0].CacheControl = anthropicCacheBreakpoint
+		}
+	}
+
+	// Anthropic has no JSON mode; structured output is emulated by forcing a call
+	// to a synthetic tool whose input schema is the requested schema. Its input
+	// is streamed back as plain text so callers see an ordinary JSON reply.
+	var structuredTool string
+	var toolChoice map[string]any
+	if rf := genOpts.ResponseFormat; rf != nil {
+		structuredTool = rf.Name
+		schema := rf.Schema
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		apiTools = append(apiTools, anthropicTool{
+			Name:        structuredTool,
+			Description: "Respond by calling this tool with your structured answer.",
+			InputSchema: schema,
+		})
+		toolChoice = map[string]any{"type": "tool", "name": structuredTool}
+	}
+
+	maxTokens := genOpts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
 
+	// Anthropic has no seed parameter; it is ignored here.
 	body := anthropicRequest{
-		Model: a.model, MaxTokens: 8192, System: systemPrompt,
+		Model: a.model, MaxTokens: maxTokens, System: system,
 		Messages: apiMsgs, Stream: true, Tools: apiTools,
+		Temperature: genOpts.Temperature, TopP: genOpts.TopP, StopSequences: genOpts.Stop,
+		ToolChoice: toolChoice,
+	}
+	if a.thinkingAllowed(msgs, genOpts, structuredTool) {
+		budget := a.opts.ThinkingBudget
+		body.Thinking = map[string]any{"type": "enabled", "budget_tokens": budget}
+		// max_tokens includes the thinking budget, and sampling parameters
+		// are rejected while thinking is enabled.
+		if body.MaxTokens <= budget {
+			body.MaxTokens = budget + maxTokens
+		}
+		body.Temperature, body.TopP = nil, nil
 	}
 	payload, _ := json.Marshal(body)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/v1/messages", bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
 	}
@@ -131,12 +350,12 @@ func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []To
 
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newTransportError("anthropic", err)
 	}
 	if resp.StatusCode != 200 {
 		b, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("anthropic: %s", parseProviderError("anthropic", resp.StatusCode, b))
+		return nil, newHTTPError("anthropic", resp, b)
 	}
 
 	ch := make(chan StreamChunk, 64)
@@ -148,7 +367,9 @@ func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []To
 		var currentToolID, currentToolName string
 		var toolArgsBuilder strings.Builder
 		var toolCalls []ToolCall
-		inThinkingBlock := false
+		var usage anthropicUsage
+		var thinking []ThinkingBlock
+		var currentThinking *ThinkingBlock
 
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -161,6 +382,14 @@ func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []To
 				continue
 			}
 			switch evt.Type {
+			case "message_start":
+				if evt.Message != nil {
+					usage = evt.Message.Usage
+				}
+			case "message_delta":
+				if evt.Usage != nil {
+					usage.OutputTokens = evt.Usage.OutputTokens
+				}
 			case "content_block_start":
 				if evt.ContentBlock != nil {
 					switch evt.ContentBlock.Type {
@@ -168,8 +397,15 @@ func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []To
 						currentToolID = evt.ContentBlock.ID
 						currentToolName = evt.ContentBlock.Name
 						toolArgsBuilder.Reset()
+						if structuredTool == "" || currentToolName != structuredTool {
+							ch <- StreamChunk{ToolCallDelta: &ToolCallDelta{
+								Index: len(toolCalls), ID: currentToolID, Name: currentToolName,
+							}}
+						}
 					case "thinking":
-						inThinkingBlock = true
+						currentThinking = &ThinkingBlock{}
+					case "redacted_thinking":
+						currentThinking = &ThinkingBlock{Data: evt.ContentBlock.Data}
 					}
 				}
 			case "content_block_delta":
@@ -177,31 +413,119 @@ func (a *AnthropicProvider) Chat(ctx context.Context, msgs []Message, tools []To
 					Type        string `json:"type"`
 					Text        string `json:"text"`
 					Thinking    string `json:"thinking"`
+					Signature   string `json:"signature"`
 					PartialJSON string `json:"partial_json"`
 				}
 				json.Unmarshal(evt.Delta, &delta)
 				if delta.Type == "thinking_delta" {
+					if currentThinking != nil {
+						currentThinking.Thinking += delta.Thinking
+					}
 					ch <- StreamChunk{Thinking: delta.Thinking}
+				} else if delta.Type == "signature_delta" {
+					if currentThinking != nil {
+						currentThinking.Signature += delta.Signature
+					}
 				} else if delta.Type == "text_delta" {
 					ch <- StreamChunk{Delta: delta.Text}
 				} else if delta.Type == "input_json_delta" {
-					toolArgsBuilder.WriteString(delta.PartialJSON)
+					if structuredTool != "" && currentToolName == structuredTool {
+						ch <- StreamChunk{Delta: delta.PartialJSON}
+					} else {
+						toolArgsBuilder.WriteString(delta.PartialJSON)
+						if delta.PartialJSON != "" {
+							ch <- StreamChunk{ToolCallDelta: &ToolCallDelta{Index: len(toolCalls), Args: delta.PartialJSON}}
+						}
+					}
 				}
 			case "content_block_stop":
-				if currentToolID != "" {
+				if currentToolID != "" && (structuredTool == "" || currentToolName != structuredTool) {
 					toolCalls = append(toolCalls, ToolCall{
 						ID: currentToolID, Name: currentToolName, Args: toolArgsBuilder.String(),
 					})
-					currentToolID = ""
 				}
-				if inThinkingBlock {
-					inThinkingBlock = false
+				currentToolID, currentToolName = "", ""
+				if currentThinking != nil {
+					thinking = append(thinking, *currentThinking)
+					currentThinking = nil
 				}
 			case "message_stop":
-				ch <- StreamChunk{Done: true, ToolCalls: toolCalls}
+				ch <- StreamChunk{Done: true, ToolCalls: toolCalls, Usage: usage.toUsage(), ThinkingBlocks: thinking}
+				return
+			case "error":
+				// Sent mid-stream, e.g. overloaded_error after a 200 response
+				err := anthropicStreamError("error", "stream error")
+				if evt.Error != nil {
+					err = anthropicStreamError(evt.Error.Type, evt.Error.Message)
+				}
+				ch <- StreamChunk{Error: err, Done: true}
 				return
 			}
 		}
+		ch <- StreamChunk{Error: streamEndError("anthropic", scanner.Err()), Done: true}
 	}()
 	return ch, nil
 }
+
+// thinkingAllowed reports whether extended thinking can be enabled for this
+// request. It is skipped when a tool call is forced (structured output),
+// when the caller's token limit leaves no room for the budget, and when the
+// turn continues a tool call that was made without thinking: the API
+// requires such turns to begin with the original thinking block.
+func (a *AnthropicProvider) thinkingAllowed(msgs []Message, opts ChatOptions, structuredTool string) bool {
+	budget := a.opts.ThinkingBudget
+	if budget <= 0 || structuredTool != "" {
+		return false
+	}
+	if opts.MaxTokens > 0 && opts.MaxTokens <= budget {
+		return false
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == RoleAssistant {
+			return len(msgs[i].ToolCalls) == 0 || len(msgs[i].ThinkingBlocks) > 0
+		}
+		if msgs[i].Role == RoleUser {
+			return true
+		}
+	}
+	return true
+}
+
+// anthropicContentBlocks builds text and image content blocks.
+func anthropicContentBlocks(text string, parts []ContentPart) []map[string]any {
+	var blocks []map[string]any
+	if text != "" {
+		blocks = append(blocks, map[string]any{"type": "text", "text": text})
+	}
+	for _, p := range parts {
+		switch p.Type {
+		case PartText:
+			blocks = append(blocks, map[string]any{"type": "text", "text": p.Text})
+		case PartImage:
+			source := map[string]any{"type": "base64", "media_type": p.MIMEType, "data": p.Base64()}
+			if p.URL != "" {
+				source = map[string]any{"type": "url", "url": p.URL}
+			}
+			blocks = append(blocks, map[string]any{"type": "image", "source": source})
+		}
+	}
+	return blocks
+}
+
+// anthropicStreamError converts an SSE error event into an Error. Overload and
+// server errors are transient; anything else (e.g. invalid_request_error) is not.
+func anthropicStreamError(errType, message string) *Error {
+	e := &Error{Provider: "anthropic", Code: errType, Message: message}
+	switch errType {
+	case "overloaded_error":
+		e.StatusCode, e.Retryable = 529, true
+	case "api_error":
+		e.StatusCode, e.Retryable = 500, true
+	case "rate_limit_error":
+		e.StatusCode, e.Retryable = 429, true
+	}
+	if e.Message == "" {
+		e.Message = errType
+	}
+	return e
+}
@@ -0,0 +1,254 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// anthropicServer returns a provider pointed at a test server that captures
+// the request body and replies with the given SSE events.
+func anthropicServer(t *testing.T, opts AnthropicOptions, events []string, captured *map[string]any) *AnthropicProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s, want /v1/messages", r.URL.Path)
+		}
+		if captured != nil {
+			json.NewDecoder(r.Body).Decode(captured)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			w.Write([]byte("data: " + e + "\n\n"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	p := NewAnthropic("key", "claude-sonnet-4-20250514", opts)
+	p.baseURL = server.URL
+	return p
+}
+
+func drainAnthropic(t *testing.T, ch <-chan StreamChunk) (thinking string, done StreamChunk) {
+	t.Helper()
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("stream error: %v", chunk.Error)
+		}
+		thinking += chunk.Thinking
+		if chunk.Done {
+			done = chunk
+		}
+	}
+	return thinking, done
+}
+
+var anthropicStop = []string{`{"type":"message_stop"}`}
+
+func TestAnthropic_CacheBreakpoints(t *testing.T) {
+	var req map[string]any
+	p := anthropicServer(t, AnthropicOptions{}, anthropicStop, &req)
+	msgs := []Message{
+		{Role: RoleSystem, Content: "You are a coding agent."},
+		{Role: RoleSystem, Content: "Project rules."},
+		{Role: RoleUser, Content: "hi"},
+		{Role: RoleSystem, Content: "Turn 3 of 50."},
+	}
+	tools := []ToolDef{{Name: "bash"}, {Name: "file_read"}}
+	ch, err := p.Chat(context.Background(), msgs, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drainAnthropic(t, ch)
+
+	system := req["system"].([]any)
+	if len(system) != 2 {
+		t.Fatalf("system blocks = %d, want 2 (stable + dynamic)", len(system))
+	}
+	stable, dynamic := system[0].(map[string]any), system[1].(map[string]any)
+	if stable["text"] != "You are a coding agent.\n\nProject rules." || stable["cache_control"] == nil {
+		t.Errorf("stable system block = %v, want joined leading prompts with cache_control", stable)
+	}
+	if dynamic["cache_control"] != nil {
+		t.Error("dynamic system block should not be cached")
+	}
+
+	apiTools := req["tools"].([]any)
+	if apiTools[0].(map[string]any)["cache_control"] != nil || apiTools[1].(map[string]any)["cache_control"] == nil {
+		t.Errorf("only the last tool should carry cache_control: %v", apiTools)
+	}
+	if req["thinking"] != nil {
+		t.Error("thinking should be off without a budget")
+	}
+}
+
+func TestAnthropic_PromptCacheDisabled(t *testing.T) {
+	var req map[string]any
+	p := anthropicServer(t, AnthropicOptions{DisablePromptCache: true}, anthropicStop, &req)
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleSystem, Content: "sys"}, {Role: RoleUser, Content: "hi"}}, []ToolDef{{Name: "bash"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drainAnthropic(t, ch)
+	if req["system"].([]any)[0].(map[string]any)["cache_control"] != nil || req["tools"].([]any)[0].(map[string]any)["cache_control"] != nil {
+		t.Error("cache_control sent with prompt caching disabled")
+	}
+}
+
+func TestAnthropic_ThinkingRoundTrip(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":20,"cache_creation_input_tokens":1500,"cache_read_input_tokens":3000,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need to list "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"files."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig123"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"opaque"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"tu_1","name":"bash"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"command\":\"ls\"}"}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","usage":{"output_tokens":40}}`,
+		`{"type":"message_stop"}`,
+	}
+	var req map[string]any
+	p := anthropicServer(t, AnthropicOptions{ThinkingBudget: 10000}, events, &req)
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "list files"}}, []ToolDef{{Name: "bash"}},
+		WithTemperature(0.2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deltas []ToolCallDelta
+	relay := make(chan StreamChunk, 64)
+	go func() {
+		defer close(relay)
+		for chunk := range ch {
+			if chunk.ToolCallDelta != nil {
+				deltas = append(deltas, *chunk.ToolCallDelta)
+			}
+			relay <- chunk
+		}
+	}()
+	thinking, done := drainAnthropic(t, relay)
+
+	wantDeltas := []ToolCallDelta{{ID: "tu_1", Name: "bash"}, {Args: `{"command":"ls"}`}}
+	if !reflect.DeepEqual(deltas, wantDeltas) {
+		t.Errorf("tool call deltas = %+v, want %+v", deltas, wantDeltas)
+	}
+	if thinking != "Need to list files." {
+		t.Errorf("thinking = %q", thinking)
+	}
+	want := []ThinkingBlock{{Thinking: "Need to list files.", Signature: "sig123"}, {Data: "opaque"}}
+	if !reflect.DeepEqual(done.ThinkingBlocks, want) {
+		t.Errorf("ThinkingBlocks = %+v, want %+v", done.ThinkingBlocks, want)
+	}
+	wantUsage := Usage{InputTokens: 4520, OutputTokens: 40, TotalTokens: 4560, CachedInputTokens: 3000, CacheWriteTokens: 1500}
+	if done.Usage == nil || *done.Usage != wantUsage {
+		t.Errorf("usage = %+v, want %+v", done.Usage, wantUsage)
+	}
+
+	th, _ := req["thinking"].(map[string]any)
+	if th["type"] != "enabled" || th["budget_tokens"] != float64(10000) {
+		t.Errorf("thinking = %v", req["thinking"])
+	}
+	if req["temperature"] != nil {
+		t.Error("temperature must not be sent with thinking enabled")
+	}
+	if req["max_tokens"] != float64(10000+anthropicDefaultMaxTokens) {
+		t.Errorf("max_tokens = %v, want room for the budget plus the answer", req["max_tokens"])
+	}
+
+	// The next request must replay the signed blocks ahead of tool_use.
+	followUp := []Message{
+		{Role: RoleUser, Content: "list files"},
+		{Role: RoleAssistant, ToolCalls: done.ToolCalls, ThinkingBlocks: done.ThinkingBlocks},
+		{Role: RoleTool, ToolCallID: "tu_1", Content: "main.go"},
+	}
+	p2 := anthropicServer(t, AnthropicOptions{ThinkingBudget: 2000}, anthropicStop, &req)
+	ch, err = p2.Chat(context.Background(), followUp, []ToolDef{{Name: "bash"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drainAnthropic(t, ch)
+	blocks := req["messages"].([]any)[1].(map[string]any)["content"].([]any)
+	types := []string{}
+	for _, b := range blocks {
+		types = append(types, b.(map[string]any)["type"].(string))
+	}
+	if !reflect.DeepEqual(types, []string{"thinking", "redacted_thinking", "tool_use"}) {
+		t.Errorf("assistant blocks = %v", types)
+	}
+	if blocks[0].(map[string]any)["signature"] != "sig123" {
+		t.Errorf("signature not replayed: %v", blocks[0])
+	}
+}
+
+func TestAnthropic_ThinkingSkipped(t *testing.T) {
+	tests := []struct {
+		name string
+		msgs []Message
+		opts []ChatOption
+	}{
+		{
+			name: "structured output",
+			msgs: []Message{{Role: RoleUser, Content: "hi"}},
+			opts: []ChatOption{WithResponseSchema("answer", nil)},
+		},
+		{
+			name: "max tokens below budget",
+			msgs: []Message{{Role: RoleUser, Content: "hi"}},
+			opts: []ChatOption{WithMaxTokens(500)},
+		},
+		{
+			name: "tool call made without thinking",
+			msgs: []Message{
+				{Role: RoleUser, Content: "hi"},
+				{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "t1", Name: "bash", Args: "{}"}}},
+				{Role: RoleTool, ToolCallID: "t1", Content: "ok"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req map[string]any
+			p := anthropicServer(t, AnthropicOptions{ThinkingBudget: 2000}, anthropicStop, &req)
+			ch, err := p.Chat(context.Background(), tt.msgs, nil, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			drainAnthropic(t, ch)
+			if req["thinking"] != nil {
+				t.Errorf("thinking enabled: %v", req["thinking"])
+			}
+		})
+	}
+}
+
+func TestAnthropic_StreamErrorEvent(t *testing.T) {
+	for _, tc := range []struct {
+		event, code string
+		retryable   bool
+	}{
+		{`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "overloaded_error", true},
+		{`{"type":"error"}`, "error", false},
+	} {
+		p := anthropicServer(t, AnthropicOptions{}, []string{tc.event}, nil)
+		ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got error
+		for chunk := range ch {
+			if chunk.Error != nil {
+				got = chunk.Error
+			}
+		}
+		var perr *Error
+		if !errors.As(got, &perr) || perr.Code != tc.code || perr.Retryable != tc.retryable {
+			t.Errorf("%s: error = %#v, want code %q retryable %v", tc.event, got, tc.code, tc.retryable)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is wrapped by the error a Breaker returns while it is
+// refusing calls.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed passes every call through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails calls immediately until the cooldown has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets one real call through as the trial; its outcome
+	// closes or reopens the circuit. Listing models is no test, since many
+	// providers answer it from a cache or a fixed list.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+func (s BreakerState) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+func (s *BreakerState) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "open":
+		*s = BreakerOpen
+	case "half-open":
+		*s = BreakerHalfOpen
+	default:
+		*s = BreakerClosed
+	}
+	return nil
+}
+
+// BreakerConfig tunes a Breaker. Zero values take the defaults.
+type BreakerConfig struct {
+	Failures int           // Consecutive failures that open the circuit; default 3
+	Cooldown time.Duration // Wait before a trial call on an open circuit; default 30s
+}
+
+// BreakerStatus is a snapshot of a Breaker, for status displays.
+type BreakerStatus struct {
+	Name      string        `json:"name"`
+	State     BreakerState  `json:"state"`
+	Failures  int           `json:"failures"`             // Consecutive, reset by a success
+	Latency   time.Duration `json:"latency"`              // Moving average time to first chunk
+	LastError string        `json:"last_error,omitempty"` // Most recent failure
+	Since     time.Time     `json:"since"`                // When State was entered
+	RetryAt   time.Time     `json:"retry_at,omitempty"`   // Next trial call, while open
+}
+
+// Breaker tracks the health of one backend. Like a Limiter, one Breaker is
+// shared by every provider instance talking to that backend, so a dead
+// endpoint found by one agent is skipped by all of them.
+type Breaker struct {
+	name string
+	cfg  BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	latency  time.Duration
+	lastErr  string
+	since    time.Time
+	retryAt  time.Time
+	probing  bool // A half-open trial call is in flight
+}
+
+func NewBreaker(name string, cfg BreakerConfig) *Breaker {
+	if cfg.Failures <= 0 {
+		cfg.Failures = 3
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	return &Breaker{name: name, cfg: cfg, since: time.Now()}
+}
+
+var (
+	sharedBreakersMu sync.Mutex
+	sharedBreakers   = map[string]*Breaker{}
+)
+
+// SharedBreaker returns the process-wide breaker for name, creating it from
+// cfg on first use.
+func SharedBreaker(name string, cfg BreakerConfig) *Breaker {
+	sharedBreakersMu.Lock()
+	defer sharedBreakersMu.Unlock()
+	if b, ok := sharedBreakers[name]; ok {
+		return b
+	}
+	b := NewBreaker(name, cfg)
+	sharedBreakers[name] = b
+	return b
+}
+
+// BreakerStatuses reports every shared breaker, by name.
+func BreakerStatuses() []BreakerStatus {
+	sharedBreakersMu.Lock()
+	breakers := make([]*Breaker, 0, len(sharedBreakers))
+	for _, b := range sharedBreakers {
+		breakers = append(breakers, b)
+	}
+	sharedBreakersMu.Unlock()
+
+	statuses := make([]BreakerStatus, len(breakers))
+	for i, b := range breakers {
+		statuses[i] = b.Status()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := BreakerStatus{
+		Name: b.name, State: b.state, Failures: b.failures,
+		Latency: b.latency, LastError: b.lastErr, Since: b.since,
+	}
+	if b.state == BreakerOpen {
+		s.RetryAt = b.retryAt
+	}
+	return s
+}
+
+// allow decides whether a call may go ahead, letting the first one after
+// an open circuit's cooldown through as the half-open trial. A nil error
+// means the caller must report the outcome with success, failure or
+// release.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	switch {
+	case b.state == BreakerClosed:
+		b.mu.Unlock()
+		return nil
+	case b.probing || time.Now().Before(b.retryAt):
+		err := b.openError()
+		b.mu.Unlock()
+		return err
+	}
+	b.probing = true
+	if b.state == BreakerOpen {
+		b.setState(BreakerHalfOpen)
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *Breaker) openError() error {
+	msg := fmt.Sprintf("circuit open after %d consecutive failures", b.failures)
+	if b.lastErr != "" {
+		msg += " (last: " + b.lastErr + ")"
+	}
+	if wait := time.Until(b.retryAt); wait > 0 {
+		msg += fmt.Sprintf("; retrying in %s", wait.Round(time.Second))
+	}
+	return &Error{Provider: b.name, Code: "circuit_open", Message: msg, Err: ErrCircuitOpen}
+}
+
+// success records a call that produced a complete response after
+// firstChunk.
+func (b *Breaker) success(firstChunk time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.latency == 0 {
+		b.latency = firstChunk
+	} else {
+		b.latency = (b.latency*4 + firstChunk) / 5
+	}
+	b.failures = 0
+	b.probing = false
+	if b.state != BreakerClosed {
+		b.setState(BreakerClosed)
+	}
+}
+
+func (b *Breaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.lastErr = err.Error()
+	if pe, ok := AsError(err); ok {
+		b.lastErr = pe.Message
+	}
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.cfg.Failures {
+		b.retryAt = time.Now().Add(b.cfg.Cooldown)
+		b.setState(BreakerOpen)
+	}
+}
+
+// release gives up a half-open trial without a verdict, e.g. when the
+// caller cancelled it.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// setState is called with mu held.
+func (b *Breaker) setState(s BreakerState) {
+	b.state = s
+	b.since = time.Now()
+	b.save()
+}
+
+// breakerFailure reports whether err says the backend is unhealthy, as
+// opposed to the request being bad or the caller giving up. Rate limiting
+// means the backend is up, so it doesn't count.
+func breakerFailure(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if pe, ok := AsError(err); ok && pe.StatusCode == 429 {
+		return false
+	}
+	return fallbackReason(err) == "unavailable"
+}
+
+// BreakerProvider fails fast while its Breaker is open instead of letting
+// every call wait out a timeout against a dead endpoint.
+type BreakerProvider struct {
+	inner   Provider
+	breaker *Breaker
+}
+
+// WithBreaker wraps p so that its calls are guarded by breaker.
+func WithBreaker(p Provider, breaker *Breaker) *BreakerProvider {
+	return &BreakerProvider{inner: p, breaker: breaker}
+}
+
+func (bp *BreakerProvider) Name() string { return bp.inner.Name() }
+
+func (bp *BreakerProvider) ModelName() string { return bp.inner.ModelName() }
+
+func (bp *BreakerProvider) Unwrap() Provider { return bp.inner }
+
+func (bp *BreakerProvider) Models(ctx context.Context) ([]string, error) {
+	return bp.inner.Models(ctx)
+}
+
+func (bp *BreakerProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	if err := bp.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	ch, err := bp.inner.Chat(ctx, msgs, tools, opts...)
+	if err != nil {
+		bp.settle(ctx, err, 0)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 64)
+	go func() {
+		defer close(out)
+		var firstChunk time.Duration
+		var streamErr error
+		for chunk := range ch {
+			if firstChunk == 0 {
+				firstChunk = time.Since(start)
+			}
+			if chunk.Error != nil {
+				streamErr = chunk.Error
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				go func() {
+					for range ch {
+					}
+				}()
+				bp.breaker.release()
+				return
+			}
+		}
+		bp.settle(ctx, streamErr, firstChunk)
+	}()
+	return out, nil
+}
+
+func (bp *BreakerProvider) settle(ctx context.Context, err error, firstChunk time.Duration) {
+	switch {
+	case err == nil && ctx.Err() == nil:
+		bp.breaker.success(firstChunk)
+	case err != nil && breakerFailure(ctx, err):
+		bp.breaker.failure(err)
+	default:
+		bp.breaker.release()
+	}
+}
+
+// Breaker state is saved on every transition so that `aseity doctor`, which
+// runs in its own process, can report what the last session saw.
+
+func breakerStatePath() string {
+	return filepath.Join(filepath.Dir(modelCacheDir()), "breakers.json")
+}
+
+var breakerFileMu sync.Mutex
+
+// save records b's status in the state file. Called with b.mu held.
+func (b *Breaker) save() {
+	s := BreakerStatus{
+		Name: b.name, State: b.state, Failures: b.failures,
+		Latency: b.latency, LastError: b.lastErr, Since: b.since, RetryAt: b.retryAt,
+	}
+	breakerFileMu.Lock()
+	defer breakerFileMu.Unlock()
+	saved, _ := LoadBreakerStatuses()
+	if saved == nil {
+		saved = map[string]BreakerStatus{}
+	}
+	saved[b.name] = s
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return
+	}
+	path := breakerStatePath()
+	if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		os.WriteFile(path, data, 0644)
+	}
+}
+
+// LoadBreakerStatuses returns the breaker states last saved by any session,
+// by provider name.
+func LoadBreakerStatuses() (map[string]BreakerStatus, error) {
+	data, err := os.ReadFile(breakerStatePath())
+	if err != nil {
+		return nil, err
+	}
+	var saved map[string]BreakerStatus
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func breakerChat(p Provider) error {
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		return err
+	}
+	for chunk := range ch {
+		if chunk.Error != nil {
+			err = chunk.Error
+		}
+	}
+	return err
+}
+
+func TestBreaker_OpensAndRecovers(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	refused := errors.New("provider ollama: dial tcp: connection refused")
+	inner := &stubProvider{name: "ollama", err: refused}
+	b := NewBreaker("ollama", BreakerConfig{Failures: 2, Cooldown: 20 * time.Millisecond})
+	p := WithBreaker(inner, b)
+
+	for i := 0; i < 2; i++ {
+		if err := breakerChat(p); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: err = %v, want the backend error", i, err)
+		}
+	}
+	if s := b.Status(); s.State != BreakerOpen || s.Failures != 2 {
+		t.Fatalf("status = %+v, want open after 2 failures", s)
+	}
+	if err := breakerChat(p); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("open circuit reached the backend: %d calls", inner.calls)
+	}
+
+	// After the cooldown one real call goes through as the trial; it fails,
+	// so the circuit reopens for another cooldown.
+	time.Sleep(30 * time.Millisecond)
+	if err := breakerChat(p); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want the trial call's backend error", err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("trial call did not reach the backend: %d calls", inner.calls)
+	}
+	if err := breakerChat(p); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen after a failed trial", err)
+	}
+
+	// The backend is back: the next trial call closes the circuit.
+	inner.err = nil
+	time.Sleep(30 * time.Millisecond)
+	if err := breakerChat(p); err != nil {
+		t.Fatalf("trial call failed: %v", err)
+	}
+	if s := b.Status(); s.State != BreakerClosed || s.Failures != 0 {
+		t.Errorf("status = %+v, want closed", s)
+	}
+
+	saved, err := LoadBreakerStatuses()
+	if err != nil {
+		t.Fatalf("LoadBreakerStatuses: %v", err)
+	}
+	if s := saved["ollama"]; s.State != BreakerClosed || s.LastError == "" {
+		t.Errorf("saved status = %+v", s)
+	}
+}
+
+func TestBreaker_IgnoresClientErrors(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	b := NewBreaker("openai", BreakerConfig{Failures: 1})
+	for _, err := range []error{
+		&Error{Provider: "openai", StatusCode: 429, Retryable: true, Err: errors.New("rate limited")},
+		errors.New("provider openai: authentication failed — check your API key"),
+	} {
+		p := WithBreaker(&stubProvider{name: "openai", err: err}, b)
+		breakerChat(p)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := WithBreaker(&stubProvider{name: "openai", err: context.Canceled}, b)
+	p.Chat(ctx, nil, nil)
+
+	if s := b.Status(); s.State != BreakerClosed || s.Failures != 0 {
+		t.Errorf("status = %+v, want closed with no failures", s)
+	}
+}
+
+func TestRouter_SkipsOpenCircuit(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	b := NewBreaker("ollama", BreakerConfig{Failures: 1, Cooldown: time.Hour})
+	b.failure(errors.New("connection refused"))
+	local := &stubProvider{name: "ollama"}
+	cloud := &stubProvider{name: "anthropic"}
+
+	r := NewRouter([]Route{{Provider: WithBreaker(local, b)}, {Provider: cloud}}, 0)
+	ch, err := r.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	route, _ := collectRoute(t, ch)
+	if route.Provider != "anthropic" || len(route.Skipped) != 1 || route.Skipped[0] != "ollama: circuit open" {
+		t.Errorf("unexpected route: %+v", route)
+	}
+	if local.calls != 0 {
+		t.Error("the open circuit should not reach the backend")
+	}
+}
@@ -0,0 +1,321 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// A cassette is a JSON Lines file with one Interaction per Chat call. It is
+// appended to as calls complete, so a crashed session still leaves a usable
+// recording.
+
+// Interaction is one recorded Chat call and the stream it produced.
+type Interaction struct {
+	Key      string          `json:"key"`       // Hash of the full request (strict matching)
+	LooseKey string          `json:"loose_key"` // Hash ignoring system messages and tools (lenient matching)
+	Provider string          `json:"provider"`
+	Model    string          `json:"model"`
+	Messages []Message       `json:"messages"`
+	Tools    []string        `json:"tools,omitempty"` // Tool names offered to the model
+	Chunks   []RecordedChunk `json:"chunks,omitempty"`
+	Error    string          `json:"error,omitempty"` // Set when Chat itself failed
+}
+
+// RecordedChunk is the serializable form of a StreamChunk.
+type RecordedChunk struct {
+	Delta     string     `json:"delta,omitempty"`
+	Thinking  string     `json:"thinking,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Done      bool       `json:"done,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Usage     *Usage     `json:"usage,omitempty"`
+
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
+	ToolCallDelta  *ToolCallDelta  `json:"tool_call_delta,omitempty"`
+}
+
+// MatchMode controls how a Replayer finds the recording for a request.
+type MatchMode int
+
+const (
+	// MatchStrict requires the exact same messages and tools.
+	MatchStrict MatchMode = iota
+	// MatchLenient ignores system messages and tool lists, then falls back to
+	// serving recordings in order. Use it when prompts contain volatile
+	// content like dates or turn counters.
+	MatchLenient
+)
+
+// requestKeys returns the strict and lenient hashes for a request.
+func requestKeys(msgs []Message, tools []ToolDef) (strict, loose string) {
+	names := toolNames(tools)
+	strict = hashJSON(struct {
+		Messages []Message `json:"messages"`
+		Tools    []string  `json:"tools"`
+	}{msgs, names})
+
+	var convo []Message
+	for _, m := range msgs {
+		if m.Role == RoleSystem {
+			continue
+		}
+		m.Content = strings.TrimSpace(m.Content)
+		convo = append(convo, m)
+	}
+	loose = hashJSON(convo)
+	return strict, loose
+}
+
+func hashJSON(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func toolNames(tools []ToolDef) []string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// CassetteWriter appends interactions to a cassette file. One writer may be
+// shared by several Recorders (e.g. the agent and judge providers).
+type CassetteWriter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// CreateCassette creates (or truncates) a cassette file for recording.
+func CreateCassette(path string) (*CassetteWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &CassetteWriter{f: f}, nil
+}
+
+func (w *CassetteWriter) write(in Interaction) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.f.Write(append(data, '\n'))
+	return err
+}
+
+func (w *CassetteWriter) Close() error { return w.f.Close() }
+
+// Recorder wraps a Provider and writes every Chat call to a cassette.
+type Recorder struct {
+	inner Provider
+	w     *CassetteWriter
+}
+
+func NewRecorder(p Provider, w *CassetteWriter) *Recorder {
+	return &Recorder{inner: p, w: w}
+}
+
+func (r *Recorder) Name() string { return r.inner.Name() }
+
+func (r *Recorder) ModelName() string { return r.inner.ModelName() }
+
+func (r *Recorder) Unwrap() Provider { return r.inner }
+
+func (r *Recorder) Models(ctx context.Context) ([]string, error) { return r.inner.Models(ctx) }
+
+func (r *Recorder) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	key, loose := requestKeys(msgs, tools)
+	in := Interaction{
+		Key:      key,
+		LooseKey: loose,
+		Provider: r.inner.Name(),
+		Model:    r.inner.ModelName(),
+		Messages: msgs,
+		Tools:    toolNames(tools),
+	}
+
+	ch, err := r.inner.Chat(ctx, msgs, tools, opts...)
+	if err != nil {
+		in.Error = err.Error()
+		r.save(in)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 64)
+	go func() {
+		defer close(out)
+		for chunk := range ch {
+			rc := RecordedChunk{
+				Delta: chunk.Delta, Thinking: chunk.Thinking, ToolCalls: chunk.ToolCalls,
+				Done: chunk.Done, Usage: chunk.Usage, ThinkingBlocks: chunk.ThinkingBlocks,
+				ToolCallDelta: chunk.ToolCallDelta,
+			}
+			if chunk.Error != nil {
+				rc.Error = chunk.Error.Error()
+			}
+			// Routing and restart markers describe this session, not the model's answer.
+			switch {
+			case chunk.Restart != nil:
+				in.Chunks = nil
+			case chunk.Route != nil:
+			default:
+				in.Chunks = append(in.Chunks, rc)
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				go func() {
+					for range ch {
+					}
+				}()
+				// Replay ends the answer where the caller gave up on it.
+				in.Chunks = append(in.Chunks, RecordedChunk{Error: ctx.Err().Error(), Done: true})
+				r.save(in)
+				return
+			}
+		}
+		r.save(in)
+	}()
+	return out, nil
+}
+
+func (r *Recorder) save(in Interaction) {
+	if err := r.w.write(in); err != nil {
+		fmt.Fprintf(os.Stderr, "recorder: failed to write cassette: %v\n", err)
+	}
+}
+
+// Replayer serves recorded interactions instead of calling a model.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	next         int // Lenient mode: next recording to serve in order
+	mode         MatchMode
+}
+
+// LoadCassette reads a cassette file for replay.
+func LoadCassette(path string, mode MatchMode) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var in Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("cassette %s line %d: %w", path, line, err)
+		}
+		interactions = append(interactions, in)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("cassette %s is empty", path)
+	}
+	return &Replayer{
+		interactions: interactions,
+		used:         make([]bool, len(interactions)),
+		mode:         mode,
+	}, nil
+}
+
+func (r *Replayer) Name() string { return r.interactions[0].Provider }
+
+func (r *Replayer) ModelName() string { return r.interactions[0].Model }
+
+func (r *Replayer) Models(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var models []string
+	for _, in := range r.interactions {
+		if !seen[in.Model] {
+			seen[in.Model] = true
+			models = append(models, in.Model)
+		}
+	}
+	return models, nil
+}
+
+// ErrNoRecording is returned when the cassette has no response for a request.
+var ErrNoRecording = errors.New("replay: no recorded response for this request")
+
+func (r *Replayer) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	in, ok := r.match(msgs, tools)
+	if !ok {
+		if r.mode == MatchStrict {
+			return nil, fmt.Errorf("%w (strict mode; try lenient matching)", ErrNoRecording)
+		}
+		return nil, ErrNoRecording
+	}
+	if in.Error != "" {
+		return nil, errors.New(in.Error)
+	}
+
+	ch := make(chan StreamChunk, len(in.Chunks))
+	for _, rc := range in.Chunks {
+		chunk := StreamChunk{
+			Delta: rc.Delta, Thinking: rc.Thinking, ToolCalls: rc.ToolCalls,
+			Done: rc.Done, Usage: rc.Usage, ThinkingBlocks: rc.ThinkingBlocks,
+			ToolCallDelta: rc.ToolCallDelta,
+		}
+		if rc.Error != "" {
+			chunk.Error = errors.New(rc.Error)
+		}
+		ch <- chunk
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (r *Replayer) match(msgs []Message, tools []ToolDef) (Interaction, bool) {
+	key, loose := requestKeys(msgs, tools)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	take := func(i int) (Interaction, bool) {
+		r.used[i] = true
+		if i >= r.next {
+			r.next = i + 1
+		}
+		return r.interactions[i], true
+	}
+	for i, in := range r.interactions {
+		if !r.used[i] && in.Key == key {
+			return take(i)
+		}
+	}
+	if r.mode == MatchStrict {
+		return Interaction{}, false
+	}
+	for i, in := range r.interactions {
+		if !r.used[i] && in.LooseKey == loose {
+			return take(i)
+		}
+	}
+	for i := r.next; i < len(r.interactions); i++ {
+		if !r.used[i] {
+			return take(i)
+		}
+	}
+	return Interaction{}, false
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func drain(t *testing.T, ch <-chan StreamChunk) string {
+	t.Helper()
+	var text string
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("stream error: %v", chunk.Error)
+		}
+		text += chunk.Delta
+	}
+	return text
+}
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	w, err := CreateCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecorder(&stubProvider{name: "ollama"}, w)
+
+	turn1 := []Message{{Role: RoleSystem, Content: "Turn 1/50"}, {Role: RoleUser, Content: "hello"}}
+	turn2 := []Message{{Role: RoleSystem, Content: "Turn 2/50"}, {Role: RoleUser, Content: "again"}}
+	for _, msgs := range [][]Message{turn1, turn2} {
+		ch, err := rec.Chat(context.Background(), msgs, []ToolDef{{Name: "bash"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		drain(t, ch)
+	}
+	w.Close()
+
+	strict, err := LoadCassette(path, MatchStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strict.Name() != "ollama" || strict.ModelName() != "ollama-model" {
+		t.Errorf("replayer identity = %s/%s", strict.Name(), strict.ModelName())
+	}
+	// Requests may arrive in any order
+	ch, err := strict.Chat(context.Background(), turn2, []ToolDef{{Name: "bash"}})
+	if err != nil {
+		t.Fatalf("strict replay failed: %v", err)
+	}
+	if got := drain(t, ch); got != "hi from ollama" {
+		t.Errorf("replayed %q", got)
+	}
+	// A changed system prompt does not match strictly
+	changed := []Message{{Role: RoleSystem, Content: "Turn 9/50"}, {Role: RoleUser, Content: "hello"}}
+	if _, err := strict.Chat(context.Background(), changed, []ToolDef{{Name: "bash"}}); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("expected ErrNoRecording, got %v", err)
+	}
+
+	lenient, err := LoadCassette(path, MatchLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lenient.Chat(context.Background(), changed, nil); err != nil {
+		t.Fatalf("lenient replay should ignore system messages and tools: %v", err)
+	}
+	// Unknown request falls back to the next unused recording
+	if _, err := lenient.Chat(context.Background(), []Message{{Role: RoleUser, Content: "?"}}, nil); err != nil {
+		t.Fatalf("lenient replay should serve in order: %v", err)
+	}
+	if _, err := lenient.Chat(context.Background(), []Message{{Role: RoleUser, Content: "?"}}, nil); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("cassette should be exhausted, got %v", err)
+	}
+}
+
+func TestCassette_RecordsChatErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	w, err := CreateCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecorder(&stubProvider{name: "vllm", err: errors.New("provider vllm: model not found")}, w)
+	msgs := []Message{{Role: RoleUser, Content: "hi"}}
+	if _, err := rec.Chat(context.Background(), msgs, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	w.Close()
+
+	r, err := LoadCassette(path, MatchStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Chat(context.Background(), msgs, nil); err == nil || err.Error() != "provider vllm: model not found" {
+		t.Errorf("replayed error = %v", err)
+	}
+}
+
+func TestCassette_RecordsCancelledStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cancel.jsonl")
+	w, err := CreateCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &stubbornProvider{stubProvider: stubProvider{name: "ollama"}, finished: make(chan struct{})}
+	rec := NewRecorder(backend, w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs := []Message{{Role: RoleUser, Content: "hi"}}
+	ch, err := rec.Chat(ctx, msgs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel() // and never read again
+
+	select {
+	case <-backend.finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the backend's stream was left blocked after the caller cancelled")
+	}
+	for range ch {
+	} // closed once the interaction is saved
+	w.Close()
+
+	r, err := LoadCassette(path, MatchStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := r.Chat(context.Background(), msgs, nil)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	var last StreamChunk
+	for chunk := range replay {
+		last = chunk
+	}
+	if last.Error == nil || last.Error.Error() != context.Canceled.Error() {
+		t.Errorf("replay ended with %+v, want the cancellation", last)
+	}
+}
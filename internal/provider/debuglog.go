@@ -0,0 +1,344 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A debug log is a JSON Lines file with one DebugEntry per Chat call: the
+// request as the provider received it, every streamed chunk with its offset,
+// latency and usage. Secrets are redacted before anything reaches disk. The
+// file is rotated by size, keeping a few older files alongside it.
+
+// DebugEntry is one logged Chat call.
+type DebugEntry struct {
+	ID        string       `json:"id"` // <session>-<n>; n counts the session's calls
+	Time      time.Time    `json:"time"`
+	Source    string       `json:"source,omitempty"` // CallSource of the request
+	Provider  string       `json:"provider"`
+	Model     string       `json:"model"`
+	Messages  []Message    `json:"messages"`
+	Tools     []ToolDef    `json:"tools,omitempty"`
+	Options   ChatOptions  `json:"options"`
+	Chunks    []DebugChunk `json:"chunks,omitempty"`
+	FirstByte int64        `json:"first_chunk_ms,omitempty"` // Time to the first chunk
+	Latency   int64        `json:"latency_ms"`               // Time until the stream closed
+	Usage     *Usage       `json:"usage,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// DebugChunk is a streamed chunk and when it arrived, in milliseconds after
+// the request was sent.
+type DebugChunk struct {
+	At int64 `json:"at_ms"`
+	RecordedChunk
+	Route   *RouteInfo `json:"route,omitempty"`
+	Restart string     `json:"restart,omitempty"` // Reason for a mid-stream retry
+}
+
+// DebugLogOptions configures a DebugLog. Zero values take the defaults.
+type DebugLogOptions struct {
+	Dir        string   // Default DefaultDebugLogDir()
+	MaxSize    int64    // Bytes before rotating; default 10 MiB
+	MaxBackups int      // Rotated files kept; default 5
+	Secrets    []string // Literal values to redact, e.g. API keys
+	Patterns   []*regexp.Regexp
+}
+
+const debugLogName = "provider.jsonl"
+
+// defaultSecretPatterns catch well-known credential formats that might
+// appear in prompts or tool output even when they aren't configured keys.
+var defaultSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{20,}`),     // OpenAI, Anthropic (sk-ant-...)
+	regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`),     // Google
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}`), // GitHub
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),           // AWS access key
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/\-]{16,}=*`),
+}
+
+const redacted = "[REDACTED]"
+
+// DebugLog writes provider traffic to disk while enabled. It is safe for
+// concurrent use and opens its file only on the first write, so a session
+// that never enables it leaves nothing behind.
+type DebugLog struct {
+	opts    DebugLogOptions
+	enabled atomic.Bool
+	session string
+	seq     atomic.Int64
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// DefaultDebugLogDir is ~/.config/aseity/logs.
+func DefaultDebugLogDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "aseity", "logs")
+}
+
+func NewDebugLog(opts DebugLogOptions) *DebugLog {
+	if opts.Dir == "" {
+		opts.Dir = DefaultDebugLogDir()
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 10 << 20
+	}
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = 5
+	}
+	// Short values would redact ordinary words; longest first so a key
+	// containing another is replaced whole.
+	var secrets []string
+	for _, s := range opts.Secrets {
+		if len(s) >= 8 {
+			secrets = append(secrets, s)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	opts.Secrets = secrets
+	opts.Patterns = append(append([]*regexp.Regexp(nil), defaultSecretPatterns...), opts.Patterns...)
+	// The random suffix keeps IDs unique when sessions start in the same second.
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	session := time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+	return &DebugLog{opts: opts, session: session}
+}
+
+func (l *DebugLog) SetEnabled(on bool) { l.enabled.Store(on) }
+
+func (l *DebugLog) Enabled() bool { return l.enabled.Load() }
+
+// Path is the file currently being written.
+func (l *DebugLog) Path() string { return filepath.Join(l.opts.Dir, debugLogName) }
+
+// Redact replaces configured secrets and known credential formats in s.
+func (l *DebugLog) Redact(s string) string {
+	for _, secret := range l.opts.Secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	for _, re := range l.opts.Patterns {
+		s = re.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+func (l *DebugLog) nextID() string {
+	return fmt.Sprintf("%s-%d", l.session, l.seq.Add(1))
+}
+
+func (l *DebugLog) write(e DebugEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line := append([]byte(l.Redact(string(data))), '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil && l.size+int64(len(line)) > l.opts.MaxSize {
+		l.rotate()
+	}
+	if l.f == nil {
+		if err := os.MkdirAll(l.opts.Dir, 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(l.Path(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		info, _ := f.Stat()
+		l.f, l.size = f, 0
+		if info != nil {
+			l.size = info.Size()
+		}
+	}
+	n, err := l.f.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// rotate moves the current file aside and drops the oldest backups. Called
+// with mu held.
+func (l *DebugLog) rotate() {
+	l.f.Close()
+	l.f = nil
+	backup := filepath.Join(l.opts.Dir, "provider-"+time.Now().Format("20060102-150405.000000000")+".jsonl")
+	os.Rename(l.Path(), backup)
+
+	backups := debugLogBackups(l.opts.Dir)
+	for len(backups) > l.opts.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+// Close closes the log file. Later writes reopen it.
+func (l *DebugLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// debugLogBackups lists rotated files in dir, oldest first.
+func debugLogBackups(dir string) []string {
+	backups, _ := filepath.Glob(filepath.Join(dir, "provider-*.jsonl"))
+	sort.Strings(backups)
+	return backups
+}
+
+// ReadDebugLog returns the entries logged in dir, oldest first, including
+// those in rotated files. Lines that don't parse are skipped.
+func ReadDebugLog(dir string) ([]DebugEntry, error) {
+	files := append(debugLogBackups(dir), filepath.Join(dir, debugLogName))
+	var entries []DebugEntry
+	found := false
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		found = true
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 1<<20), 64<<20)
+		for scanner.Scan() {
+			var e DebugEntry
+			if json.Unmarshal(scanner.Bytes(), &e) == nil {
+				entries = append(entries, e)
+			}
+		}
+		f.Close()
+	}
+	if !found {
+		return nil, fmt.Errorf("no provider debug log in %s (run with --debug-provider or use /debug)", dir)
+	}
+	return entries, nil
+}
+
+// DebugLogProvider logs every Chat call to a DebugLog while it is enabled.
+// Place it outside any RouterProvider and retry wrapper so the log shows
+// which backend served the call and any mid-stream restarts.
+type DebugLogProvider struct {
+	inner Provider
+	log   *DebugLog
+}
+
+// WithDebugLog wraps p so that its calls are written to log.
+func WithDebugLog(p Provider, log *DebugLog) *DebugLogProvider {
+	return &DebugLogProvider{inner: p, log: log}
+}
+
+// DebugLogOf returns the DebugLog behind p, unwrapping middleware.
+func DebugLogOf(p Provider) (*DebugLog, bool) {
+	for inner := p; inner != nil; inner = unwrap(inner) {
+		if d, ok := inner.(*DebugLogProvider); ok {
+			return d.log, true
+		}
+	}
+	return nil, false
+}
+
+func (d *DebugLogProvider) Name() string { return d.inner.Name() }
+
+func (d *DebugLogProvider) ModelName() string { return d.inner.ModelName() }
+
+func (d *DebugLogProvider) Unwrap() Provider { return d.inner }
+
+func (d *DebugLogProvider) Models(ctx context.Context) ([]string, error) {
+	return d.inner.Models(ctx)
+}
+
+func (d *DebugLogProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	if !d.log.Enabled() {
+		return d.inner.Chat(ctx, msgs, tools, opts...)
+	}
+
+	start := time.Now()
+	entry := DebugEntry{
+		ID:       d.log.nextID(),
+		Time:     start,
+		Source:   CallSource(ctx),
+		Provider: d.inner.Name(),
+		Model:    d.inner.ModelName(),
+		Messages: msgs,
+		Tools:    tools,
+		Options:  ResolveChatOptions(ctx, opts),
+	}
+	ch, err := d.inner.Chat(ctx, msgs, tools, opts...)
+	if err != nil {
+		entry.Latency = time.Since(start).Milliseconds()
+		entry.Error = err.Error()
+		d.log.write(entry)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 64)
+	go func() {
+		defer close(out)
+	recv:
+		for chunk := range ch {
+			at := time.Since(start).Milliseconds()
+			if entry.Chunks == nil {
+				entry.FirstByte = at
+			}
+			dc := DebugChunk{
+				At: at,
+				RecordedChunk: RecordedChunk{
+					Delta: chunk.Delta, Thinking: chunk.Thinking, ToolCalls: chunk.ToolCalls,
+					Done: chunk.Done, Usage: chunk.Usage, ThinkingBlocks: chunk.ThinkingBlocks,
+					ToolCallDelta: chunk.ToolCallDelta,
+				},
+				Route: chunk.Route,
+			}
+			if chunk.Error != nil {
+				dc.Error = chunk.Error.Error()
+				entry.Error = dc.Error
+			}
+			if chunk.Restart != nil {
+				dc.Restart = fmt.Sprintf("attempt %d: %v", chunk.Restart.Attempt, chunk.Restart.Reason)
+				entry.Error = ""
+			}
+			if chunk.Route != nil {
+				entry.Provider, entry.Model = chunk.Route.Provider, chunk.Route.Model
+			}
+			if chunk.Usage != nil {
+				entry.Usage = chunk.Usage
+			}
+			entry.Chunks = append(entry.Chunks, dc)
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				go func() {
+					for range ch {
+					}
+				}()
+				break recv
+			}
+		}
+		entry.Latency = time.Since(start).Milliseconds()
+		if entry.Error == "" && ctx.Err() != nil {
+			entry.Error = ctx.Err().Error()
+		}
+		d.log.write(entry)
+	}()
+	return out, nil
+}
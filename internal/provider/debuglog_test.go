@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugLog_RecordsAndRedacts(t *testing.T) {
+	dir := t.TempDir()
+	log := NewDebugLog(DebugLogOptions{
+		Dir:      dir,
+		Secrets:  []string{"super-secret-key-123", "short"},
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`corp-[0-9]{6}`)},
+	})
+	p := WithDebugLog(WithRetry(&stubProvider{name: "stub"}, 1), log)
+
+	// Disabled: nothing is written, not even the file.
+	drainStub(t, p, "first")
+	if _, err := os.Stat(log.Path()); !os.IsNotExist(err) {
+		t.Fatalf("log file created while disabled: %v", err)
+	}
+
+	log.SetEnabled(true)
+	ctx := WithCallSource(context.Background(), "validator")
+	ch, err := p.Chat(ctx, []Message{{Role: RoleUser, Content: "key super-secret-key-123, token corp-123456, sk-abcdefghijklmnopqrstuvwx, short"}}, nil, WithTemperature(0.3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+
+	raw, _ := os.ReadFile(log.Path())
+	for _, leak := range []string{"super-secret-key-123", "corp-123456", "sk-abcdefghijklmnopqrstuvwx"} {
+		if strings.Contains(string(raw), leak) {
+			t.Errorf("log contains secret %q", leak)
+		}
+	}
+	if !strings.Contains(string(raw), "short") {
+		t.Error("values shorter than 8 bytes should not be treated as secrets")
+	}
+
+	entries, err := ReadDebugLog(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries = %d, err = %v", len(entries), err)
+	}
+	e := entries[0]
+	if e.Source != "validator" || e.Provider != "stub" || e.Options.Temperature == nil || *e.Options.Temperature != 0.3 {
+		t.Errorf("entry = %+v", e)
+	}
+	if len(e.Chunks) != 2 || e.Chunks[0].Delta != "hi from stub" || !e.Chunks[1].Done {
+		t.Errorf("chunks = %+v", e.Chunks)
+	}
+	if !strings.HasSuffix(e.ID, "-1") {
+		t.Errorf("ID = %q, want the session's first call", e.ID)
+	}
+}
+
+func TestDebugLog_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	log := NewDebugLog(DebugLogOptions{Dir: dir, MaxSize: 200, MaxBackups: 2})
+	log.SetEnabled(true)
+	p := WithDebugLog(&stubProvider{name: "stub"}, log)
+	for i := 0; i < 6; i++ {
+		drainStub(t, p, strings.Repeat("x", 100))
+	}
+	log.Close()
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "provider-*.jsonl"))
+	if len(backups) != 2 {
+		t.Errorf("backups = %d, want 2", len(backups))
+	}
+	entries, err := ReadDebugLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || !strings.HasSuffix(entries[2].ID, "-6") {
+		t.Errorf("kept %d entries ending with %q, want the latest 3", len(entries), entries[len(entries)-1].ID)
+	}
+}
+
+// floodProvider streams deltas until the caller cancels.
+type floodProvider struct{ stubProvider }
+
+func (f *floodProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case ch <- StreamChunk{Delta: "x"}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func TestDebugLog_CallerStopsReading(t *testing.T) {
+	dir := t.TempDir()
+	log := NewDebugLog(DebugLogOptions{Dir: dir})
+	log.SetEnabled(true)
+	p := WithDebugLog(&floodProvider{stubProvider{name: "stub"}}, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.Chat(ctx, []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel() // and never read again
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, _ := ReadDebugLog(dir)
+		if len(entries) == 1 {
+			if entries[0].Error != context.Canceled.Error() {
+				t.Errorf("entry error = %q, want the cancellation", entries[0].Error)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the entry was never written: the wrapper is stuck sending to a caller that left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDebugLogOf(t *testing.T) {
+	log := NewDebugLog(DebugLogOptions{Dir: t.TempDir()})
+	p := WithRetry(WithDebugLog(&stubProvider{name: "stub"}, log), 1)
+	if got, ok := DebugLogOf(p); !ok || got != log {
+		t.Error("DebugLogOf should find the log behind middleware")
+	}
+	if _, ok := DebugLogOf(&stubProvider{}); ok {
+		t.Error("found a debug log on a bare provider")
+	}
+}
+
+func drainStub(t *testing.T, p Provider, prompt string) {
+	t.Helper()
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: prompt}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+}
@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// Embedder is implemented by providers that can turn text into vectors for
+// retrieval. It is optional; use AsEmbedder to find one behind middleware.
+type Embedder interface {
+	// Embed returns one vector per input, in input order. Large inputs are
+	// split into batches the backend accepts.
+	Embed(ctx context.Context, inputs []string) ([][]float32, error)
+
+	// EmbeddingModel is the model used for Embed, which is usually not the
+	// chat model.
+	EmbeddingModel() string
+
+	// Dimensions is the length of the vectors Embed returns, or 0 when it
+	// isn't known until the first call.
+	Dimensions() int
+}
+
+// Default embedding models, used when a provider config has no
+// embedding_model.
+const (
+	DefaultOpenAIEmbeddingModel = "text-embedding-3-small"
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+	DefaultGoogleEmbeddingModel = "gemini-embedding-001"
+)
+
+// AsEmbedder returns the Embedder behind p, unwrapping middleware.
+func AsEmbedder(p Provider) (Embedder, bool) {
+	for inner := p; inner != nil; inner = unwrap(inner) {
+		if e, ok := inner.(Embedder); ok {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// embedBatches calls embed on consecutive batches of at most size inputs
+// and joins the results, recording the vector length in dims.
+func embedBatches(ctx context.Context, inputs []string, size int, dims *atomic.Int64, embed func(context.Context, []string) ([][]float32, error)) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += size {
+		batch := inputs[start:min(start+size, len(inputs))]
+		out, err := embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != len(batch) {
+			return nil, fmt.Errorf("embedding: got %d vectors for %d inputs", len(out), len(batch))
+		}
+		vectors = append(vectors, out...)
+	}
+	if len(vectors) > 0 {
+		dims.Store(int64(len(vectors[0])))
+	}
+	return vectors, nil
+}
+
+// HashEmbedder is a deterministic local embedder for tests and offline use.
+// Words and character trigrams are hashed into a fixed number of buckets
+// (the "hashing trick"), so texts sharing vocabulary score as similar
+// without any model. It captures no meaning beyond shared terms.
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder returns a hashing embedder producing vectors of dims
+// entries (256 if dims <= 0).
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = 256
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+func (h *HashEmbedder) EmbeddingModel() string { return "hash" }
+
+func (h *HashEmbedder) Dimensions() int { return h.dims }
+
+func (h *HashEmbedder) Embed(_ context.Context, inputs []string) ([][]float32, error) {
+	vectors := make([][]float32, len(inputs))
+	for i, text := range inputs {
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+func (h *HashEmbedder) embed(text string) []float32 {
+	v := make([]float32, h.dims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, w := range words {
+		h.add(v, "w:"+w, 1)
+		rs := []rune(" " + w + " ")
+		for j := 0; j+3 <= len(rs); j++ {
+			h.add(v, "t:"+string(rs[j:j+3]), 0.5)
+		}
+	}
+
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range v {
+			v[i] *= scale
+		}
+	}
+	return v
+}
+
+// add hashes feature into a bucket; a second hash bit picks the sign so
+// collisions tend to cancel rather than accumulate.
+func (h *HashEmbedder) add(v []float32, feature string, weight float32) {
+	f := fnv.New64a()
+	f.Write([]byte(feature))
+	sum := f.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%uint64(h.dims)] += weight
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, or 0 if
+// either is zero or their lengths differ.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAI_EmbedBatchesInOrder(t *testing.T) {
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != DefaultOpenAIEmbeddingModel {
+			t.Errorf("model = %s", req.Model)
+		}
+		batches = append(batches, len(req.Input))
+		// Reply out of order; index decides placement.
+		var data []map[string]any
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			var n float32
+			fmt.Sscanf(req.Input[i], "doc %f", &n)
+			data = append(data, map[string]any{"index": i, "embedding": []float32{n, 0, 0}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	inputs := make([]string, openAIEmbedBatchSize+10)
+	for i := range inputs {
+		inputs[i] = fmt.Sprintf("doc %d", i)
+	}
+	p := NewOpenAI("openai", server.URL, "key", "gpt-4o")
+	if p.Dimensions() != 0 {
+		t.Error("dimensions should be unknown before the first call")
+	}
+	vectors, err := p.Embed(context.Background(), inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || batches[0] != openAIEmbedBatchSize || batches[1] != 10 {
+		t.Errorf("batches = %v", batches)
+	}
+	for i, v := range vectors {
+		if v[0] != float32(i) {
+			t.Fatalf("vectors[%d] = %v, out of order", i, v)
+		}
+	}
+	if p.Dimensions() != 3 {
+		t.Errorf("Dimensions = %d, want 3", p.Dimensions())
+	}
+}
+
+func TestOllama_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]]}`))
+	}))
+	defer server.Close()
+
+	p := NewOllama("ollama", server.URL, "qwen2.5-coder", OllamaOptions{})
+	vectors, err := p.Embed(context.Background(), []string{"a", "b"})
+	if err != nil || len(vectors) != 2 || vectors[1][1] != 0.4 {
+		t.Errorf("Embed = %v, %v", vectors, err)
+	}
+	if p.EmbeddingModel() != DefaultOllamaEmbeddingModel {
+		t.Errorf("EmbeddingModel = %s", p.EmbeddingModel())
+	}
+}
+
+func TestGoogle_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-embedding-001:batchEmbedContents" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req struct {
+			Requests []struct {
+				Model string `json:"model"`
+			} `json:"requests"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Requests) != 2 || req.Requests[0].Model != "models/gemini-embedding-001" {
+			t.Errorf("requests = %+v", req.Requests)
+		}
+		w.Write([]byte(`{"embeddings":[{"values":[1,0]},{"values":[0,1]}]}`))
+	}))
+	defer server.Close()
+
+	p := NewGoogle("key", "", GoogleOptions{BaseURL: server.URL})
+	vectors, err := p.Embed(context.Background(), []string{"a", "b"})
+	if err != nil || len(vectors) != 2 || vectors[1][1] != 1 {
+		t.Errorf("Embed = %v, %v", vectors, err)
+	}
+}
+
+func TestHashEmbedder(t *testing.T) {
+	h := NewHashEmbedder(128)
+	vectors, _ := h.Embed(context.Background(), []string{
+		"parse the config file",
+		"Parse the CONFIG file!",
+		"reading configuration files",
+		"kubernetes pod scheduling",
+	})
+	if CosineSimilarity(vectors[0], vectors[1]) < 0.999 {
+		t.Error("case and punctuation should not change the vector")
+	}
+	related, unrelated := CosineSimilarity(vectors[0], vectors[2]), CosineSimilarity(vectors[0], vectors[3])
+	if related <= unrelated {
+		t.Errorf("similarity: related %.3f <= unrelated %.3f", related, unrelated)
+	}
+	again, _ := NewHashEmbedder(128).Embed(context.Background(), []string{"parse the config file"})
+	if CosineSimilarity(vectors[0], again[0]) < 0.999 {
+		t.Error("embedding is not deterministic")
+	}
+}
+
+func TestAsEmbedder_Unwraps(t *testing.T) {
+	if _, ok := AsEmbedder(WithRetry(NewOllama("ollama", "", "m", OllamaOptions{}), 1)); !ok {
+		t.Error("embedder behind RetryProvider not found")
+	}
+	if _, ok := AsEmbedder(&stubProvider{name: "stub"}); ok {
+		t.Error("mock provider has no embedder")
+	}
+}
@@ -1,11 +1,169 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Error is returned by providers when a request fails. It keeps the HTTP
+// status and any server-requested delay so callers can decide what to do
+// without matching on message text.
+type Error struct {
+	Provider   string
+	StatusCode int           // HTTP status; 0 for transport failures
+	Code       string        // Provider error code or type, e.g. "rate_limit_error"
+	Message    string        // Human-readable message
+	Retryable  bool          // Worth retrying the same request later
+	RetryAfter time.Duration // Server-requested wait before retrying; 0 if none given
+	Err        error         // Underlying transport error, if any
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("provider %s: %s", e.Provider, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// AsError returns err as a *Error if it wraps one.
+func AsError(err error) (*Error, bool) {
+	var pe *Error
+	ok := errors.As(err, &pe)
+	return pe, ok
+}
+
+// newHTTPError builds an Error from a non-200 response. body must already be read.
+func newHTTPError(providerName string, resp *http.Response, body []byte) *Error {
+	return &Error{
+		Provider:   providerName,
+		StatusCode: resp.StatusCode,
+		Code:       parseErrorCode(body),
+		Message:    parseProviderError(providerName, resp.StatusCode, body),
+		Retryable:  isRetryableStatus(resp.StatusCode),
+		RetryAfter: parseRetryAfter(resp.StatusCode, resp.Header, body, time.Now()),
+	}
+}
+
+// newTransportError wraps a failure to reach the provider at all.
+func newTransportError(providerName string, err error) *Error {
+	return &Error{
+		Provider:  providerName,
+		Message:   friendlyProviderError(err),
+		Retryable: !errors.Is(err, context.Canceled),
+		Err:       err,
+	}
+}
+
+// streamEndError reports a stream that ended before the provider's terminal
+// event. A dropped connection mid-response is worth retrying.
+func streamEndError(providerName string, err error) *Error {
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return newTransportError(providerName, err)
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests ||
+		(status >= 500 && status != http.StatusNotImplemented)
+}
+
+// parseErrorCode pulls the machine-readable error code out of the body. OpenAI
+// uses error.code, Anthropic error.type, and Google error.status (its
+// error.code is the numeric HTTP status).
+func parseErrorCode(body []byte) string {
+	var errResp struct {
+		Error struct {
+			Code   json.RawMessage `json:"code"`
+			Type   string          `json:"type"`
+			Status string          `json:"status"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return ""
+	}
+	var code string
+	if json.Unmarshal(errResp.Error.Code, &code) == nil && code != "" {
+		return code
+	}
+	if errResp.Error.Status != "" {
+		return errResp.Error.Status
+	}
+	return errResp.Error.Type
+}
+
+// parseRetryAfter reads the server-requested delay from, in order of
+// preference: retry-after-ms, Retry-After (seconds or HTTP date), Google's
+// RetryInfo body detail, and on 429s the Anthropic/OpenAI rate-limit reset
+// headers. It returns 0 when the server gave no hint.
+func parseRetryAfter(status int, h http.Header, body []byte, now time.Time) time.Duration {
+	if v := h.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if t, err := http.ParseTime(v); err == nil && t.After(now) {
+			return t.Sub(now)
+		}
+	}
+	if d := parseGoogleRetryDelay(body); d > 0 {
+		return d
+	}
+	if status != http.StatusTooManyRequests {
+		return 0
+	}
+
+	// Rate-limit reset headers: wait for the latest exhausted window to reset.
+	var wait time.Duration
+	for _, name := range []string{
+		"anthropic-ratelimit-requests-reset", "anthropic-ratelimit-tokens-reset",
+		"anthropic-ratelimit-input-tokens-reset", "anthropic-ratelimit-output-tokens-reset",
+	} {
+		if t, err := time.Parse(time.RFC3339, h.Get(name)); err == nil && t.Sub(now) > wait {
+			wait = t.Sub(now)
+		}
+	}
+	for _, name := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens"} {
+		if d, err := time.ParseDuration(h.Get(name)); err == nil && d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// parseGoogleRetryDelay reads the google.rpc.RetryInfo detail, e.g. "retryDelay": "30s".
+func parseGoogleRetryDelay(body []byte) time.Duration {
+	var errResp struct {
+		Error struct {
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) != nil {
+		return 0
+	}
+	for _, d := range errResp.Error.Details {
+		if strings.HasSuffix(d.Type, "RetryInfo") {
+			if delay, err := time.ParseDuration(d.RetryDelay); err == nil {
+				return delay
+			}
+		}
+	}
+	return 0
+}
+
 // parseProviderError extracts a human-readable error from provider API responses.
 func parseProviderError(providerName string, statusCode int, body []byte) string {
 	// Try to parse JSON error
@@ -13,7 +171,6 @@ func parseProviderError(providerName string, statusCode int, body []byte) string
 		Error struct {
 			Message string `json:"message"`
 			Type    string `json:"type"`
-			Code    string `json:"code"`
 		} `json:"error"`
 		Message string `json:"message"`
 	}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   time.Duration
+	}{
+		{"seconds", 429, http.Header{"Retry-After": {"7"}}, "", 7 * time.Second},
+		{"http date", 503, http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, "", 90 * time.Second},
+		{"milliseconds win", 429, http.Header{"Retry-After": {"7"}, "Retry-After-Ms": {"250"}}, "", 250 * time.Millisecond},
+		{"anthropic reset", 429, http.Header{
+			"Anthropic-Ratelimit-Requests-Reset": {now.Add(2 * time.Second).Format(time.RFC3339)},
+			"Anthropic-Ratelimit-Tokens-Reset":   {now.Add(20 * time.Second).Format(time.RFC3339)},
+		}, "", 20 * time.Second},
+		{"openai reset", 429, http.Header{"X-Ratelimit-Reset-Tokens": {"6m0s"}}, "", 6 * time.Minute},
+		{"reset ignored unless 429", 500, http.Header{"X-Ratelimit-Reset-Tokens": {"6m0s"}}, "", 0},
+		{"google retry info", 429, http.Header{},
+			`{"error":{"code":429,"status":"RESOURCE_EXHAUSTED","details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"30s"}]}}`,
+			30 * time.Second},
+		{"none", 503, http.Header{}, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.status, tt.header, []byte(tt.body), now); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHTTPError(t *testing.T) {
+	resp := &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": {"3"}}}
+	body := []byte(`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	err := error(newHTTPError("anthropic", resp, body))
+
+	pe, ok := AsError(err)
+	if !ok {
+		t.Fatal("expected *Error")
+	}
+	if pe.StatusCode != 429 || pe.Code != "rate_limit_error" || !pe.Retryable || pe.RetryAfter != 3*time.Second {
+		t.Errorf("unexpected error: %+v", pe)
+	}
+	if err.Error() != "provider anthropic: slow down" {
+		t.Errorf("message = %q", err.Error())
+	}
+
+	// Google sends a numeric code; the status string is used instead
+	googleBody := []byte(`{"error":{"code":400,"message":"bad","status":"INVALID_ARGUMENT"}}`)
+	if e := newHTTPError("google", &http.Response{StatusCode: 400}, googleBody); e.Code != "INVALID_ARGUMENT" || e.Retryable || e.Message != "bad" {
+		t.Errorf("unexpected google error: %+v", e)
+	}
+}
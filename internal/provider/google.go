@@ -4,42 +4,156 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
 )
 
 type GoogleProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey  string
+	model   string
+	baseURL string
+	opts    GoogleOptions
+	client  *http.Client
+	dims    atomic.Int64 // Embedding vector length, once seen
 }
 
-func NewGoogle(apiKey, model string) *GoogleProvider {
+// GoogleOptions are the Gemini-specific settings from a provider's config
+// entry.
+type GoogleOptions struct {
+	// BaseURL replaces https://generativelanguage.googleapis.com, e.g. for a
+	// proxy or a local stub.
+	BaseURL string
+
+	EmbeddingModel string // Model for Embed
+
+	// ThinkingBudget caps the reasoning tokens of thinking models (Gemini
+	// 2.5 and later). 0 leaves the model's default.
+	ThinkingBudget int
+}
+
+func NewGoogle(apiKey, model string, opts GoogleOptions) *GoogleProvider {
 	if model == "" {
 		model = "gemini-1.5-flash"
 	}
-	return &GoogleProvider{apiKey: apiKey, model: model, client: &http.Client{}}
+	baseURL := strings.TrimRight(opts.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://generativelanguage.googleapis.com"
+	}
+	if opts.EmbeddingModel == "" {
+		opts.EmbeddingModel = DefaultGoogleEmbeddingModel
+	}
+	return &GoogleProvider{apiKey: apiKey, model: model, baseURL: baseURL, opts: opts, client: &http.Client{}}
 }
 
 func (g *GoogleProvider) Name() string { return "google" }
 
 func (g *GoogleProvider) ModelName() string { return g.model }
 
-func (g *GoogleProvider) Models(_ context.Context) ([]string, error) {
-	return []string{
-		"gemini-1.5-flash",
-		"gemini-1.5-pro",
-		"gemini-2.0-flash-exp",
-	}, nil
+func (g *GoogleProvider) Models(ctx context.Context) ([]string, error) {
+	infos, err := g.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return modelIDs(infos), nil
+}
+
+// ListModels fetches the models that support generateContent, cached on disk.
+func (g *GoogleProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return cachedModels("google", g.baseURL, func() ([]ModelInfo, error) {
+		var infos []ModelInfo
+		pageToken := ""
+		for {
+			apiURL := g.baseURL + "/v1beta/models?pageSize=1000"
+			if pageToken != "" {
+				apiURL += "&pageToken=" + url.QueryEscape(pageToken)
+			}
+			req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("x-goog-api-key", g.apiKey)
+			resp, err := g.client.Do(req)
+			if err != nil {
+				return nil, newTransportError("google", err)
+			}
+			if resp.StatusCode != 200 {
+				b, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				return nil, newHTTPError("google", resp, b)
+			}
+			var page struct {
+				Models []struct {
+					Name                       string   `json:"name"`
+					DisplayName                string   `json:"displayName"`
+					InputTokenLimit            int      `json:"inputTokenLimit"`
+					OutputTokenLimit           int      `json:"outputTokenLimit"`
+					SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+					Thinking                   bool     `json:"thinking"`
+				} `json:"models"`
+				NextPageToken string `json:"nextPageToken"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&page)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range page.Models {
+				if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+					continue
+				}
+				id := strings.TrimPrefix(m.Name, "models/")
+				// Gemini models are multimodal and call functions; Gemma and
+				// other open models served through the API do neither.
+				gemini := strings.HasPrefix(id, "gemini-")
+				infos = append(infos, ModelInfo{
+					ID:              id,
+					DisplayName:     m.DisplayName,
+					ContextWindow:   m.InputTokenLimit,
+					MaxOutputTokens: m.OutputTokenLimit,
+					Vision:          gemini,
+					Tools:           gemini,
+					Thinking:        m.Thinking,
+				})
+			}
+			if page.NextPageToken == "" {
+				return infos, nil
+			}
+			pageToken = page.NextPageToken
+		}
+	})
 }
 
 type geminiRequest struct {
-	Contents          []geminiContent `json:"contents"`
-	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
-	Tools             []geminiTool    `json:"tools,omitempty"`
+	Contents          []geminiContent  `json:"contents"`
+	SystemInstruction *geminiContent   `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool     `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiGenConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+
+	ResponseMimeType   string `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema any    `json:"responseJsonSchema,omitempty"`
+
+	ThinkingConfig *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
 }
 
 type geminiContent struct {
@@ -49,16 +163,33 @@ type geminiContent struct {
 
 type geminiPart struct {
 	Text             string        `json:"text,omitempty"`
+	InlineData       *geminiBlob   `json:"inlineData,omitempty"`
+	FileData         *geminiFile   `json:"fileData,omitempty"`
 	FunctionCall     *geminiFnCall `json:"functionCall,omitempty"`
 	FunctionResponse *geminiFnResp `json:"functionResponse,omitempty"`
+
+	Thought          bool   `json:"thought,omitempty"`          // Text is a thought summary
+	ThoughtSignature string `json:"thoughtSignature,omitempty"` // Must be returned with function calls
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type geminiFile struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
 }
 
 type geminiFnCall struct {
+	ID   string         `json:"id,omitempty"`
 	Name string         `json:"name"`
 	Args map[string]any `json:"args"`
 }
 
 type geminiFnResp struct {
+	ID       string         `json:"id,omitempty"`
 	Name     string         `json:"name"`
 	Response map[string]any `json:"response"`
 }
@@ -73,16 +204,23 @@ type geminiFnDecl struct {
 	Parameters  any    `json:"parameters"`
 }
 
-func (g *GoogleProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef) (<-chan StreamChunk, error) {
+func (g *GoogleProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	genOpts := ResolveChatOptions(ctx, opts)
+
 	var contents []geminiContent
 	var sysInstruction *geminiContent
+	// Function responses are matched to calls by ID, but Gemini also wants
+	// the function's name, which tool messages don't carry.
+	callNames := map[string]string{}
+	lastWasTool := false
 
 	for _, m := range msgs {
 		switch m.Role {
 		case RoleSystem:
 			sysInstruction = &geminiContent{Parts: []geminiPart{{Text: m.Content}}}
 		case RoleUser:
-			contents = append(contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: m.Content}}})
+			parts := []geminiPart{{Text: m.Content}}
+			contents = append(contents, geminiContent{Role: "user", Parts: append(parts, geminiParts(m.Parts)...)})
 		case RoleAssistant:
 			parts := []geminiPart{}
 			if m.Content != "" {
@@ -91,18 +229,32 @@ func (g *GoogleProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 			for _, tc := range m.ToolCalls {
 				var args map[string]any
 				json.Unmarshal([]byte(tc.Args), &args)
-				parts = append(parts, geminiPart{FunctionCall: &geminiFnCall{Name: tc.Name, Args: args}})
+				callNames[tc.ID] = tc.Name
+				parts = append(parts, geminiPart{
+					FunctionCall:     &geminiFnCall{ID: tc.ID, Name: tc.Name, Args: args},
+					ThoughtSignature: tc.Signature,
+				})
 			}
 			contents = append(contents, geminiContent{Role: "model", Parts: parts})
 		case RoleTool:
-			contents = append(contents, geminiContent{
-				Role: "user",
-				Parts: []geminiPart{{FunctionResponse: &geminiFnResp{
-					Name:     "tool",
-					Response: map[string]any{"result": m.Content},
-				}}},
-			})
+			name := callNames[m.ToolCallID]
+			if name == "" {
+				name = "tool"
+			}
+			parts := append([]geminiPart{{FunctionResponse: &geminiFnResp{
+				ID:       m.ToolCallID,
+				Name:     name,
+				Response: map[string]any{"result": m.Content},
+			}}}, geminiParts(m.Parts)...)
+			// The responses to parallel calls go back together in one turn.
+			if lastWasTool {
+				last := &contents[len(contents)-1]
+				last.Parts = append(last.Parts, parts...)
+			} else {
+				contents = append(contents, geminiContent{Role: "user", Parts: parts})
+			}
 		}
+		lastWasTool = m.Role == RoleTool
 	}
 
 	var gemTools []geminiTool
@@ -115,10 +267,35 @@ func (g *GoogleProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 	}
 
 	body := geminiRequest{Contents: contents, SystemInstruction: sysInstruction, Tools: gemTools}
+	thinking := geminiThinks(g.model)
+	if genOpts.Temperature != nil || genOpts.TopP != nil || genOpts.MaxTokens > 0 || len(genOpts.Stop) > 0 || genOpts.Seed != nil || genOpts.ResponseFormat != nil || thinking {
+		body.GenerationConfig = &geminiGenConfig{
+			Temperature:     genOpts.Temperature,
+			TopP:            genOpts.TopP,
+			MaxOutputTokens: genOpts.MaxTokens,
+			StopSequences:   genOpts.Stop,
+			Seed:            genOpts.Seed,
+		}
+		if rf := genOpts.ResponseFormat; rf != nil {
+			// responseJsonSchema accepts standard JSON Schema, unlike the
+			// OpenAPI-subset responseSchema field.
+			body.GenerationConfig.ResponseMimeType = "application/json"
+			body.GenerationConfig.ResponseJSONSchema = rf.Schema
+		}
+		if thinking {
+			// Thinking models reason either way; this asks for summaries
+			// of it. Older models reject thinkingConfig outright.
+			tc := &geminiThinkingConfig{IncludeThoughts: true}
+			if g.opts.ThinkingBudget > 0 {
+				tc.ThinkingBudget = &g.opts.ThinkingBudget
+			}
+			body.GenerationConfig.ThinkingConfig = tc
+		}
+	}
 	payload, _ := json.Marshal(body)
 
 	// Use header for API key instead of URL parameter
-	apiURL := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent?alt=sse", g.model)
+	apiURL := fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse", g.baseURL, g.model)
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
@@ -128,12 +305,12 @@ func (g *GoogleProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("google API error: %s", friendlyProviderError(err))
+		return nil, newTransportError("google", err)
 	}
 	if resp.StatusCode != 200 {
 		b, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("google returned %d: %s", resp.StatusCode, parseProviderError("google", resp.StatusCode, b))
+		return nil, newHTTPError("google", resp, b)
 	}
 
 	ch := make(chan StreamChunk, 64)
@@ -141,42 +318,222 @@ func (g *GoogleProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 		defer close(ch)
 		defer resp.Body.Close()
 		scanner := bufio.NewScanner(resp.Body)
+		var usage *Usage // Sent with each chunk; the last one is cumulative
+		var toolCalls []ToolCall
+		callPrefix := geminiCallPrefix()
 		for scanner.Scan() {
 			line := scanner.Text()
 			if !strings.HasPrefix(line, "data: ") {
 				continue
 			}
 			data := strings.TrimPrefix(line, "data: ")
-			var resp struct {
-				Candidates []struct {
-					Content      geminiContent `json:"content"`
-					FinishReason string        `json:"finishReason"`
-				} `json:"candidates"`
-			}
+			var resp geminiStreamChunk
 			if err := json.Unmarshal([]byte(data), &resp); err != nil {
 				continue
 			}
+			if um := resp.UsageMetadata; um != nil {
+				usage = &Usage{
+					InputTokens:       um.PromptTokenCount,
+					OutputTokens:      um.CandidatesTokenCount + um.ThoughtsTokenCount, // Thoughts are billed as output
+					TotalTokens:       um.TotalTokenCount,
+					CachedInputTokens: um.CachedContentTokenCount,
+				}
+			}
+			if pf := resp.PromptFeedback; pf != nil && pf.BlockReason != "" {
+				ch <- StreamChunk{Error: geminiBlockedError("prompt", pf.BlockReason, pf.SafetyRatings), Done: true, Usage: usage}
+				return
+			}
 			if len(resp.Candidates) == 0 {
 				continue
 			}
 			cand := resp.Candidates[0]
-			var toolCalls []ToolCall
 			for _, part := range cand.Content.Parts {
-				if part.Text != "" {
-					ch <- StreamChunk{Delta: part.Text}
-				}
-				if part.FunctionCall != nil {
-					args, _ := json.Marshal(part.FunctionCall.Args)
+				switch {
+				case part.FunctionCall != nil:
+					fc := part.FunctionCall
+					args, _ := json.Marshal(fc.Args)
+					id := fc.ID
+					if id == "" {
+						id = fmt.Sprintf("%s-%d", callPrefix, len(toolCalls))
+					}
+					ch <- StreamChunk{ToolCallDelta: &ToolCallDelta{Index: len(toolCalls), ID: id, Name: fc.Name, Args: string(args)}}
 					toolCalls = append(toolCalls, ToolCall{
-						ID: part.FunctionCall.Name, Name: part.FunctionCall.Name, Args: string(args),
+						ID: id, Name: fc.Name, Args: string(args), Signature: part.ThoughtSignature,
 					})
+				case part.Thought:
+					if part.Text != "" {
+						ch <- StreamChunk{Thinking: part.Text}
+					}
+				case part.Text != "":
+					ch <- StreamChunk{Delta: part.Text}
 				}
 			}
 			if cand.FinishReason != "" {
-				ch <- StreamChunk{Done: true, ToolCalls: toolCalls}
+				if err := geminiFinishError(cand.FinishReason, cand.FinishMessage, cand.SafetyRatings); err != nil {
+					ch <- StreamChunk{Error: err, Done: true, Usage: usage}
+					return
+				}
+				ch <- StreamChunk{Done: true, ToolCalls: toolCalls, Usage: usage}
 				return
 			}
 		}
+		ch <- StreamChunk{Error: streamEndError("google", scanner.Err()), Done: true}
 	}()
 	return ch, nil
 }
+
+type geminiStreamChunk struct {
+	Candidates []struct {
+		Content       geminiContent        `json:"content"`
+		FinishReason  string               `json:"finishReason"`
+		FinishMessage string               `json:"finishMessage"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason   string               `json:"blockReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+// geminiThinks reports whether model is a thinking model, which accepts
+// thinkingConfig.
+func geminiThinks(model string) bool {
+	return strings.HasPrefix(model, "gemini-2.5") || strings.HasPrefix(model, "gemini-3") || strings.Contains(model, "thinking")
+}
+
+// geminiCallPrefix starts the IDs given to function calls that arrive
+// without one, unique per response so that a tool called twice in a turn,
+// or in two turns, gets its own result back.
+func geminiCallPrefix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return "gemini-" + hex.EncodeToString(b)
+}
+
+// geminiFinishError converts a finish reason that means the response was
+// withheld or cut short into an error; normal reasons return nil.
+func geminiFinishError(reason, message string, ratings []geminiSafetyRating) error {
+	switch reason {
+	case "STOP", "MAX_TOKENS", "FINISH_REASON_UNSPECIFIED":
+		return nil
+	case "MALFORMED_FUNCTION_CALL", "UNEXPECTED_TOOL_CALL":
+		msg := "the model produced an invalid function call"
+		if message != "" {
+			msg += ": " + message
+		}
+		return &Error{Provider: "google", Code: strings.ToLower(reason), Message: msg}
+	}
+	return geminiBlockedError("response", reason, ratings)
+}
+
+// geminiBlockedError explains a prompt or response blocked by Gemini's
+// filters, naming the safety categories that tripped.
+func geminiBlockedError(what, reason string, ratings []geminiSafetyRating) error {
+	var msg string
+	switch reason {
+	case "SAFETY", "IMAGE_SAFETY":
+		msg = what + " blocked by Gemini safety filters"
+	case "RECITATION":
+		msg = what + " blocked because it recited copyrighted material; ask for a summary or paraphrase"
+	case "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		msg = what + " blocked for prohibited or sensitive content (" + reason + ")"
+	case "LANGUAGE":
+		msg = what + " is in a language the model doesn't support"
+	default:
+		msg = what + " stopped by Gemini (" + reason + ")"
+	}
+	var categories []string
+	for _, r := range ratings {
+		if r.Blocked || r.Probability == "HIGH" {
+			categories = append(categories, strings.TrimPrefix(r.Category, "HARM_CATEGORY_"))
+		}
+	}
+	if len(categories) > 0 {
+		msg += ": " + strings.ToLower(strings.Join(categories, ", "))
+	}
+	return &Error{Provider: "google", Code: "blocked_" + strings.ToLower(reason), Message: msg}
+}
+
+// geminiParts converts extra content parts to inline or file data parts.
+func geminiParts(parts []ContentPart) []geminiPart {
+	var out []geminiPart
+	for _, p := range parts {
+		switch p.Type {
+		case PartText:
+			out = append(out, geminiPart{Text: p.Text})
+		case PartImage:
+			if p.URL != "" {
+				out = append(out, geminiPart{FileData: &geminiFile{MimeType: p.MIMEType, FileURI: p.URL}})
+			} else {
+				out = append(out, geminiPart{InlineData: &geminiBlob{MimeType: p.MIMEType, Data: p.Base64()}})
+			}
+		}
+	}
+	return out
+}
+
+// googleEmbedBatchSize is the most requests batchEmbedContents accepts.
+const googleEmbedBatchSize = 100
+
+func (g *GoogleProvider) EmbeddingModel() string { return g.opts.EmbeddingModel }
+
+func (g *GoogleProvider) Dimensions() int { return int(g.dims.Load()) }
+
+// Embed calls batchEmbedContents, the batched form of embedContent.
+func (g *GoogleProvider) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	return embedBatches(ctx, inputs, googleEmbedBatchSize, &g.dims, g.embed)
+}
+
+func (g *GoogleProvider) embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	model := "models/" + g.opts.EmbeddingModel
+	requests := make([]map[string]any, len(inputs))
+	for i, text := range inputs {
+		requests[i] = map[string]any{
+			"model":   model,
+			"content": geminiContent{Parts: []geminiPart{{Text: text}}},
+		}
+	}
+	payload, _ := json.Marshal(map[string]any{"requests": requests})
+	apiURL := fmt.Sprintf("%s/v1beta/%s:batchEmbedContents", g.baseURL, model)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.apiKey)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, newTransportError("google", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newHTTPError("google", resp, body)
+	}
+	var result struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(result.Embeddings))
+	for i, e := range result.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
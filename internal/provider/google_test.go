@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// geminiStub serves canned SSE events and captures the request body.
+func geminiStub(t *testing.T, events ...string) (*httptest.Server, *geminiRequest) {
+	t.Helper()
+	var got geminiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			w.Write([]byte("data: " + e + "\n\n"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &got
+}
+
+func TestGoogle_ThinkingUsageAndParallelCalls(t *testing.T) {
+	server, got := geminiStub(t,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Checking both files.","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"file_read","args":{"path":"a.go"}},"thoughtSignature":"sig-1"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"file_read","args":{"path":"b.go"}}}]},"finishReason":"STOP"}],`+
+			`"usageMetadata":{"promptTokenCount":100,"candidatesTokenCount":20,"thoughtsTokenCount":30,"totalTokenCount":150,"cachedContentTokenCount":40}}`,
+	)
+
+	p := NewGoogle("key", "gemini-2.5-flash", GoogleOptions{BaseURL: server.URL, ThinkingBudget: 2048})
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "compare a.go and b.go"}}, []ToolDef{{Name: "file_read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var thinking string
+	var deltas []ToolCallDelta
+	var final StreamChunk
+	for chunk := range ch {
+		thinking += chunk.Thinking
+		if chunk.ToolCallDelta != nil {
+			deltas = append(deltas, *chunk.ToolCallDelta)
+		}
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	if tc := got.GenerationConfig.ThinkingConfig; tc == nil || !tc.IncludeThoughts || tc.ThinkingBudget == nil || *tc.ThinkingBudget != 2048 {
+		t.Errorf("thinkingConfig = %+v", tc)
+	}
+	if thinking != "Checking both files." {
+		t.Errorf("thinking = %q", thinking)
+	}
+	if final.Error != nil {
+		t.Fatalf("unexpected error: %v", final.Error)
+	}
+	if len(final.ToolCalls) != 2 || len(deltas) != 2 {
+		t.Fatalf("tool calls = %+v, deltas = %+v", final.ToolCalls, deltas)
+	}
+	a, b := final.ToolCalls[0], final.ToolCalls[1]
+	if a.ID == "" || a.ID == b.ID || a.Name != "file_read" || b.Name != "file_read" {
+		t.Errorf("calls to the same tool need distinct IDs: %+v", final.ToolCalls)
+	}
+	if a.Args != `{"path":"a.go"}` || a.Signature != "sig-1" || b.Signature != "" {
+		t.Errorf("calls = %+v", final.ToolCalls)
+	}
+	if deltas[1].Index != 1 || deltas[1].ID != b.ID {
+		t.Errorf("deltas = %+v", deltas)
+	}
+	u := final.Usage
+	if u == nil || u.InputTokens != 100 || u.OutputTokens != 50 || u.TotalTokens != 150 || u.CachedInputTokens != 40 {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestGoogle_FunctionResponsesMatchedByID(t *testing.T) {
+	server, got := geminiStub(t, `{"candidates":[{"content":{"parts":[{"text":"a.go is longer"}]},"finishReason":"STOP"}]}`)
+
+	p := NewGoogle("key", "gemini-1.5-flash", GoogleOptions{BaseURL: server.URL})
+	msgs := []Message{
+		{Role: RoleUser, Content: "compare"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "c1", Name: "file_read", Args: `{"path":"a.go"}`, Signature: "sig-1"},
+			{ID: "c2", Name: "wc", Args: `{"path":"b.go"}`},
+		}},
+		{Role: RoleTool, ToolCallID: "c2", Content: "12 lines"},
+		{Role: RoleTool, ToolCallID: "c1", Content: "package a"},
+	}
+	ch, err := p.Chat(context.Background(), msgs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+
+	if got.GenerationConfig != nil {
+		t.Errorf("older models must not get a thinkingConfig: %+v", got.GenerationConfig)
+	}
+	if len(got.Contents) != 3 {
+		t.Fatalf("contents = %+v, want the tool results in one turn", got.Contents)
+	}
+	calls := got.Contents[1].Parts
+	if calls[0].FunctionCall.ID != "c1" || calls[0].ThoughtSignature != "sig-1" {
+		t.Errorf("call part = %+v", calls[0])
+	}
+	results := got.Contents[2].Parts
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	if r := results[0].FunctionResponse; r.ID != "c2" || r.Name != "wc" || r.Response["result"] != "12 lines" {
+		t.Errorf("first response = %+v", r)
+	}
+	if r := results[1].FunctionResponse; r.ID != "c1" || r.Name != "file_read" {
+		t.Errorf("second response = %+v", r)
+	}
+}
+
+func TestGoogle_SafetyBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		code  string
+		want  string
+	}{
+		{
+			"response",
+			`{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"HIGH","blocked":true},{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"}]}]}`,
+			"blocked_safety", "response blocked by Gemini safety filters: dangerous_content",
+		},
+		{
+			"prompt",
+			`{"promptFeedback":{"blockReason":"PROHIBITED_CONTENT"}}`,
+			"blocked_prohibited_content", "prompt blocked for prohibited or sensitive content",
+		},
+		{
+			"recitation",
+			`{"candidates":[{"content":{"parts":[{"text":"Once upon"}]},"finishReason":"RECITATION"}]}`,
+			"blocked_recitation", "recited copyrighted material",
+		},
+		{
+			"malformed call",
+			`{"candidates":[{"content":{"parts":[]},"finishReason":"MALFORMED_FUNCTION_CALL","finishMessage":"bad args"}]}`,
+			"malformed_function_call", "invalid function call: bad args",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := geminiStub(t, tt.event)
+			p := NewGoogle("key", "gemini-2.0-flash", GoogleOptions{BaseURL: server.URL})
+			ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var streamErr error
+			for chunk := range ch {
+				if chunk.Error != nil {
+					streamErr = chunk.Error
+				}
+			}
+			var pe *Error
+			if !errors.As(streamErr, &pe) || pe.Code != tt.code || !strings.Contains(pe.Message, tt.want) {
+				t.Errorf("error = %v, want code %s containing %q", streamErr, tt.code, tt.want)
+			}
+			if pe != nil && pe.Retryable {
+				t.Error("a blocked response should not be retried")
+			}
+		})
+	}
+}
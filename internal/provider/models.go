@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ModelInfo describes a model as reported by a provider's listing API.
+// Zero values mean the provider didn't say.
+type ModelInfo struct {
+	ID              string `json:"id"`
+	DisplayName     string `json:"display_name,omitempty"`
+	ContextWindow   int    `json:"context_window,omitempty"` // Input tokens
+	MaxOutputTokens int    `json:"max_output_tokens,omitempty"`
+	Vision          bool   `json:"vision,omitempty"`
+	Tools           bool   `json:"tools,omitempty"`
+	Thinking        bool   `json:"thinking,omitempty"`
+}
+
+// ModelLister is implemented by providers that can describe their models,
+// not just name them.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// ListModels describes the models p serves. Middleware is unwrapped to find
+// a ModelLister; providers without one report IDs only.
+func ListModels(ctx context.Context, p Provider) ([]ModelInfo, error) {
+	for inner := p; inner != nil; inner = unwrap(inner) {
+		if l, ok := inner.(ModelLister); ok {
+			return l.ListModels(ctx)
+		}
+	}
+	ids, err := p.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]ModelInfo, len(ids))
+	for i, id := range ids {
+		infos[i] = ModelInfo{ID: id}
+	}
+	return infos, nil
+}
+
+// DescribeModel returns what the provider reports about model. It returns
+// false when p has no ModelLister or the model isn't listed, so callers fall
+// back to guessing from the name.
+func DescribeModel(ctx context.Context, p Provider, model string) (ModelInfo, bool) {
+	for inner := p; inner != nil; inner = unwrap(inner) {
+		l, ok := inner.(ModelLister)
+		if !ok {
+			continue
+		}
+		infos, err := l.ListModels(ctx)
+		if err != nil {
+			return ModelInfo{}, false
+		}
+		for _, info := range infos {
+			if info.ID == model {
+				return info, true
+			}
+		}
+		return ModelInfo{}, false
+	}
+	return ModelInfo{}, false
+}
+
+// unwrap returns the provider wrapped by middleware, or nil.
+func unwrap(p Provider) Provider {
+	if w, ok := p.(interface{ Unwrap() Provider }); ok {
+		return w.Unwrap()
+	}
+	return nil
+}
+
+// modelCacheTTL is how long a model listing is reused before it is fetched
+// again. Listings change rarely, and startup shouldn't wait on the network.
+const modelCacheTTL = 24 * time.Hour
+
+type modelCacheFile struct {
+	FetchedAt time.Time   `json:"fetched_at"`
+	Models    []ModelInfo `json:"models"`
+}
+
+// cachedModels returns the listing for name at baseURL from the disk cache,
+// calling fetch when it is missing or older than modelCacheTTL. A stale
+// listing is still returned when fetch fails, so an offline start keeps the
+// metadata it had.
+func cachedModels(name, baseURL string, fetch func() ([]ModelInfo, error)) ([]ModelInfo, error) {
+	sum := sha256.Sum256([]byte(baseURL))
+	path := filepath.Join(modelCacheDir(), name+"-"+hex.EncodeToString(sum[:4])+".json")
+
+	var cached modelCacheFile
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cached) == nil {
+		if time.Since(cached.FetchedAt) < modelCacheTTL {
+			return cached.Models, nil
+		}
+	}
+
+	models, err := fetch()
+	if err != nil {
+		if cached.Models != nil {
+			return cached.Models, nil
+		}
+		return nil, err
+	}
+	if data, err := json.Marshal(modelCacheFile{FetchedAt: time.Now(), Models: models}); err == nil {
+		if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+			os.WriteFile(path, data, 0644)
+		}
+	}
+	return models, nil
+}
+
+func modelCacheDir() string {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "aseity", "models")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "aseity", "models")
+}
+
+// modelIDs returns the IDs of a listing.
+func modelIDs(infos []ModelInfo) []string {
+	ids := make([]string, len(infos))
+	for i, info := range infos {
+		ids[i] = info.ID
+	}
+	return ids
+}
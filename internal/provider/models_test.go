@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAnthropic_ListModels(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("x-api-key") != "key" {
+			t.Errorf("missing api key header")
+		}
+		if r.URL.Query().Get("after_id") == "" {
+			w.Write([]byte(`{"data":[{"id":"claude-sonnet-4-5","display_name":"Claude Sonnet 4.5"}],"has_more":true,"last_id":"claude-sonnet-4-5"}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"claude-3-5-haiku-20241022","display_name":"Claude Haiku 3.5"}],"has_more":false}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropic("key", "", AnthropicOptions{BaseURL: server.URL})
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || calls != 2 {
+		t.Fatalf("got %d models in %d requests, want 2 in 2", len(models), calls)
+	}
+	if m := models[0]; m.DisplayName != "Claude Sonnet 4.5" || m.ContextWindow != 200000 || !m.Thinking || !m.Tools {
+		t.Errorf("models[0] = %+v", m)
+	}
+	if models[1].Thinking {
+		t.Error("Claude 3.5 has no extended thinking")
+	}
+
+	// A second provider at the same URL reads the disk cache.
+	ids, err := NewAnthropic("key", "", AnthropicOptions{BaseURL: server.URL}).Models(context.Background())
+	if err != nil || len(ids) != 2 || calls != 2 {
+		t.Errorf("Models = %v, %v after %d requests; want cached listing", ids, err, calls)
+	}
+}
+
+func TestGoogle_ListModels(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models" || r.Header.Get("x-goog-api-key") != "key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"models":[
+			{"name":"models/gemini-2.5-pro","displayName":"Gemini 2.5 Pro","inputTokenLimit":1048576,"outputTokenLimit":65536,"supportedGenerationMethods":["generateContent","countTokens"],"thinking":true},
+			{"name":"models/gemini-embedding-001","supportedGenerationMethods":["embedContent"]},
+			{"name":"models/gemma-3-27b-it","inputTokenLimit":131072,"supportedGenerationMethods":["generateContent"]}
+		]}`))
+	}))
+	defer server.Close()
+
+	models, err := NewGoogle("key", "", GoogleOptions{BaseURL: server.URL}).ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ModelInfo{
+		{ID: "gemini-2.5-pro", DisplayName: "Gemini 2.5 Pro", ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Tools: true, Thinking: true},
+		{ID: "gemma-3-27b-it", ContextWindow: 131072},
+	}
+	if len(models) != len(want) || models[0] != want[0] || models[1] != want[1] {
+		t.Errorf("models = %+v, want %+v", models, want)
+	}
+}
+
+func TestCachedModels_StaleOnError(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	fetch := func() ([]ModelInfo, error) { return []ModelInfo{{ID: "m1"}}, nil }
+	if _, err := cachedModels("test", "http://x", fetch); err != nil {
+		t.Fatal(err)
+	}
+
+	// Age the cache past its TTL.
+	files, _ := filepath.Glob(filepath.Join(dir, "aseity", "models", "test-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("cache files = %v", files)
+	}
+	old := time.Now().Add(-2 * modelCacheTTL)
+	os.WriteFile(files[0], []byte(`{"fetched_at":"`+old.Format(time.RFC3339)+`","models":[{"id":"m1"}]}`), 0644)
+
+	refetched := false
+	models, err := cachedModels("test", "http://x", func() ([]ModelInfo, error) {
+		refetched = true
+		return nil, errors.New("offline")
+	})
+	if !refetched || err != nil || len(models) != 1 {
+		t.Errorf("got %v, %v (refetched %v); want stale listing after a failed refresh", models, err, refetched)
+	}
+}
+
+func TestDescribeModel_Unwraps(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"id":"claude-opus-4-1"}]}`))
+	}))
+	defer server.Close()
+
+	p := WithRetry(NewAnthropic("key", "claude-opus-4-1", AnthropicOptions{BaseURL: server.URL}), 1)
+	info, ok := DescribeModel(context.Background(), p, "claude-opus-4-1")
+	if !ok || info.ContextWindow != 200000 {
+		t.Errorf("DescribeModel = %+v, %v", info, ok)
+	}
+	if _, ok := DescribeModel(context.Background(), p, "claude-unknown"); ok {
+		t.Error("unlisted model should not be described")
+	}
+}
@@ -0,0 +1,406 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// textToolFallbackInstruction is injected when a backend rejects native tools so
+// the model keeps using the [TOOL:name|args] text protocol instead of improvising.
+const textToolFallbackInstruction = "OPERATIONAL ALERT: Native tool calling is unavailable. You MUST use the text format `[TOOL:name|json_args]` for all actions. Do not write code to execute tools; use the tag."
+
+// OllamaOptions holds the native Ollama generation settings.
+// Zero values mean "use the server default".
+type OllamaOptions struct {
+	NumCtx      int      // Context window size (Ollama defaults to 2048)
+	Temperature *float64 // Sampling temperature
+	KeepAlive   string   // How long the model stays loaded, e.g. "10m" or "-1"
+	Think       bool     // Request native thinking output from reasoning models
+
+	EmbeddingModel string // Model for Embed
+}
+
+// OllamaProvider talks to Ollama's native /api/chat endpoint instead of the
+// OpenAI-compatible shim, which ignores model options like num_ctx.
+type OllamaProvider struct {
+	name    string
+	baseURL string
+	model   string
+	opts    OllamaOptions
+	client  *http.Client
+	dims    atomic.Int64 // Embedding vector length, once seen
+}
+
+func NewOllama(name, baseURL, model string, opts OllamaOptions) *OllamaProvider {
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	// Accept the OpenAI-style base URL so existing configs can switch types.
+	baseURL = strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+	if opts.NumCtx == 0 {
+		opts.NumCtx = 32768
+	}
+	if opts.EmbeddingModel == "" {
+		opts.EmbeddingModel = DefaultOllamaEmbeddingModel
+	}
+	return &OllamaProvider{
+		name:    name,
+		baseURL: baseURL,
+		model:   model,
+		opts:    opts,
+		client:  &http.Client{},
+	}
+}
+
+func (o *OllamaProvider) Name() string { return o.name }
+
+func (o *OllamaProvider) ModelName() string { return o.model }
+
+func (o *OllamaProvider) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", o.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, newTransportError(o.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, o.httpError(resp, body)
+	}
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	models := make([]string, len(result.Models))
+	for i, m := range result.Models {
+		models[i] = m.Name
+	}
+	return models, nil
+}
+
+type ollamaRequest struct {
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Tools     []oaiTool       `json:"tools,omitempty"`
+	Stream    bool            `json:"stream"`
+	Think     bool            `json:"think,omitempty"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+	Options   map[string]any  `json:"options,omitempty"`
+	Format    any             `json:"format,omitempty"` // "json" or a JSON Schema object
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"` // base64, no data: prefix
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaStreamChunk struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (o *OllamaProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	genOpts := ResolveChatOptions(ctx, opts)
+
+	// Ollama identifies tool results by tool name rather than call ID.
+	toolNames := map[string]string{}
+	ollamaMsgs := make([]ollamaMessage, 0, len(msgs))
+	// Images from tool results follow the last result of a batch in one
+	// user message, as for OpenAI.
+	var attachments *ollamaMessage
+	flushAttachments := func() {
+		if attachments != nil {
+			ollamaMsgs = append(ollamaMsgs, *attachments)
+			attachments = nil
+		}
+	}
+	for _, m := range msgs {
+		if m.Role != RoleTool {
+			flushAttachments()
+		}
+		om := ollamaMessage{Role: string(m.Role), Content: m.Content}
+		for _, tc := range m.ToolCalls {
+			toolNames[tc.ID] = tc.Name
+			var call ollamaToolCall
+			call.Function.Name = tc.Name
+			call.Function.Arguments = json.RawMessage(normalizeToolArgs(tc.Args))
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		if m.Role == RoleTool {
+			om.ToolName = toolNames[m.ToolCallID]
+		}
+		images, text := ollamaParts(m.Parts)
+		if m.Role == RoleTool && len(images) > 0 {
+			ollamaMsgs = append(ollamaMsgs, om)
+			if attachments == nil {
+				attachments = &ollamaMessage{Role: "user"}
+			} else {
+				attachments.Content += "\n\n"
+			}
+			attachments.Content += toolAttachmentCaption(m.ToolCallID) + text
+			attachments.Images = append(attachments.Images, images...)
+			continue
+		}
+		om.Content += text
+		om.Images = images
+		ollamaMsgs = append(ollamaMsgs, om)
+	}
+	flushAttachments()
+
+	var ollamaTools []oaiTool
+	for _, t := range tools {
+		ollamaTools = append(ollamaTools, oaiTool{
+			Type:     "function",
+			Function: oaiFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+		})
+	}
+
+	reqBody := ollamaRequest{
+		Model:     o.model,
+		Messages:  ollamaMsgs,
+		Tools:     ollamaTools,
+		Stream:    true,
+		Think:     o.opts.Think,
+		KeepAlive: o.opts.KeepAlive,
+		Options:   map[string]any{"num_ctx": o.opts.NumCtx},
+	}
+	// Per-request options take precedence over the configured defaults.
+	if genOpts.Temperature != nil {
+		reqBody.Options["temperature"] = *genOpts.Temperature
+	} else if o.opts.Temperature != nil {
+		reqBody.Options["temperature"] = *o.opts.Temperature
+	}
+	if genOpts.TopP != nil {
+		reqBody.Options["top_p"] = *genOpts.TopP
+	}
+	if genOpts.MaxTokens > 0 {
+		reqBody.Options["num_predict"] = genOpts.MaxTokens
+	}
+	if len(genOpts.Stop) > 0 {
+		reqBody.Options["stop"] = genOpts.Stop
+	}
+	if genOpts.Seed != nil {
+		reqBody.Options["seed"] = *genOpts.Seed
+	}
+	if rf := genOpts.ResponseFormat; rf != nil {
+		if rf.Schema != nil {
+			reqBody.Format = rf.Schema
+		} else {
+			reqBody.Format = "json"
+		}
+	}
+
+	resp, err := o.post(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same auto-fix as the OpenAI path: models without tool support reject the request.
+	if resp.StatusCode != 200 && len(ollamaTools) > 0 {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.Contains(strings.ToLower(string(body)), "does not support tools") {
+			fmt.Fprintf(os.Stderr, "\r\n(Auto-Fix) Native tools failed. Switching to text-based tool mode...\r\n")
+			reqBody.Tools = nil
+			reqBody.Messages = append(reqBody.Messages, ollamaMessage{Role: "system", Content: textToolFallbackInstruction})
+			resp, err = o.post(ctx, reqBody)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, o.httpError(resp, body)
+	}
+
+	ch := make(chan StreamChunk, 64)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		var toolCalls []ToolCall
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var chunk ollamaStreamChunk
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				continue
+			}
+			if chunk.Error != "" {
+				ch <- StreamChunk{Error: &Error{Provider: o.name, Message: chunk.Error}, Done: true}
+				return
+			}
+			if chunk.Message.Thinking != "" {
+				ch <- StreamChunk{Thinking: chunk.Message.Thinking}
+			}
+			if chunk.Message.Content != "" {
+				ch <- StreamChunk{Delta: chunk.Message.Content}
+			}
+			for _, tc := range chunk.Message.ToolCalls {
+				args := string(tc.Function.Arguments)
+				if args == "" || args == "null" {
+					args = "{}"
+				}
+				toolCalls = append(toolCalls, ToolCall{
+					ID:   fmt.Sprintf("ollama-%d", len(toolCalls)),
+					Name: tc.Function.Name,
+					Args: args,
+				})
+			}
+			if chunk.Done {
+				ch <- StreamChunk{
+					Done:      true,
+					ToolCalls: toolCalls,
+					Usage: &Usage{
+						InputTokens:  chunk.PromptEvalCount,
+						OutputTokens: chunk.EvalCount,
+						TotalTokens:  chunk.PromptEvalCount + chunk.EvalCount,
+					},
+				}
+				return
+			}
+		}
+		ch <- StreamChunk{Error: streamEndError(o.name, scanner.Err()), Done: true}
+	}()
+	return ch, nil
+}
+
+func (o *OllamaProvider) post(ctx context.Context, body ollamaRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, newTransportError(o.name, err)
+	}
+	return resp, nil
+}
+
+func (o *OllamaProvider) httpError(resp *http.Response, body []byte) *Error {
+	e := newHTTPError(o.name, resp, body)
+	e.Message = parseOllamaError(resp.StatusCode, body)
+	return e
+}
+
+// normalizeToolArgs returns args as a JSON object, since Ollama expects
+// arguments as an object rather than the OpenAI-style encoded string.
+func normalizeToolArgs(args string) string {
+	if json.Valid([]byte(args)) && strings.HasPrefix(strings.TrimSpace(args), "{") {
+		return args
+	}
+	return "{}"
+}
+
+// parseOllamaError handles Ollama's {"error": "..."} body, which uses a plain
+// string rather than the OpenAI error object.
+func parseOllamaError(statusCode int, body []byte) string {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		return errResp.Error
+	}
+	return parseProviderError("ollama", statusCode, body)
+}
+
+// ollamaParts splits content parts into Ollama's base64 image list and any
+// extra text. Ollama only accepts inline image data, so URL images are skipped.
+func ollamaParts(parts []ContentPart) (images []string, text string) {
+	for _, p := range parts {
+		switch p.Type {
+		case PartImage:
+			if len(p.Data) > 0 {
+				images = append(images, p.Base64())
+			}
+		case PartText:
+			text += "\n" + p.Text
+		}
+	}
+	return images, text
+}
+
+// ollamaEmbedBatchSize bounds each /api/embed request; Ollama embeds a batch
+// in one forward pass, so very large batches only add memory pressure.
+const ollamaEmbedBatchSize = 64
+
+func (o *OllamaProvider) EmbeddingModel() string { return o.opts.EmbeddingModel }
+
+func (o *OllamaProvider) Dimensions() int { return int(o.dims.Load()) }
+
+// Embed calls the native /api/embed endpoint.
+func (o *OllamaProvider) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	return embedBatches(ctx, inputs, ollamaEmbedBatchSize, &o.dims, o.embed)
+}
+
+func (o *OllamaProvider) embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	payload, _ := json.Marshal(struct {
+		Model     string   `json:"model"`
+		Input     []string `json:"input"`
+		KeepAlive string   `json:"keep_alive,omitempty"`
+	}{o.opts.EmbeddingModel, inputs, o.opts.KeepAlive})
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/embed", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, newTransportError(o.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, o.httpError(resp, body)
+	}
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Embeddings, nil
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllama_RequestOptionsAndStream(t *testing.T) {
+	temp := 0.2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected path /api/chat, got %s", r.URL.Path)
+		}
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Options["num_ctx"] != float64(8192) {
+			t.Errorf("num_ctx = %v, want 8192", req.Options["num_ctx"])
+		}
+		if req.Options["temperature"] != 0.2 {
+			t.Errorf("temperature = %v, want 0.2", req.Options["temperature"])
+		}
+		if req.KeepAlive != "10m" || !req.Think {
+			t.Errorf("keep_alive/think not forwarded: %q %v", req.KeepAlive, req.Think)
+		}
+		// Tool results must be tagged with the tool name
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role != "tool" || last.ToolName != "bash" {
+			t.Errorf("tool message = %+v, want tool_name bash", last)
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"message":{"role":"assistant","thinking":"hmm"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Hi"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","tool_calls":[{"function":{"name":"file_read","arguments":{"path":"a.go"}}}]},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":12,"eval_count":5}` + "\n"))
+	}))
+	defer server.Close()
+
+	p := NewOllama("ollama", server.URL+"/v1", "llama3", OllamaOptions{
+		NumCtx: 8192, Temperature: &temp, KeepAlive: "10m", Think: true,
+	})
+
+	msgs := []Message{
+		{Role: RoleUser, Content: "run it"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "c1", Name: "bash", Args: `{"command":"ls"}`}}},
+		{Role: RoleTool, ToolCallID: "c1", Content: "ok"},
+	}
+	stream, err := p.Chat(context.Background(), msgs, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	var text, thinking string
+	var final StreamChunk
+	for chunk := range stream {
+		if chunk.Error != nil {
+			t.Fatalf("stream error: %v", chunk.Error)
+		}
+		text += chunk.Delta
+		thinking += chunk.Thinking
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	if text != "Hi" || thinking != "hmm" {
+		t.Errorf("text=%q thinking=%q", text, thinking)
+	}
+	if len(final.ToolCalls) != 1 || final.ToolCalls[0].Name != "file_read" || final.ToolCalls[0].Args != `{"path":"a.go"}` {
+		t.Errorf("unexpected tool calls: %+v", final.ToolCalls)
+	}
+	if final.Usage == nil || final.Usage.InputTokens != 12 || final.Usage.OutputTokens != 5 || final.Usage.TotalTokens != 17 {
+		t.Errorf("unexpected usage: %+v", final.Usage)
+	}
+}
+
+func TestOllama_ToolImagesAfterBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		var roles []string
+		for _, m := range req.Messages {
+			roles = append(roles, m.Role)
+		}
+		if len(roles) != 5 || roles[2] != "tool" || roles[3] != "tool" || roles[4] != "user" {
+			t.Fatalf("roles = %v, want both tool results before one user turn", roles)
+		}
+		if len(req.Messages[4].Images) != 2 {
+			t.Errorf("attachments carry %d images, want 2", len(req.Messages[4].Images))
+		}
+		w.Write([]byte(`{"message":{"role":"assistant","content":"ok"},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	png := []byte{0x89, 0x50, 0x4e}
+	msgs := []Message{
+		{Role: RoleUser, Content: "look"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "c1", Name: "web_crawl", Args: "{}"}, {ID: "c2", Name: "web_crawl", Args: "{}"}}},
+		{Role: RoleTool, ToolCallID: "c1", Content: "one", Parts: []ContentPart{ImagePart("image/png", png)}},
+		{Role: RoleTool, ToolCallID: "c2", Content: "two", Parts: []ContentPart{ImagePart("image/png", png)}},
+	}
+	stream, err := NewOllama("ollama", server.URL, "llava", OllamaOptions{}).Chat(context.Background(), msgs, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	for range stream {
+	}
+}
+
+func TestOllama_ErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model 'nope' not found"}`))
+	}))
+	defer server.Close()
+
+	p := NewOllama("ollama", server.URL, "nope", OllamaOptions{})
+	_, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err == nil || err.Error() != "provider ollama: model 'nope' not found" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
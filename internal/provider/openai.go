@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync/atomic"
 )
 
 type OpenAIProvider struct {
@@ -17,15 +20,58 @@ type OpenAIProvider struct {
 	baseURL string
 	apiKey  string
 	model   string
+	opts    OpenAIOptions
 	client  *http.Client
+	dims    atomic.Int64 // Embedding vector length, once seen
+}
+
+// OpenAI-compatible server variants with their own URL, auth or request
+// quirks.
+const (
+	VariantAzure    = "azure"    // Azure OpenAI: deployment URLs, api-version, api-key header
+	VariantLlamaCpp = "llamacpp" // llama.cpp server: /models may be missing
+	VariantLMStudio = "lmstudio" // LM Studio: only json_schema response formats
+)
+
+// azureDefaultAPIVersion is used when an Azure provider doesn't set one.
+const azureDefaultAPIVersion = "2024-10-21"
+
+// OpenAIOptions adapts the provider to OpenAI-compatible servers that differ
+// from the OpenAI API. Zero values mean plain OpenAI behavior.
+type OpenAIOptions struct {
+	Variant    string // One of the Variant constants, or empty
+	APIVersion string // Azure api-version query parameter
+	Deployment string // Azure deployment name; defaults to the model
+
+	Headers map[string]string // Sent with every request
+	Query   map[string]string // Added to every request URL
+	Extra   map[string]any    // Merged into chat request bodies, e.g. llama.cpp grammar or n_predict
+
+	EmbeddingModel string // Model (or Azure deployment) for Embed
 }
 
 func NewOpenAI(name, baseURL, apiKey, model string) *OpenAIProvider {
+	return NewOpenAIWithOptions(name, baseURL, apiKey, model, OpenAIOptions{})
+}
+
+func NewOpenAIWithOptions(name, baseURL, apiKey, model string, opts OpenAIOptions) *OpenAIProvider {
+	if opts.Variant == VariantAzure {
+		if opts.APIVersion == "" {
+			opts.APIVersion = azureDefaultAPIVersion
+		}
+		if opts.Deployment == "" {
+			opts.Deployment = model
+		}
+	}
+	if opts.EmbeddingModel == "" {
+		opts.EmbeddingModel = DefaultOpenAIEmbeddingModel
+	}
 	return &OpenAIProvider{
 		name:    name,
 		baseURL: strings.TrimRight(baseURL, "/"),
 		apiKey:  apiKey,
 		model:   model,
+		opts:    opts,
 		client:  &http.Client{},
 	}
 }
@@ -35,18 +81,33 @@ func (o *OpenAIProvider) Name() string { return o.name }
 func (o *OpenAIProvider) ModelName() string { return o.model }
 
 func (o *OpenAIProvider) Models(ctx context.Context) ([]string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", o.baseURL+"/models", nil)
+	// An Azure resource serves its deployments, which /models doesn't list.
+	if o.opts.Variant == VariantAzure {
+		return []string{o.opts.Deployment}, nil
+	}
+	models, err := o.listModels(ctx)
+	// llama.cpp only gained /models recently and serves a single model anyway.
+	if err != nil && o.opts.Variant == VariantLlamaCpp && o.model != "" {
+		return []string{o.model}, nil
+	}
+	return models, err
+}
+
+func (o *OpenAIProvider) listModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", o.endpoint("/models"), nil)
 	if err != nil {
 		return nil, err
 	}
-	if o.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+o.apiKey)
-	}
+	o.setHeaders(req)
 	resp, err := o.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newTransportError(o.name, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newHTTPError(o.name, resp, body)
+	}
 	var result struct {
 		Data []struct {
 			ID string `json:"id"`
@@ -62,6 +123,43 @@ func (o *OpenAIProvider) Models(ctx context.Context) ([]string, error) {
 	return models, nil
 }
 
+// endpoint builds the URL for an API path such as "/chat/completions".
+func (o *OpenAIProvider) endpoint(path string) string {
+	return o.deploymentEndpoint(o.opts.Deployment, path)
+}
+
+// deploymentEndpoint is endpoint for a specific Azure deployment; Azure
+// serves chat and embedding models from separate deployments.
+func (o *OpenAIProvider) deploymentEndpoint(deployment, path string) string {
+	base, q := o.baseURL, url.Values{}
+	if o.opts.Variant == VariantAzure {
+		base += "/openai/deployments/" + url.PathEscape(deployment)
+		q.Set("api-version", o.opts.APIVersion)
+	}
+	u := base + path
+	for k, v := range o.opts.Query {
+		q.Set(k, v)
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+// setHeaders adds authentication and any configured extra headers.
+func (o *OpenAIProvider) setHeaders(req *http.Request) {
+	if o.apiKey != "" {
+		if o.opts.Variant == VariantAzure {
+			req.Header.Set("api-key", o.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+o.apiKey)
+		}
+	}
+	for k, v := range o.opts.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
 type oaiRequest struct {
 	Model         string       `json:"model"`
 	Messages      []oaiMessage `json:"messages"`
@@ -71,11 +169,23 @@ type oaiRequest struct {
 		IncludeUsage bool `json:"include_usage"`
 	} `json:"stream_options,omitempty"`
 	Options map[string]any `json:"options,omitempty"` // For Ollama-specific parameters
+
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+
+	// MaxCompletionTokens replaces max_tokens where the reasoning models
+	// (o-series, gpt-5) may be served; they reject the older field.
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+
+	ResponseFormat any `json:"response_format,omitempty"`
 }
 
 type oaiMessage struct {
 	Role       string        `json:"role"`
-	Content    string        `json:"content"`
+	Content    any           `json:"content"` // string, or []map for multimodal content
 	ToolCalls  []oaiToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string        `json:"tool_call_id,omitempty"`
 }
@@ -115,13 +225,42 @@ type oaiStreamChunk struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
+
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details,omitempty"`
 	} `json:"usage,omitempty"`
+	Error *oaiStreamErr `json:"error,omitempty"`
+}
+
+type oaiStreamErr struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code"` // String or numeric HTTP status, depending on server
 }
 
-func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef) (<-chan StreamChunk, error) {
-	oaiMsgs := make([]oaiMessage, len(msgs))
-	for i, m := range msgs {
+func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	genOpts := ResolveChatOptions(ctx, opts)
+
+	// Tool messages are text-only, so images produced by tools are handed
+	// over in one user message after the last result of a batch: a user
+	// message between results of parallel calls is rejected.
+	oaiMsgs := make([]oaiMessage, 0, len(msgs))
+	var attachments []map[string]any
+	flushAttachments := func() {
+		if len(attachments) > 0 {
+			oaiMsgs = append(oaiMsgs, oaiMessage{Role: "user", Content: attachments})
+			attachments = nil
+		}
+	}
+	for _, m := range msgs {
+		if m.Role != RoleTool {
+			flushAttachments()
+		}
 		om := oaiMessage{Role: string(m.Role), Content: m.Content, ToolCallID: m.ToolCallID}
+		if len(m.Parts) > 0 && m.Role != RoleTool {
+			om.Content = oaiContentParts(m.Content, m.Parts)
+		}
 		for _, tc := range m.ToolCalls {
 			om.ToolCalls = append(om.ToolCalls, oaiToolCall{
 				ID:       tc.ID,
@@ -129,8 +268,12 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 				Function: oaiToolCallFunc{Name: tc.Name, Arguments: tc.Args},
 			})
 		}
-		oaiMsgs[i] = om
+		oaiMsgs = append(oaiMsgs, om)
+		if m.Role == RoleTool && len(m.Parts) > 0 {
+			attachments = append(attachments, oaiContentParts(toolAttachmentCaption(m.ToolCallID), m.Parts)...)
+		}
 	}
+	flushAttachments()
 
 	var oaiTools []oaiTool
 	for _, t := range tools {
@@ -148,32 +291,31 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 		StreamOptions: &struct {
 			IncludeUsage bool `json:"include_usage"`
 		}{IncludeUsage: true},
+		Temperature: genOpts.Temperature,
+		TopP:        genOpts.TopP,
+		MaxTokens:   genOpts.MaxTokens,
+		Stop:        genOpts.Stop,
+		Seed:        genOpts.Seed,
+
+		ResponseFormat: oaiResponseFormat(genOpts.ResponseFormat),
+	}
+	if o.opts.Variant == VariantLMStudio {
+		reqBody.ResponseFormat = lmStudioResponseFormat(genOpts.ResponseFormat)
+	}
+	if o.usesMaxCompletionTokens() {
+		reqBody.MaxTokens, reqBody.MaxCompletionTokens = 0, genOpts.MaxTokens
 	}
 
 	// Ollama Optimization: Force larger context window
 	// Aseity assumes high context usage, but Ollama defaults to 2048.
 	// We check for common Ollama ports or exact localhost matches to be safe.
-	if strings.Contains(o.baseURL, "11434") || strings.Contains(o.baseURL, "localhost") {
+	if o.opts.Variant == "" && (strings.Contains(o.baseURL, "11434") || strings.Contains(o.baseURL, "localhost")) {
 		reqBody.Options = map[string]any{
 			"num_ctx": 32768, // Default to a reasonable high value (Qwen supports 32k)
 		}
 	}
 
-	payload, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(payload))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if o.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+o.apiKey)
-	}
-
-	resp, err := o.client.Do(req)
+	resp, err := o.post(ctx, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -201,36 +343,42 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 			// This prevents models from hallucinating shell sessions when tools are removed.
 			fallbackInstruction := oaiMessage{
 				Role:    "system",
-				Content: "OPERATIONAL ALERT: Native tool calling is unavailable. You MUST use the text format `[TOOL:name|json_args]` for all actions. Do not write code to execute tools; use the tag.",
+				Content: textToolFallbackInstruction,
 			}
 			reqBody.Messages = append(reqBody.Messages, fallbackInstruction)
 
-			payload, _ = json.Marshal(reqBody)
-
-			req, err = http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(payload))
+			// Retry the request
+			resp, err = o.post(ctx, reqBody)
 			if err != nil {
 				return nil, err
 			}
-			req.Header.Set("Content-Type", "application/json")
-			if o.apiKey != "" {
-				req.Header.Set("Authorization", "Bearer "+o.apiKey)
-			}
+		} else {
+			// Restore body for regular error handling
+			resp.Body = io.NopCloser(strings.NewReader(errMsg))
+		}
+	}
 
-			// Retry the request
-			resp, err = o.client.Do(req)
+	// Not every OpenAI-compatible server understands response_format. Drop it and
+	// let the caller fall back to cleaning up free-form JSON.
+	if resp.StatusCode == 400 && reqBody.ResponseFormat != nil {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		errMsgLower := strings.ToLower(string(body))
+		if strings.Contains(errMsgLower, "response_format") || strings.Contains(errMsgLower, "json_schema") {
+			reqBody.ResponseFormat = nil
+			resp, err = o.post(ctx, reqBody)
 			if err != nil {
 				return nil, err
 			}
 		} else {
-			// Restore body for regular error handling
-			resp.Body = io.NopCloser(strings.NewReader(errMsg))
+			resp.Body = io.NopCloser(bytes.NewReader(body))
 		}
 	}
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("provider %s: %s", o.name, parseProviderError(o.name, resp.StatusCode, body))
+		return nil, newHTTPError(o.name, resp, body)
 	}
 
 	ch := make(chan StreamChunk, 64)
@@ -242,6 +390,11 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 		// Track <think> blocks for reasoning models (DeepSeek-R1, QwQ, etc.)
 		inThink := false
 		var contentBuf strings.Builder
+		// With include_usage, the usage arrives in its own chunk after the one
+		// carrying finish_reason, so completion is held until it (or [DONE]) is seen.
+		var usage *Usage
+		var finished []ToolCall
+		isFinished := false
 		for scanner.Scan() {
 			line := scanner.Text()
 			if !strings.HasPrefix(line, "data: ") {
@@ -260,19 +413,37 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 					contentBuf.Reset()
 				}
 
-				var tcs []ToolCall
-				for _, tc := range toolCalls {
-					tcs = append(tcs, *tc)
+				if isFinished {
+					ch <- StreamChunk{Done: true, ToolCalls: finished, Usage: usage}
+					return
 				}
-				// Note: [DONE] marker doesn't include usage, it was in previous chunk
-				ch <- StreamChunk{Done: true, ToolCalls: tcs}
+				ch <- StreamChunk{Done: true, ToolCalls: orderedToolCalls(toolCalls), Usage: usage}
 				return
 			}
 			var chunk oaiStreamChunk
 			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 				continue
 			}
+			// Some servers (vLLM, OpenRouter) report failures inside the stream.
+			if chunk.Error != nil {
+				ch <- StreamChunk{Error: oaiStreamError(o.name, chunk.Error), Done: true}
+				return
+			}
+			if chunk.Usage != nil {
+				usage = &Usage{
+					InputTokens:  chunk.Usage.PromptTokens,
+					OutputTokens: chunk.Usage.CompletionTokens,
+					TotalTokens:  chunk.Usage.TotalTokens,
+				}
+				if d := chunk.Usage.PromptTokensDetails; d != nil {
+					usage.CachedInputTokens = d.CachedTokens
+				}
+			}
 			if len(chunk.Choices) == 0 {
+				if isFinished && usage != nil {
+					ch <- StreamChunk{Done: true, ToolCalls: finished, Usage: usage}
+					return
+				}
 				continue
 			}
 			delta := chunk.Choices[0].Delta
@@ -370,6 +541,9 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 				if tc.Function.Name != "" {
 					toolCalls[idx].Name = tc.Function.Name
 				}
+				ch <- StreamChunk{ToolCallDelta: &ToolCallDelta{
+					Index: idx, ID: tc.ID, Name: tc.Function.Name, Args: tc.Function.Arguments,
+				}}
 			}
 			// Helper to flush remaining buffer
 			flush := func() {
@@ -386,24 +560,21 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 
 			if chunk.Choices[0].FinishReason != nil {
 				flush() // Flush any remaining content before finishing
-				var tcs []ToolCall
-				for _, tc := range toolCalls {
-					tcs = append(tcs, *tc)
+				tcs := orderedToolCalls(toolCalls)
+				if usage != nil {
+					ch <- StreamChunk{Done: true, ToolCalls: tcs, Usage: usage}
+					return
 				}
-				// Populate usage if available
-				var usage *Usage
-				if chunk.Usage != nil {
-					usage = &Usage{
-						InputTokens:  chunk.Usage.PromptTokens,
-						OutputTokens: chunk.Usage.CompletionTokens,
-						TotalTokens:  chunk.Usage.TotalTokens,
-					}
-				}
-				ch <- StreamChunk{Done: true, ToolCalls: tcs, Usage: usage}
-				return
+				finished, isFinished = tcs, true
 			}
 		}
 
+		if isFinished {
+			// The answer is complete even if the usage chunk never came.
+			ch <- StreamChunk{Done: true, ToolCalls: finished, Usage: usage}
+			return
+		}
+
 		// Final safety flush if loop exits without [DONE] or FinishReason
 		if contentBuf.Len() > 0 {
 			remaining := contentBuf.String()
@@ -414,9 +585,191 @@ func (o *OpenAIProvider) Chat(ctx context.Context, msgs []Message, tools []ToolD
 			}
 		}
 
-		if err := scanner.Err(); err != nil {
-			ch <- StreamChunk{Error: err, Done: true}
-		}
+		ch <- StreamChunk{Error: streamEndError(o.name, scanner.Err()), Done: true}
 	}()
 	return ch, nil
 }
+
+// usesMaxCompletionTokens reports whether the token limit must be sent as
+// max_completion_tokens: always on OpenAI itself and on Azure, whose
+// deployment names needn't say which model they run, and elsewhere for the
+// reasoning model families.
+func (o *OpenAIProvider) usesMaxCompletionTokens() bool {
+	if o.opts.Variant == VariantAzure {
+		return true
+	}
+	if u, err := url.Parse(o.baseURL); err == nil && u.Hostname() == "api.openai.com" {
+		return true
+	}
+	model := strings.ToLower(o.model)
+	model = model[strings.LastIndex(model, "/")+1:] // e.g. OpenRouter's "openai/o3"
+	for _, family := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if model == family || strings.HasPrefix(model, family+"-") || strings.HasPrefix(model, family+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// orderedToolCalls lists streamed tool calls by their index, the order the
+// model wrote them in.
+func orderedToolCalls(calls map[int]*ToolCall) []ToolCall {
+	indexes := make([]int, 0, len(calls))
+	for i := range calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	var tcs []ToolCall
+	for _, i := range indexes {
+		tcs = append(tcs, *calls[i])
+	}
+	return tcs
+}
+
+func (o *OpenAIProvider) post(ctx context.Context, body oaiRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.opts.Extra) > 0 {
+		if payload, err = mergeJSON(payload, o.opts.Extra); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", o.endpoint("/chat/completions"), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	o.setHeaders(req)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, newTransportError(o.name, err)
+	}
+	return resp, nil
+}
+
+// mergeJSON sets extra top-level fields on a JSON object, replacing any
+// existing ones.
+func mergeJSON(payload []byte, extra map[string]any) ([]byte, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		fields[k] = v
+	}
+	return json.Marshal(fields)
+}
+
+// lmStudioResponseFormat is oaiResponseFormat for LM Studio, which rejects
+// json_object: JSON mode is sent as a schema that accepts any object.
+func lmStudioResponseFormat(rf *ResponseFormat) any {
+	if rf != nil && rf.Schema == nil {
+		rf = &ResponseFormat{Name: rf.Name, Schema: map[string]any{"type": "object"}}
+	}
+	return oaiResponseFormat(rf)
+}
+
+// oaiResponseFormat maps a ResponseFormat to OpenAI's response_format field.
+func oaiResponseFormat(rf *ResponseFormat) any {
+	if rf == nil {
+		return nil
+	}
+	if rf.Schema == nil {
+		return map[string]any{"type": "json_object"}
+	}
+	return map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   rf.Name,
+			"schema": rf.Schema,
+		},
+	}
+}
+
+// oaiContentParts builds the OpenAI content-array form of a message.
+func oaiContentParts(text string, parts []ContentPart) []map[string]any {
+	var out []map[string]any
+	if text != "" {
+		out = append(out, map[string]any{"type": "text", "text": text})
+	}
+	for _, p := range parts {
+		switch p.Type {
+		case PartText:
+			out = append(out, map[string]any{"type": "text", "text": p.Text})
+		case PartImage:
+			out = append(out, map[string]any{"type": "image_url", "image_url": map[string]any{"url": p.DataURL()}})
+		}
+	}
+	return out
+}
+
+// oaiStreamError converts an in-stream error object into an Error.
+func oaiStreamError(providerName string, se *oaiStreamErr) *Error {
+	e := &Error{Provider: providerName, Code: se.Type, Message: se.Message}
+	var status int
+	var code string
+	if json.Unmarshal(se.Code, &status) == nil {
+		e.StatusCode = status
+	} else if json.Unmarshal(se.Code, &code) == nil && code != "" {
+		e.Code = code
+	}
+	e.Retryable = isRetryableStatus(e.StatusCode) ||
+		strings.Contains(e.Code, "server_error") || strings.Contains(e.Code, "overloaded")
+	if e.Message == "" {
+		e.Message = "stream error"
+	}
+	return e
+}
+
+// openAIEmbedBatchSize keeps requests well under the API's 2048-input limit
+// and the smaller limits of self-hosted servers.
+const openAIEmbedBatchSize = 256
+
+func (o *OpenAIProvider) EmbeddingModel() string { return o.opts.EmbeddingModel }
+
+func (o *OpenAIProvider) Dimensions() int { return int(o.dims.Load()) }
+
+// Embed calls the /embeddings endpoint.
+func (o *OpenAIProvider) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	return embedBatches(ctx, inputs, openAIEmbedBatchSize, &o.dims, o.embed)
+}
+
+func (o *OpenAIProvider) embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	payload, _ := json.Marshal(map[string]any{
+		"model":           o.opts.EmbeddingModel,
+		"input":           inputs,
+		"encoding_format": "float",
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", o.deploymentEndpoint(o.opts.EmbeddingModel, "/embeddings"), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	o.setHeaders(req)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, newTransportError(o.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newHTTPError(o.name, resp, body)
+	}
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+	vectors := make([][]float32, len(result.Data))
+	for i, d := range result.Data {
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
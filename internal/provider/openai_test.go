@@ -3,6 +3,9 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -151,3 +154,378 @@ func TestOpenAI_ToolCallFlow(t *testing.T) {
 		t.Fatalf("Chat with tool history failed: %v", err)
 	}
 }
+
+func TestOpenAI_ChatOptions(t *testing.T) {
+	server := httptest.NewServer(MockOllamaHandler(t, func(req *oaiRequest) {
+		if req.Temperature == nil || *req.Temperature != 0 {
+			t.Errorf("temperature = %v, want 0 (context override)", req.Temperature)
+		}
+		if req.TopP == nil || *req.TopP != 0.9 {
+			t.Errorf("top_p = %v, want 0.9", req.TopP)
+		}
+		if req.MaxTokens != 256 {
+			t.Errorf("max_tokens = %d, want 256", req.MaxTokens)
+		}
+		if len(req.Stop) != 1 || req.Stop[0] != "END" {
+			t.Errorf("stop = %v, want [END]", req.Stop)
+		}
+		if req.Seed == nil || *req.Seed != 7 {
+			t.Errorf("seed = %v, want 7", req.Seed)
+		}
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test", server.URL, "key", "model")
+
+	// Context options override explicit ones
+	ctx := WithChatOptions(context.Background(), WithTemperature(0))
+	_, err := p.Chat(ctx, []Message{{Role: RoleUser, Content: "hi"}}, nil,
+		WithTemperature(0.9), WithTopP(0.9), WithMaxTokens(256), WithStop("END"), WithSeed(7))
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+}
+
+func TestOpenAI_ResponseFormatFallback(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var raw map[string]any
+		json.NewDecoder(r.Body).Decode(&raw)
+		if calls == 1 {
+			rf, ok := raw["response_format"].(map[string]any)
+			if !ok || rf["type"] != "json_schema" {
+				t.Errorf("first request response_format = %v, want json_schema", raw["response_format"])
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"response_format is not supported"}}`))
+			return
+		}
+		if _, ok := raw["response_format"]; ok {
+			t.Error("retry should drop response_format")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test", server.URL, "key", "model")
+	schema := map[string]any{"type": "object"}
+	_, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil, WithResponseSchema("answer", schema))
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 requests, got %d", calls)
+	}
+}
+
+func TestOpenAI_ImageParts(t *testing.T) {
+	server := httptest.NewServer(MockOllamaHandler(t, func(req *oaiRequest) {
+		var roles []string
+		for _, m := range req.Messages {
+			roles = append(roles, m.Role)
+		}
+		// Tool images of a parallel batch follow the last result together
+		if strings.Join(roles, ",") != "user,assistant,tool,tool,user" {
+			t.Fatalf("roles = %v, want tool images moved to one user turn after both results", roles)
+		}
+		// User message with parts becomes a content array
+		user, ok := req.Messages[0].Content.([]any)
+		if !ok || len(user) != 2 {
+			t.Fatalf("user content = %#v, want text + image parts", req.Messages[0].Content)
+		}
+		img := user[1].(map[string]any)["image_url"].(map[string]any)
+		if img["url"] != "data:image/png;base64,iVBO" {
+			t.Errorf("image url = %v", img["url"])
+		}
+		// Tool messages stay plain text
+		if req.Messages[2].Content != "screenshot saved" || req.Messages[3].Content != "second" {
+			t.Errorf("tool messages = %+v, %+v", req.Messages[2], req.Messages[3])
+		}
+		if parts, _ := req.Messages[4].Content.([]any); len(parts) != 4 {
+			t.Errorf("attachments = %#v, want a caption and image per tool", req.Messages[4].Content)
+		}
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test", server.URL, "key", "gpt-4o")
+	png := []byte{0x89, 0x50, 0x4e}
+	msgs := []Message{
+		{Role: RoleUser, Content: "what is this?", Parts: []ContentPart{ImagePart("image/png", png)}},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "c1", Name: "web_crawl", Args: "{}"}, {ID: "c2", Name: "web_crawl", Args: "{}"}}},
+		{Role: RoleTool, ToolCallID: "c1", Content: "screenshot saved", Parts: []ContentPart{ImagePart("image/png", png)}},
+		{Role: RoleTool, ToolCallID: "c2", Content: "second", Parts: []ContentPart{ImagePart("image/png", png)}},
+	}
+	if _, err := p.Chat(context.Background(), msgs, nil); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+}
+
+func TestOpenAI_UsageAfterFinishReason(t *testing.T) {
+	// With include_usage, OpenAI sends usage in a chunk with no choices after
+	// the one carrying finish_reason.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Hello"},"finish_reason":null}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[],"usage":{"prompt_tokens":120,"completion_tokens":5,"total_tokens":125,"prompt_tokens_details":{"cached_tokens":100}}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test", server.URL, "key", "gpt-4o")
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var usage *Usage
+	for chunk := range ch {
+		if chunk.Done {
+			usage = chunk.Usage
+		}
+	}
+	want := Usage{InputTokens: 120, OutputTokens: 5, TotalTokens: 125, CachedInputTokens: 100}
+	if usage == nil || *usage != want {
+		t.Errorf("usage = %+v, want %+v", usage, want)
+	}
+}
+
+func TestOpenAI_StreamCutShort(t *testing.T) {
+	// The body ends cleanly but without finish_reason or [DONE].
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Hello, this answer stops"},"finish_reason":null}]}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test", server.URL, "key", "gpt-4o")
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	if !last.Done || !errors.Is(last.Error, io.ErrUnexpectedEOF) {
+		t.Fatalf("last chunk = %+v, want an unexpected EOF error", last)
+	}
+	if pe, ok := AsError(last.Error); !ok || !pe.Retryable {
+		t.Errorf("error = %v, want a retryable provider error", last.Error)
+	}
+}
+
+func TestOpenAI_ParallelToolCallOrder(t *testing.T) {
+	// Parallel calls stream interleaved; the final list keeps their indexes'
+	// order whether the stream ends with finish_reason or only with [DONE].
+	for _, finish := range []bool{true, false} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 4; i++ {
+				fmt.Fprintf(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":%d,"id":"call_%d","function":{"name":"file_read","arguments":"{\"path\":"}}]}}]}`+"\n\n", i, i)
+			}
+			for i := 3; i >= 0; i-- {
+				fmt.Fprintf(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":%d,"function":{"arguments":"\"f%d\"}"}}]}}]}`+"\n\n", i, i)
+			}
+			if finish {
+				w.Write([]byte(`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n"))
+			}
+			w.Write([]byte("data: [DONE]\n\n"))
+		}))
+
+		p := NewOpenAI("test", server.URL, "key", "gpt-4o")
+		for run := 0; run < 5; run++ {
+			ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, []ToolDef{{Name: "file_read"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var final []ToolCall
+			for chunk := range ch {
+				if chunk.Done {
+					final = chunk.ToolCalls
+				}
+			}
+			if len(final) != 4 {
+				t.Fatalf("finish_reason=%v: %d calls, want 4", finish, len(final))
+			}
+			for i, tc := range final {
+				if tc.ID != fmt.Sprintf("call_%d", i) || tc.Args != fmt.Sprintf(`{"path":"f%d"}`, i) {
+					t.Fatalf("finish_reason=%v: call %d = %+v, want call_%d", finish, i, tc, i)
+				}
+			}
+		}
+		server.Close()
+	}
+}
+
+func TestOpenAI_ToolCallDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"bash","arguments":""}}]}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"command\":"}}]}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"ls\"}"}}]}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAI("test", server.URL, "key", "gpt-4o")
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, []ToolDef{{Name: "bash"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deltas []ToolCallDelta
+	var final []ToolCall
+	for chunk := range ch {
+		if chunk.ToolCallDelta != nil {
+			deltas = append(deltas, *chunk.ToolCallDelta)
+		}
+		if chunk.Done {
+			final = chunk.ToolCalls
+		}
+	}
+	if len(deltas) != 3 || deltas[0].ID != "call_1" || deltas[0].Name != "bash" {
+		t.Fatalf("deltas = %+v", deltas)
+	}
+	var args string
+	for _, d := range deltas {
+		args += d.Args
+	}
+	if args != `{"command":"ls"}` {
+		t.Errorf("streamed args = %q", args)
+	}
+	if len(final) != 1 || final[0].Args != args {
+		t.Errorf("final tool calls = %+v, want the streamed call", final)
+	}
+}
+
+func TestOpenAI_AzureVariant(t *testing.T) {
+	var path, apiVersion, apiKey, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, apiVersion = r.URL.Path, r.URL.Query().Get("api-version")
+		apiKey, auth = r.Header.Get("api-key"), r.Header.Get("Authorization")
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIWithOptions("azure", server.URL, "secret", "gpt-4o", OpenAIOptions{Variant: VariantAzure, Deployment: "prod-4o"})
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+	if path != "/openai/deployments/prod-4o/chat/completions" || apiVersion != azureDefaultAPIVersion {
+		t.Errorf("request = %s?api-version=%s", path, apiVersion)
+	}
+	if apiKey != "secret" || auth != "" {
+		t.Errorf("api-key = %q, Authorization = %q; want key in api-key only", apiKey, auth)
+	}
+	if models, _ := p.Models(context.Background()); len(models) != 1 || models[0] != "prod-4o" {
+		t.Errorf("Models = %v, want the deployment", models)
+	}
+}
+
+func TestOpenAI_MaxCompletionTokens(t *testing.T) {
+	var raw map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw = nil
+		json.NewDecoder(r.Body).Decode(&raw)
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		model string
+		opts  OpenAIOptions
+		want  string
+	}{
+		{"o3-mini", OpenAIOptions{}, "max_completion_tokens"},
+		{"openai/gpt-5", OpenAIOptions{}, "max_completion_tokens"},
+		{"prod-deployment", OpenAIOptions{Variant: VariantAzure}, "max_completion_tokens"},
+		{"qwen2.5:14b", OpenAIOptions{}, "max_tokens"},
+		{"gpt-4o", OpenAIOptions{}, "max_tokens"},
+	}
+	for _, tt := range tests {
+		p := NewOpenAIWithOptions("test", server.URL, "key", tt.model, tt.opts)
+		ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil, WithMaxTokens(4096))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range ch {
+		}
+		other := "max_tokens"
+		if tt.want == other {
+			other = "max_completion_tokens"
+		}
+		if raw[tt.want] != float64(4096) || raw[other] != nil {
+			t.Errorf("%s: %s = %v, %s = %v; want the limit in %s", tt.model, tt.want, raw[tt.want], other, raw[other], tt.want)
+		}
+	}
+}
+
+func TestOpenAI_ExtraHeadersQueryAndBody(t *testing.T) {
+	var req map[string]any
+	var header, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, query = r.Header.Get("X-Team"), r.URL.Query().Get("tenant")
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIWithOptions("llama", server.URL, "", "local", OpenAIOptions{
+		Variant: VariantLlamaCpp,
+		Headers: map[string]string{"X-Team": "infra"},
+		Query:   map[string]string{"tenant": "a"},
+		Extra:   map[string]any{"grammar": "root ::= \"yes\"", "n_predict": 64},
+	})
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+	if header != "infra" || query != "a" {
+		t.Errorf("X-Team = %q, tenant = %q", header, query)
+	}
+	if req["grammar"] != "root ::= \"yes\"" || req["n_predict"] != float64(64) || req["model"] != "local" {
+		t.Errorf("request body = %v", req)
+	}
+	if _, ok := req["options"]; ok {
+		t.Error("Ollama options must not be sent to llama.cpp")
+	}
+}
+
+func TestOpenAI_LlamaCppModelsFallback(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	p := NewOpenAIWithOptions("llama", server.URL, "", "qwen2.5-coder", OpenAIOptions{Variant: VariantLlamaCpp})
+	models, err := p.Models(context.Background())
+	if err != nil || len(models) != 1 || models[0] != "qwen2.5-coder" {
+		t.Errorf("Models = %v, %v; want the configured model", models, err)
+	}
+}
+
+func TestOpenAI_LMStudioJSONMode(t *testing.T) {
+	var req map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIWithOptions("lms", server.URL, "", "local", OpenAIOptions{Variant: VariantLMStudio})
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil, WithJSONMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+	rf, _ := req["response_format"].(map[string]any)
+	if rf["type"] != "json_schema" {
+		t.Errorf("response_format = %v, want json_schema", req["response_format"])
+	}
+}
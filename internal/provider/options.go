@@ -0,0 +1,96 @@
+package provider
+
+import "context"
+
+// ChatOptions holds per-request generation parameters.
+// Nil or zero fields leave the provider/server default in place.
+type ChatOptions struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int // Maximum output tokens
+	Stop        []string
+	Seed        *int
+
+	// ResponseFormat constrains the output to JSON (optionally schema-checked).
+	// Providers that can't enforce it ignore it; callers should still tolerate
+	// loosely formatted JSON.
+	ResponseFormat *ResponseFormat
+}
+
+// ResponseFormat describes the structured output a caller expects.
+type ResponseFormat struct {
+	Name   string // Short identifier, e.g. "intent"; used as the schema/tool name
+	Schema any    // JSON Schema object; nil means any JSON object
+}
+
+// ChatOption configures a single Chat call.
+type ChatOption func(*ChatOptions)
+
+func WithTemperature(t float64) ChatOption {
+	return func(o *ChatOptions) { o.Temperature = &t }
+}
+
+func WithTopP(p float64) ChatOption {
+	return func(o *ChatOptions) { o.TopP = &p }
+}
+
+func WithMaxTokens(n int) ChatOption {
+	return func(o *ChatOptions) { o.MaxTokens = n }
+}
+
+func WithStop(seqs ...string) ChatOption {
+	return func(o *ChatOptions) { o.Stop = append([]string(nil), seqs...) }
+}
+
+func WithSeed(seed int) ChatOption {
+	return func(o *ChatOptions) { o.Seed = &seed }
+}
+
+// WithResponseSchema requests output that is a JSON document matching schema.
+func WithResponseSchema(name string, schema any) ChatOption {
+	return func(o *ChatOptions) { o.ResponseFormat = &ResponseFormat{Name: name, Schema: schema} }
+}
+
+// WithJSONMode requests a JSON object without enforcing a particular schema.
+func WithJSONMode() ChatOption {
+	return func(o *ChatOptions) { o.ResponseFormat = &ResponseFormat{Name: "json_output"} }
+}
+
+type chatOptionsKey struct{}
+
+// WithChatOptions returns a context carrying options that override those passed
+// to Chat. It lets a caller constrain everything beneath it (e.g. a critic
+// sub-agent spawned through types.AgentSpawner) without plumbing options through.
+func WithChatOptions(ctx context.Context, opts ...ChatOption) context.Context {
+	existing, _ := ctx.Value(chatOptionsKey{}).([]ChatOption)
+	merged := append(append([]ChatOption(nil), existing...), opts...)
+	return context.WithValue(ctx, chatOptionsKey{}, merged)
+}
+
+// ResolveChatOptions applies the explicit options, then any context overrides.
+func ResolveChatOptions(ctx context.Context, opts []ChatOption) ChatOptions {
+	var o ChatOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if ctxOpts, ok := ctx.Value(chatOptionsKey{}).([]ChatOption); ok {
+		for _, opt := range ctxOpts {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+type callSourceKey struct{}
+
+// WithCallSource labels the model calls made under ctx (e.g. "validator",
+// "judge") so usage can be attributed to the part of the app that made them.
+func WithCallSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, callSourceKey{}, source)
+}
+
+// CallSource returns the label set by WithCallSource, or "".
+func CallSource(ctx context.Context) string {
+	s, _ := ctx.Value(callSourceKey{}).(string)
+	return s
+}
@@ -1,6 +1,9 @@
 package provider
 
-import "context"
+import (
+	"context"
+	"encoding/base64"
+)
 
 type Role string
 
@@ -12,16 +15,69 @@ const (
 )
 
 type Message struct {
-	Role       Role       `json:"role"`
-	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Role       Role          `json:"role"`
+	Content    string        `json:"content"`
+	Parts      []ContentPart `json:"parts,omitempty"` // Extra non-text content sent after Content
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+
+	// ThinkingBlocks holds the signed reasoning behind an assistant turn.
+	// Anthropic requires it to be sent back alongside tool_use blocks when
+	// extended thinking is enabled.
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
+}
+
+// ThinkingBlock is one block of provider-signed reasoning. Redacted blocks
+// carry only opaque Data.
+type ThinkingBlock struct {
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // Set for redacted_thinking blocks
+}
+
+type PartType string
+
+const (
+	PartText  PartType = "text"
+	PartImage PartType = "image"
+)
+
+// ContentPart is a typed piece of multimodal message content.
+// Images carry either raw Data or a URL, plus their MIME type.
+type ContentPart struct {
+	Type     PartType `json:"type"`
+	Text     string   `json:"text,omitempty"`
+	MIMEType string   `json:"mime_type,omitempty"`
+	Data     []byte   `json:"data,omitempty"` // base64-encoded when serialized
+	URL      string   `json:"url,omitempty"`
+}
+
+// ImagePart builds an inline image part from raw bytes.
+func ImagePart(mimeType string, data []byte) ContentPart {
+	return ContentPart{Type: PartImage, MIMEType: mimeType, Data: data}
+}
+
+// DataURL returns the image as a data: URL, or URL if the part is remote.
+func (p ContentPart) DataURL() string {
+	if p.URL != "" {
+		return p.URL
+	}
+	return "data:" + p.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// Base64 returns the inline image data base64-encoded.
+func (p ContentPart) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Data)
 }
 
 type ToolCall struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 	Args string `json:"arguments"`
+
+	// Signature is opaque provider state that must be sent back with the
+	// call, such as Gemini's thoughtSignature.
+	Signature string `json:"signature,omitempty"`
 }
 
 type ToolDef struct {
@@ -32,9 +88,11 @@ type ToolDef struct {
 
 // Usage tracks token consumption for a request
 type Usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
-	TotalTokens  int `json:"total_tokens"`
+	InputTokens       int `json:"input_tokens"` // All prompt tokens, including cached ones
+	OutputTokens      int `json:"output_tokens"`
+	TotalTokens       int `json:"total_tokens"`
+	CachedInputTokens int `json:"cached_input_tokens,omitempty"` // Prompt tokens served from the provider's cache
+	CacheWriteTokens  int `json:"cache_write_tokens,omitempty"`  // Prompt tokens written to the cache (billed at a premium)
 }
 
 type StreamChunk struct {
@@ -43,12 +101,47 @@ type StreamChunk struct {
 	ToolCalls []ToolCall
 	Done      bool
 	Error     error
-	Usage     *Usage // Token usage (populated in final chunk when Done=true)
+	Usage     *Usage     // Token usage (populated in final chunk when Done=true)
+	Route     *RouteInfo // Set on the first chunk when a RouterProvider picked the backend
+
+	// ThinkingBlocks is set on the final chunk when the provider returns
+	// signed reasoning that must be replayed in later turns.
+	ThinkingBlocks []ThinkingBlock
+
+	// ToolCallDelta is a fragment of a tool call still being generated. The
+	// complete calls are still delivered in ToolCalls on the final chunk.
+	ToolCallDelta *ToolCallDelta
+
+	// Restart means the stream failed part-way and the request is being
+	// reissued: discard any Delta/Thinking received so far.
+	Restart *StreamRestart
+}
+
+// ToolCallDelta carries part of a tool call's arguments as they stream in.
+// Fragments of the same call share an Index (its position among the
+// response's calls); ID and Name may only be set on the first fragment.
+type ToolCallDelta struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Args  string `json:"args,omitempty"`
+}
+
+// StreamRestart describes a transparent mid-stream retry.
+type StreamRestart struct {
+	Attempt int   // 1 for the first retry
+	Reason  error // The transient error that interrupted the stream
 }
 
 type Provider interface {
-	Chat(ctx context.Context, msgs []Message, tools []ToolDef) (<-chan StreamChunk, error)
+	Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error)
 	Name() string
 	ModelName() string // Returns the model name for capability detection
 	Models(ctx context.Context) ([]string, error)
 }
+
+// toolAttachmentCaption labels images re-sent on behalf of a tool result for
+// APIs whose tool messages can't carry images.
+func toolAttachmentCaption(toolCallID string) string {
+	return "Attachments from tool result " + toolCallID + ":"
+}
@@ -0,0 +1,220 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit caps how hard the client drives one backend. Zero fields are
+// unlimited.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int // Prompt plus completion tokens
+	MaxInFlight       int // Concurrent streams
+}
+
+// Enabled reports whether any limit is set.
+func (l RateLimit) Enabled() bool {
+	return l.RequestsPerMinute > 0 || l.TokensPerMinute > 0 || l.MaxInFlight > 0
+}
+
+// Limiter enforces a RateLimit. One Limiter should be shared by every
+// provider instance that talks to the same backend, so sub-agents, parallel
+// tool calls and the validator all draw from the same budget.
+type Limiter struct {
+	requests *bucket
+	tokens   *bucket
+	inFlight chan struct{}
+}
+
+func NewLimiter(l RateLimit) *Limiter {
+	lim := &Limiter{}
+	if l.RequestsPerMinute > 0 {
+		lim.requests = newBucket(l.RequestsPerMinute)
+	}
+	if l.TokensPerMinute > 0 {
+		lim.tokens = newBucket(l.TokensPerMinute)
+	}
+	if l.MaxInFlight > 0 {
+		lim.inFlight = make(chan struct{}, l.MaxInFlight)
+	}
+	return lim
+}
+
+var (
+	sharedLimitersMu sync.Mutex
+	sharedLimiters   = map[string]*Limiter{}
+)
+
+// SharedLimiter returns the process-wide limiter for key, creating it from l
+// on first use. Later calls with the same key get the same limiter whatever
+// l they pass.
+func SharedLimiter(key string, l RateLimit) *Limiter {
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+	if lim, ok := sharedLimiters[key]; ok {
+		return lim
+	}
+	lim := NewLimiter(l)
+	sharedLimiters[key] = lim
+	return lim
+}
+
+// acquire blocks until a request estimated at promptTokens may start. The
+// returned release must be called when the request finishes.
+func (l *Limiter) acquire(ctx context.Context, promptTokens int) (release func(), err error) {
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+	}
+	if err := l.requests.wait(ctx, 1); err != nil {
+		release()
+		return nil, err
+	}
+	if err := l.tokens.wait(ctx, promptTokens); err != nil {
+		l.requests.refund(1)
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// bucket is a token bucket refilled continuously at capacity per minute.
+// Takers may drive it negative: a request that doesn't fit reserves its
+// tokens and waits out the deficit, so waiters are served in arrival order.
+type bucket struct {
+	mu       sync.Mutex
+	capacity float64
+	perSec   float64
+	level    float64
+	last     time.Time
+}
+
+func newBucket(perMinute int) *bucket {
+	return &bucket{
+		capacity: float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		level:    float64(perMinute),
+		last:     time.Now(),
+	}
+}
+
+// reserve takes n tokens and returns how long to wait before using them.
+func (b *bucket) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*b.perSec)
+	b.last = now
+
+	// A single request larger than the whole budget would never fit; let it
+	// through once the bucket is full.
+	need := min(float64(n), b.capacity)
+	b.level -= need
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / b.perSec * float64(time.Second))
+}
+
+// wait reserves n tokens and sleeps until they are available. A nil bucket
+// never waits.
+func (b *bucket) wait(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	d := b.reserve(n)
+	if d == 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		b.refund(n)
+		return ctx.Err()
+	}
+}
+
+// refund returns tokens that were reserved but not used, or takes more when
+// n is negative (a request used more than estimated).
+func (b *bucket) refund(n int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.level = min(b.capacity, b.level+min(float64(n), b.capacity))
+}
+
+// RateLimitedProvider holds each Chat call until its Limiter allows it. The
+// in-flight slot is held until the stream is fully consumed.
+type RateLimitedProvider struct {
+	inner   Provider
+	limiter *Limiter
+}
+
+// WithRateLimit wraps p so that its calls are governed by limiter.
+func WithRateLimit(p Provider, limiter *Limiter) *RateLimitedProvider {
+	return &RateLimitedProvider{inner: p, limiter: limiter}
+}
+
+func (r *RateLimitedProvider) Name() string { return r.inner.Name() }
+
+func (r *RateLimitedProvider) ModelName() string { return r.inner.ModelName() }
+
+func (r *RateLimitedProvider) Models(ctx context.Context) ([]string, error) {
+	return r.inner.Models(ctx)
+}
+
+func (r *RateLimitedProvider) Unwrap() Provider { return r.inner }
+
+func (r *RateLimitedProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	estimate := estimatePromptTokens(msgs, tools)
+	release, err := r.limiter.acquire(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := r.inner.Chat(ctx, msgs, tools, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 64)
+	go func() {
+		defer close(out)
+		defer release()
+		settled := false
+		for chunk := range ch {
+			// Settle the token budget against what was actually used.
+			if u := chunk.Usage; u != nil && !settled && u.InputTokens+u.OutputTokens > 0 {
+				r.limiter.tokens.refund(estimate - u.InputTokens - u.OutputTokens)
+				settled = true
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				// The caller has gone; free the slot rather than block on a
+				// reader that will never come back.
+				go func() {
+					for range ch {
+					}
+				}()
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// gatedProvider streams one chunk per call and holds the stream open until
+// release is closed.
+type gatedProvider struct {
+	stubProvider
+	release chan struct{}
+}
+
+func (g *gatedProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk, 1)
+	go func() {
+		defer close(ch)
+		<-g.release
+		ch <- StreamChunk{Done: true, Usage: &Usage{InputTokens: 10, OutputTokens: 5}}
+	}()
+	return ch, nil
+}
+
+func TestRateLimit_MaxInFlight(t *testing.T) {
+	inner := &gatedProvider{stubProvider: stubProvider{name: "ollama"}, release: make(chan struct{})}
+	lim := NewLimiter(RateLimit{MaxInFlight: 1})
+	a, b := WithRateLimit(inner, lim), WithRateLimit(inner, lim)
+
+	first, err := a.Chat(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second wrapper sharing the limiter must wait for the first stream.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := b.Chat(ctx, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second call err = %v, want it to block until the deadline", err)
+	}
+
+	close(inner.release)
+	for range first {
+	}
+	second, err := b.Chat(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("slot not released after the stream ended: %v", err)
+	}
+	for range second {
+	}
+}
+
+func TestBucket_Reserve(t *testing.T) {
+	b := newBucket(60) // one per second
+	if d := b.reserve(60); d != 0 {
+		t.Errorf("full bucket should not wait, got %v", d)
+	}
+	if d := b.reserve(1); d < 900*time.Millisecond || d > 1100*time.Millisecond {
+		t.Errorf("wait = %v, want ~1s", d)
+	}
+	if d := b.reserve(1); d < 1900*time.Millisecond {
+		t.Errorf("queued waiters should be served in order, got %v", d)
+	}
+
+	// Requests larger than the budget still get through.
+	big := newBucket(100)
+	if d := big.reserve(1000); d != 0 {
+		t.Errorf("oversized request on a full bucket waited %v", d)
+	}
+}
+
+func TestBucket_CancelRefunds(t *testing.T) {
+	b := newBucket(60)
+	b.reserve(60)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.wait(ctx, 30); err == nil {
+		t.Fatal("expected cancellation")
+	}
+	if d := b.reserve(1); d > 1100*time.Millisecond {
+		t.Errorf("cancelled reservation was not refunded: wait %v", d)
+	}
+}
+
+func TestSharedLimiter(t *testing.T) {
+	a := SharedLimiter("test-shared", RateLimit{MaxInFlight: 2})
+	if SharedLimiter("test-shared", RateLimit{MaxInFlight: 5}) != a {
+		t.Error("same key should return the same limiter")
+	}
+	if SharedLimiter("test-other", RateLimit{MaxInFlight: 2}) == a {
+		t.Error("different keys should not share")
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"strings"
 	"time"
 )
 
+// maxRetryAfter caps how long RetryProvider will wait when a server asks for a
+// delay; anything longer is returned to the caller instead of blocking a turn.
+const maxRetryAfter = 60 * time.Second
+
 // RetryProvider wraps a Provider with exponential backoff retry logic.
 type RetryProvider struct {
 	inner      Provider
@@ -26,6 +31,8 @@ func (r *RetryProvider) Name() string { return r.inner.Name() }
 
 func (r *RetryProvider) ModelName() string { return r.inner.ModelName() }
 
+func (r *RetryProvider) Unwrap() Provider { return r.inner }
+
 func (r *RetryProvider) Models(ctx context.Context) ([]string, error) {
 	var lastErr error
 	for attempt := 0; attempt <= r.maxRetries; attempt++ {
@@ -37,18 +44,86 @@ func (r *RetryProvider) Models(ctx context.Context) ([]string, error) {
 		if !r.isRetryable(err) || attempt == r.maxRetries {
 			break
 		}
-		if err := r.backoff(ctx, attempt); err != nil {
+		if err := r.backoff(ctx, attempt, err); err != nil {
 			return nil, lastErr
 		}
 	}
 	return nil, lastErr
 }
 
-func (r *RetryProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef) (<-chan StreamChunk, error) {
+// Chat retries failures to start the stream and also transient failures part
+// way through it (connection resets, overload events). A mid-stream retry
+// reissues the whole request and sends a Restart chunk first so consumers
+// can drop the partial output.
+func (r *RetryProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	in, err := r.open(ctx, msgs, tools, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan StreamChunk, 64)
+	go r.relay(ctx, in, out, func() (<-chan StreamChunk, error) {
+		return r.open(ctx, msgs, tools, opts)
+	})
+	return out, nil
+}
+
+func (r *RetryProvider) relay(ctx context.Context, in <-chan StreamChunk, out chan<- StreamChunk, reopen func() (<-chan StreamChunk, error)) {
+	defer close(out)
+	send := func(chunk StreamChunk) bool {
+		select {
+		case out <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		var streamErr error
+		for chunk := range in {
+			if chunk.Error != nil {
+				streamErr = chunk.Error
+				break
+			}
+			if !send(chunk) {
+				return
+			}
+		}
+		if streamErr == nil {
+			return
+		}
+		// Let the abandoned stream finish in the background.
+		go func(ch <-chan StreamChunk) {
+			for range ch {
+			}
+		}(in)
+
+		if attempt >= r.maxRetries || ctx.Err() != nil || !r.isRetryable(streamErr) {
+			send(StreamChunk{Error: streamErr, Done: true})
+			return
+		}
+		if err := r.backoff(ctx, attempt, streamErr); err != nil {
+			send(StreamChunk{Error: streamErr, Done: true})
+			return
+		}
+		next, err := reopen()
+		if err != nil {
+			send(StreamChunk{Error: err, Done: true})
+			return
+		}
+		if !send(StreamChunk{Restart: &StreamRestart{Attempt: attempt + 1, Reason: streamErr}}) {
+			return
+		}
+		in = next
+	}
+}
+
+// open starts a stream, retrying errors returned before any output.
+func (r *RetryProvider) open(ctx context.Context, msgs []Message, tools []ToolDef, opts []ChatOption) (<-chan StreamChunk, error) {
 	var lastErr error
 	var attempt int
 	for attempt = 0; attempt <= r.maxRetries; attempt++ {
-		ch, err := r.inner.Chat(ctx, msgs, tools)
+		ch, err := r.inner.Chat(ctx, msgs, tools, opts...)
 		if err == nil {
 			return ch, nil
 		}
@@ -56,7 +131,7 @@ func (r *RetryProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDe
 		if !r.isRetryable(err) || attempt == r.maxRetries {
 			break
 		}
-		if err := r.backoff(ctx, attempt); err != nil {
+		if err := r.backoff(ctx, attempt, err); err != nil {
 			return nil, lastErr
 		}
 	}
@@ -64,6 +139,10 @@ func (r *RetryProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDe
 }
 
 func (r *RetryProvider) isRetryable(err error) bool {
+	if pe, ok := AsError(err); ok {
+		return pe.Retryable && pe.RetryAfter <= maxRetryAfter
+	}
+	// Untyped errors (e.g. from wrapped or test providers): fall back to the message.
 	msg := err.Error()
 	// Retry on rate limits, server errors, connection issues
 	for _, s := range []string{"429", "500", "502", "503", "529", "connection refused", "timeout", "deadline exceeded", "EOF", "reset by peer"} {
@@ -74,11 +153,8 @@ func (r *RetryProvider) isRetryable(err error) bool {
 	return false
 }
 
-func (r *RetryProvider) backoff(ctx context.Context, attempt int) error {
-	delay := time.Duration(float64(r.baseDelay) * math.Pow(2, float64(attempt)))
-	if delay > 30*time.Second {
-		delay = 30 * time.Second
-	}
+func (r *RetryProvider) backoff(ctx context.Context, attempt int, err error) error {
+	delay := r.delay(attempt, err)
 	select {
 	case <-time.After(delay):
 		return nil
@@ -86,3 +162,17 @@ func (r *RetryProvider) backoff(ctx context.Context, attempt int) error {
 		return ctx.Err()
 	}
 }
+
+// delay honors a server-provided Retry-After, padded by up to 10% so parallel
+// agents don't all come back at once. Otherwise it uses exponential backoff
+// with jitter in [delay/2, delay).
+func (r *RetryProvider) delay(attempt int, err error) time.Duration {
+	if pe, ok := AsError(err); ok && pe.RetryAfter > 0 {
+		return pe.RetryAfter + rand.N(pe.RetryAfter/10+1)
+	}
+	delay := time.Duration(float64(r.baseDelay) * math.Pow(2, float64(attempt)))
+	if delay > 30*time.Second {
+		delay = 30 * time.Second
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryProvider_HonorsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("retry-after-ms", "50")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"rate limited","code":"rate_limit_exceeded"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := WithRetry(NewOpenAI("test", server.URL, "", "model"), 2)
+	p.baseDelay = time.Hour // Must not be used when the server gives a delay
+
+	start := time.Now()
+	if _, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("retry waited %v, want ~50ms", elapsed)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestRetryProvider_NonRetryableTypedError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// The message mentions 500 but the status says this is a client error
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"max_tokens must be below 500"}}`))
+	}))
+	defer server.Close()
+
+	p := WithRetry(NewOpenAI("test", server.URL, "", "model"), 3)
+	_, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	var pe *Error
+	if !errors.As(err, &pe) || pe.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected typed 400 error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1 (no retries)", calls.Load())
+	}
+}
+
+func TestRetryProvider_MidStreamRetry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls.Add(1) == 1 {
+			w.Write([]byte(`data: {"choices":[{"delta":{"content":"partial answer that is long enough"}}]}` + "\n\n"))
+			w.Write([]byte(`data: {"error":{"message":"model overloaded","code":503}}` + "\n\n"))
+			return
+		}
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"complete answer, no tags"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	p := WithRetry(NewOpenAI("test", server.URL, "", "model"), 2)
+	p.baseDelay = time.Millisecond
+
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	var text string
+	var restarts int
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Error)
+		}
+		if chunk.Restart != nil {
+			restarts++
+			text = ""
+		}
+		text += chunk.Delta
+	}
+	if restarts != 1 || text != "complete answer, no tags" {
+		t.Errorf("restarts=%d text=%q", restarts, text)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestRetryProvider_MidStreamNonRetryable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"error":{"message":"bad request","type":"invalid_request_error"}}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	p := WithRetry(NewOpenAI("test", server.URL, "", "model"), 2)
+	ch, err := p.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	var streamErr error
+	for chunk := range ch {
+		if chunk.Restart != nil {
+			t.Error("non-retryable stream error must not be retried")
+		}
+		if chunk.Error != nil {
+			streamErr = chunk.Error
+		}
+	}
+	if pe, ok := AsError(streamErr); !ok || pe.Code != "invalid_request_error" {
+		t.Errorf("expected typed stream error, got %v", streamErr)
+	}
+}
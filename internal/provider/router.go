@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Route is one backend in a RouterProvider chain, with the rules that decide
+// whether a request may be sent to it.
+type Route struct {
+	Provider   Provider
+	MaxContext int  // Skip when the prompt estimate exceeds this many tokens (0 = unlimited)
+	NoTools    bool // Backend can't call tools; skip requests that offer tools
+	CostTier   int  // 1 = local/free, higher = more expensive (0 is treated as 1)
+}
+
+// RouteInfo reports which backend served a request.
+type RouteInfo struct {
+	Provider string
+	Model    string
+	Skipped  []string // Backends passed over before this one, with the reason
+}
+
+// Fallback reports whether the request was served by something other than
+// the first backend in the chain.
+func (ri RouteInfo) Fallback() bool { return len(ri.Skipped) > 0 }
+
+func (ri RouteInfo) String() string {
+	s := ri.Provider + " / " + ri.Model
+	if ri.Fallback() {
+		s += " (skipped " + strings.Join(ri.Skipped, ", ") + ")"
+	}
+	return s
+}
+
+// RouterProvider sends each request to the first backend whose rules allow it,
+// falling back down the chain when a backend is down, overloaded or rejects
+// the prompt as too long. The chosen backend is announced in the first chunk
+// of the stream (StreamChunk.Route).
+type RouterProvider struct {
+	routes      []Route
+	maxCostTier int
+}
+
+// NewRouter builds a router over routes in order of preference. maxCostTier
+// caps which backends may be used (0 = no cap).
+func NewRouter(routes []Route, maxCostTier int) *RouterProvider {
+	return &RouterProvider{routes: routes, maxCostTier: maxCostTier}
+}
+
+// Name and ModelName report the primary backend, which is what capability
+// detection should be based on.
+func (r *RouterProvider) Name() string { return r.routes[0].Provider.Name() }
+
+func (r *RouterProvider) ModelName() string { return r.routes[0].Provider.ModelName() }
+
+// Unwrap returns the primary backend, so that capability lookups such as
+// DescribeModel reach the backend that Name and ModelName describe.
+func (r *RouterProvider) Unwrap() Provider { return r.routes[0].Provider }
+
+func (r *RouterProvider) Models(ctx context.Context) ([]string, error) {
+	var lastErr error
+	for _, rt := range r.routes {
+		models, err := rt.Provider.Models(ctx)
+		if err == nil {
+			return models, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (r *RouterProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	promptTokens := estimatePromptTokens(msgs, tools)
+
+	var skipped []string
+	var lastErr error
+	for _, rt := range r.routes {
+		name := rt.Provider.Name()
+		if reason := r.skipReason(rt, promptTokens, len(tools) > 0); reason != "" {
+			skipped = append(skipped, name+": "+reason)
+			continue
+		}
+
+		ch, err := rt.Provider.Chat(ctx, msgs, tools, opts...)
+		if err != nil {
+			if ctx.Err() != nil || !isFallbackError(err) {
+				return nil, err
+			}
+			lastErr = err
+			skipped = append(skipped, name+": "+fallbackReason(err))
+			continue
+		}
+
+		info := &RouteInfo{Provider: name, Model: rt.Provider.ModelName(), Skipped: skipped}
+		return announceRoute(ctx, ch, info), nil
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("all backends failed (%s): %w", strings.Join(skipped, "; "), lastErr)
+	}
+	return nil, fmt.Errorf("no backend can serve this request (%s)", strings.Join(skipped, "; "))
+}
+
+func (r *RouterProvider) skipReason(rt Route, promptTokens int, needsTools bool) string {
+	tier := rt.CostTier
+	if tier == 0 {
+		tier = 1
+	}
+	switch {
+	case r.maxCostTier > 0 && tier > r.maxCostTier:
+		return fmt.Sprintf("cost tier %d", tier)
+	case rt.MaxContext > 0 && promptTokens > rt.MaxContext:
+		return fmt.Sprintf("context ~%d > %d", promptTokens, rt.MaxContext)
+	case needsTools && rt.NoTools:
+		return "no tool support"
+	}
+	return ""
+}
+
+// announceRoute prefixes the stream with a chunk naming the serving backend.
+func announceRoute(ctx context.Context, in <-chan StreamChunk, info *RouteInfo) <-chan StreamChunk {
+	out := make(chan StreamChunk, 64)
+	go func() {
+		defer close(out)
+		out <- StreamChunk{Route: info}
+		for chunk := range in {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				go func() {
+					for range in {
+					}
+				}()
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// estimatePromptTokens gives a rough prompt size (~4 chars per token).
+func estimatePromptTokens(msgs []Message, tools []ToolDef) int {
+	chars := 0
+	for _, m := range msgs {
+		chars += len(m.Content)
+		for _, tc := range m.ToolCalls {
+			chars += len(tc.Args)
+		}
+	}
+	for _, t := range tools {
+		chars += len(t.Description) + 200 // Parameter schemas are roughly constant
+	}
+	return chars / 4
+}
+
+// contextErrorPhrases identify a backend rejecting the prompt as too long;
+// another backend with a larger window may still accept it.
+var contextErrorPhrases = []string{
+	"context length", "context window", "maximum context", "prompt is too long", "too many tokens", "413",
+}
+
+var unavailableErrorPhrases = []string{
+	"connection refused", "no such host", "timeout", "deadline exceeded", "EOF", "reset by peer",
+	"500", "502", "503", "504", "529", "temporarily unavailable", "overloaded", "internal server error",
+	"429", "rate limited",
+}
+
+// IsContextLengthError reports whether err is a backend rejecting a prompt
+// as too long for the model's context window.
+func IsContextLengthError(err error) bool {
+	return err != nil && fallbackReason(err) == "context too large"
+}
+
+// isFallbackError reports whether err means another backend should be tried.
+func isFallbackError(err error) bool {
+	return fallbackReason(err) != ""
+}
+
+func fallbackReason(err error) string {
+	if pe, ok := AsError(err); ok {
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			return "circuit open"
+		case pe.StatusCode == 413 || pe.Code == "context_length_exceeded":
+			return "context too large"
+		case pe.Retryable:
+			return "unavailable"
+		}
+	}
+	msg := err.Error()
+	lower := strings.ToLower(msg)
+	for _, s := range contextErrorPhrases {
+		if strings.Contains(lower, s) {
+			return "context too large"
+		}
+	}
+	for _, s := range unavailableErrorPhrases {
+		if strings.Contains(msg, s) || strings.Contains(lower, s) {
+			return "unavailable"
+		}
+	}
+	return ""
+}
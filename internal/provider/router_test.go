@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (s *stubProvider) Name() string      { return s.name }
+func (s *stubProvider) ModelName() string { return s.name + "-model" }
+func (s *stubProvider) Models(ctx context.Context) ([]string, error) {
+	return []string{s.ModelName()}, nil
+}
+
+func (s *stubProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Delta: "hi from " + s.name}
+	ch <- StreamChunk{Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func collectRoute(t *testing.T, ch <-chan StreamChunk) (*RouteInfo, string) {
+	t.Helper()
+	var route *RouteInfo
+	var text string
+	for chunk := range ch {
+		if chunk.Route != nil {
+			route = chunk.Route
+		}
+		text += chunk.Delta
+	}
+	if route == nil {
+		t.Fatal("stream did not announce a route")
+	}
+	return route, text
+}
+
+func TestRouter_FallsBackWhenUnavailable(t *testing.T) {
+	local := &stubProvider{name: "ollama", err: errors.New("provider ollama: dial tcp: connection refused")}
+	vllm := &stubProvider{name: "vllm", err: errors.New("provider vllm: provider service temporarily unavailable")}
+	cloud := &stubProvider{name: "anthropic"}
+
+	r := NewRouter([]Route{{Provider: local}, {Provider: vllm}, {Provider: cloud, CostTier: 2}}, 0)
+	ch, err := r.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	route, text := collectRoute(t, ch)
+	if route.Provider != "anthropic" || !route.Fallback() || len(route.Skipped) != 2 {
+		t.Errorf("unexpected route: %+v", route)
+	}
+	if text != "hi from anthropic" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestRouter_NonFallbackErrorStops(t *testing.T) {
+	local := &stubProvider{name: "ollama", err: errors.New("provider ollama: authentication failed — check your API key")}
+	cloud := &stubProvider{name: "anthropic"}
+
+	r := NewRouter([]Route{{Provider: local}, {Provider: cloud}}, 0)
+	if _, err := r.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hi"}}, nil); err == nil {
+		t.Fatal("expected the auth error to be returned")
+	}
+	if cloud.calls != 0 {
+		t.Error("fallback should not be tried for non-transient errors")
+	}
+}
+
+func TestRouter_Rules(t *testing.T) {
+	small := &stubProvider{name: "small"}
+	textOnly := &stubProvider{name: "text-only"}
+	pricey := &stubProvider{name: "pricey"}
+	big := &stubProvider{name: "big"}
+
+	r := NewRouter([]Route{
+		{Provider: small, MaxContext: 10},
+		{Provider: textOnly, NoTools: true},
+		{Provider: pricey, CostTier: 3},
+		{Provider: big, CostTier: 2},
+	}, 2)
+
+	msgs := []Message{{Role: RoleUser, Content: strings.Repeat("x", 400)}}
+	tools := []ToolDef{{Name: "bash"}}
+	ch, err := r.Chat(context.Background(), msgs, tools)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	route, _ := collectRoute(t, ch)
+	if route.Provider != "big" {
+		t.Errorf("served by %s, want big (skipped: %v)", route.Provider, route.Skipped)
+	}
+	if small.calls+textOnly.calls+pricey.calls != 0 {
+		t.Error("rule-skipped backends should not be called")
+	}
+
+	// Without tools, the text-only backend is eligible
+	ch, err = r.Chat(context.Background(), msgs, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if route, _ = collectRoute(t, ch); route.Provider != "text-only" {
+		t.Errorf("served by %s, want text-only", route.Provider)
+	}
+}
+
+// stubbornProvider streams many chunks without watching the context, and
+// closes finished once it has sent them all.
+type stubbornProvider struct {
+	stubProvider
+	finished chan struct{}
+}
+
+func (s *stubbornProvider) Chat(ctx context.Context, msgs []Message, tools []ToolDef, opts ...ChatOption) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(s.finished)
+		defer close(ch)
+		for i := 0; i < 200; i++ {
+			ch <- StreamChunk{Delta: "x"}
+		}
+		ch <- StreamChunk{Done: true}
+	}()
+	return ch, nil
+}
+
+func TestRouter_CancelDrainsBackend(t *testing.T) {
+	backend := &stubbornProvider{stubProvider: stubProvider{name: "ollama"}, finished: make(chan struct{})}
+	r := NewRouter([]Route{{Provider: backend}}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := r.Chat(ctx, []Message{{Role: RoleUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	<-ch
+	cancel() // and never read again
+
+	select {
+	case <-backend.finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the backend's stream was left blocked after the caller cancelled")
+	}
+}
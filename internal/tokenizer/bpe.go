@@ -0,0 +1,334 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BPE is a byte-pair-encoding tokenizer in the style of OpenAI's tiktoken.
+// Text is first split with the encoding's pre-tokenizer, then each piece is
+// merged using the encoding's rank table.
+//
+// Rank tables are large, so they aren't bundled. When
+// ~/.config/aseity/tokenizers/<name>.tiktoken exists it is used and counts
+// are exact; otherwise each piece is estimated from its shape, which is
+// still far closer than a flat chars/4 for code and non-English text.
+type BPE struct {
+	name  string
+	o200k bool // Use the o200k pre-tokenizer rules
+
+	// nonASCIIBytesPerToken is used to estimate pieces outside ASCII when no
+	// rank table is loaded. o200k's larger vocabulary covers far more
+	// non-Latin scripts than cl100k.
+	nonASCIIBytesPerToken float64
+
+	once  sync.Once
+	ranks map[string]int
+}
+
+var (
+	cl100k = &BPE{name: "cl100k_base", nonASCIIBytesPerToken: 2.5}
+	o200k  = &BPE{name: "o200k_base", o200k: true, nonASCIIBytesPerToken: 3.5}
+)
+
+// Cl100k returns the encoding used by GPT-4 and GPT-3.5.
+func Cl100k() *BPE { return cl100k }
+
+// O200k returns the encoding used by GPT-4o and the o-series.
+func O200k() *BPE { return o200k }
+
+// NewBPE builds a tokenizer from a tiktoken rank table. o200kRules selects
+// the o200k pre-tokenizer instead of cl100k's.
+func NewBPE(name string, ranks map[string]int, o200kRules bool) *BPE {
+	b := &BPE{name: name, o200k: o200kRules, ranks: ranks, nonASCIIBytesPerToken: 2.5}
+	b.once.Do(func() {})
+	return b
+}
+
+func (b *BPE) Name() string { return b.name }
+
+// Exact reports whether a rank table is loaded.
+func (b *BPE) Exact() bool {
+	b.load()
+	return b.ranks != nil
+}
+
+func (b *BPE) Count(text string) int {
+	b.load()
+	n := 0
+	for _, piece := range b.split(text) {
+		if b.ranks != nil {
+			n += b.mergeCount(piece)
+		} else {
+			n += b.estimatePiece(piece)
+		}
+	}
+	return n
+}
+
+// load reads the rank table from the tokenizers directory, once.
+func (b *BPE) load() {
+	b.once.Do(func() {
+		f, err := os.Open(filepath.Join(tokenizerDir(), b.name+".tiktoken"))
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		if ranks, err := LoadRanks(f); err == nil {
+			b.ranks = ranks
+		} else {
+			fmt.Fprintf(os.Stderr, "tokenizer: ignoring %s: %v\n", f.Name(), err)
+		}
+	})
+}
+
+func tokenizerDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "aseity", "tokenizers")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "aseity", "tokenizers")
+}
+
+// LoadRanks parses a tiktoken rank file: one "<base64 token> <rank>" per line.
+func LoadRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<token> <rank>\"", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
+
+// mergeCount applies byte-pair merges to one piece, lowest rank first, and
+// returns the number of tokens left.
+func (b *BPE) mergeCount(piece string) int {
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+	parts := make([]string, len(piece))
+	for i := range piece {
+		parts[i] = piece[i : i+1]
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-1; i++ {
+			if r, ok := b.ranks[parts[i]+parts[i+1]]; ok && r < bestRank {
+				best, bestRank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return len(parts)
+}
+
+// estimatePiece guesses the token count of one pre-tokenized piece without
+// a rank table. Short words, numbers and indentation are almost always a
+// single token; longer words and symbol runs split predictably.
+func (b *BPE) estimatePiece(piece string) int {
+	ascii := true
+	letters, digits, spaces := 0, 0, 0
+	for i := 0; i < len(piece); i++ {
+		c := piece[i]
+		switch {
+		case c >= utf8.RuneSelf:
+			ascii = false
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			letters++
+		case c >= '0' && c <= '9':
+			digits++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			spaces++
+		}
+	}
+	n := len(piece)
+	switch {
+	case !ascii:
+		return int(math.Ceil(float64(n) / b.nonASCIIBytesPerToken))
+	case letters > 0:
+		return ceilDiv(letters, 6)
+	case digits > 0:
+		return 1
+	case spaces == n:
+		return ceilDiv(n, 16)
+	default:
+		return ceilDiv(n-spaces, 2)
+	}
+}
+
+func ceilDiv(a, b int) int { return (a + b - 1) / b }
+
+// split runs the encoding's pre-tokenizer. It is a hand-written equivalent
+// of tiktoken's regular expressions, which use lookahead that Go's regexp
+// package doesn't support:
+//
+//	's|'t|'re|'ve|'m|'ll|'d          contractions (cl100k only; o200k attaches them to words)
+//	[^\r\n\p{L}\p{N}]?\p{L}+         words, with one leading space or symbol
+//	\p{N}{1,3}                       numbers in groups of up to three digits
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*        symbol runs
+//	\s*[\r\n]+                       line breaks with preceding whitespace
+//	\s+(?!\S)|\s+                    other whitespace, leaving one space for the next word
+func (b *BPE) split(text string) []string {
+	rs := []rune(text)
+	var pieces []string
+	for i := 0; i < len(rs); {
+		j := b.next(rs, i)
+		pieces = append(pieces, string(rs[i:j]))
+		i = j
+	}
+	return pieces
+}
+
+// next returns the end of the piece starting at i.
+func (b *BPE) next(rs []rune, i int) int {
+	r := rs[i]
+	if !b.o200k {
+		if j := contraction(rs, i); j > i {
+			return j
+		}
+	}
+
+	// Words, optionally prefixed by one non-letter, non-digit character
+	start := i
+	if !isLetter(r) && !unicode.IsNumber(r) && r != '\r' && r != '\n' && i+1 < len(rs) && isLetter(rs[i+1]) {
+		start = i + 1
+	}
+	if isLetter(rs[start]) {
+		j := b.word(rs, start)
+		if b.o200k {
+			if k := contraction(rs, j); k > j {
+				j = k
+			}
+		}
+		return j
+	}
+
+	if unicode.IsNumber(r) {
+		j := i
+		for j < len(rs) && j-i < 3 && unicode.IsNumber(rs[j]) {
+			j++
+		}
+		return j
+	}
+
+	// Symbol runs, optionally prefixed by a space
+	j := i
+	if r == ' ' && j+1 < len(rs) && isSymbol(rs[j+1]) {
+		j++
+	}
+	if isSymbol(rs[j]) {
+		for j < len(rs) && isSymbol(rs[j]) {
+			j++
+		}
+		for j < len(rs) && (rs[j] == '\r' || rs[j] == '\n' || b.o200k && rs[j] == '/') {
+			j++
+		}
+		return j
+	}
+
+	// Whitespace
+	end := i
+	lastBreak := -1
+	for end < len(rs) && unicode.IsSpace(rs[end]) {
+		if rs[end] == '\r' || rs[end] == '\n' {
+			lastBreak = end
+		}
+		end++
+	}
+	switch {
+	case lastBreak >= 0:
+		return lastBreak + 1
+	case end == len(rs) || end-i == 1:
+		return end
+	default:
+		return end - 1
+	}
+}
+
+// word consumes letters from i. cl100k takes any run of letters; o200k
+// splits camelCase so that "parseHTTPRequest" becomes parse|HTTPRequest.
+func (b *BPE) word(rs []rune, i int) int {
+	if !b.o200k {
+		j := i
+		for j < len(rs) && isLetter(rs[j]) {
+			j++
+		}
+		return j
+	}
+	j := i
+	for j < len(rs) && isUpperish(rs[j]) {
+		j++
+	}
+	k := j
+	for k < len(rs) && isLowerish(rs[k]) {
+		k++
+	}
+	if k > j {
+		return k
+	}
+	return j
+}
+
+func contraction(rs []rune, i int) int {
+	if i+1 >= len(rs) || rs[i] != '\'' {
+		return i
+	}
+	rest := strings.ToLower(string(rs[i+1 : min(i+3, len(rs))]))
+	for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+		if strings.HasPrefix(rest, suffix) {
+			return i + 1 + len(suffix)
+		}
+	}
+	return i
+}
+
+func isLetter(r rune) bool { return unicode.IsLetter(r) || isMark(r) }
+
+// isMark reports combining marks, which o200k keeps inside words. cl100k
+// splits them off, but they are rare enough in source and prose that one
+// rule serves both.
+func isMark(r rune) bool { return unicode.Is(unicode.M, r) }
+
+func isUpperish(r rune) bool {
+	return unicode.IsUpper(r) || unicode.IsTitle(r) || unicode.In(r, unicode.Lm, unicode.Lo) || isMark(r)
+}
+
+func isLowerish(r rune) bool {
+	return unicode.IsLower(r) || unicode.In(r, unicode.Lm, unicode.Lo) || isMark(r)
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !isLetter(r) && !unicode.IsNumber(r)
+}
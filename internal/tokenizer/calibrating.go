@@ -0,0 +1,63 @@
+package tokenizer
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// defaultCharsPerToken is the usual ratio for English prose until the
+	// first usage report arrives.
+	defaultCharsPerToken = 4.0
+	// calibrationWeight is how much each new observation moves the ratio.
+	calibrationWeight = 0.3
+	minCharsPerToken  = 1.0
+	maxCharsPerToken  = 8.0
+)
+
+// Calibrating estimates tokens as len(text) / ratio, where ratio starts at
+// ~4 bytes per token and is refined from provider usage reports. It is
+// the fallback for models whose tokenizer isn't available locally.
+type Calibrating struct {
+	mu      sync.Mutex
+	ratio   float64
+	samples int
+}
+
+func NewCalibrating() *Calibrating {
+	return &Calibrating{ratio: defaultCharsPerToken}
+}
+
+func (c *Calibrating) Name() string { return "calibrated" }
+
+func (c *Calibrating) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(math.Ceil(float64(len(text)) / c.Ratio()))
+}
+
+// Observe folds a provider-reported token count into the ratio. The first
+// report replaces the default outright; later ones are averaged in.
+func (c *Calibrating) Observe(chars, tokens int) {
+	if chars <= 0 || tokens <= 0 {
+		return
+	}
+	observed := math.Max(minCharsPerToken, math.Min(maxCharsPerToken, float64(chars)/float64(tokens)))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.samples == 0 {
+		c.ratio = observed
+	} else {
+		c.ratio += calibrationWeight * (observed - c.ratio)
+	}
+	c.samples++
+}
+
+// Ratio returns the current chars-per-token estimate.
+func (c *Calibrating) Ratio() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ratio
+}
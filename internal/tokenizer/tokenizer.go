@@ -0,0 +1,57 @@
+// Package tokenizer counts tokens for context-window accounting.
+//
+// OpenAI-family models get a BPE tokenizer matching their encoding
+// (cl100k_base or o200k_base). Every other model gets a Calibrating
+// tokenizer that learns its chars-per-token ratio from the usage figures
+// providers return.
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+)
+
+// Tokenizer counts the tokens a model would see for a piece of text.
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+// Calibrator is implemented by tokenizers that improve from the token counts
+// reported by providers. chars is the length of the prompt text that was
+// sent and tokens is the provider's input token count for it.
+type Calibrator interface {
+	Observe(chars, tokens int)
+}
+
+// o200kPrefixes and cl100kPrefixes select the BPE encoding for OpenAI
+// models. Order matters: "gpt-4o" must be checked before "gpt-4".
+var (
+	o200kPrefixes  = []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "chatgpt-4o", "o1", "o3", "o4", "gpt-oss"}
+	cl100kPrefixes = []string{"gpt-4", "gpt-3.5", "text-embedding-3", "text-embedding-ada"}
+)
+
+// calibrated holds one Calibrating tokenizer per model so that every
+// conversation on the same model shares what has been learned.
+var calibrated sync.Map // model -> *Calibrating
+
+// ForModel picks the tokenizer for a model name, with or without a
+// provider prefix ("openai/gpt-4o").
+func ForModel(model string) Tokenizer {
+	base := strings.ToLower(model)
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[i+1:]
+	}
+	for _, p := range o200kPrefixes {
+		if strings.HasPrefix(base, p) {
+			return O200k()
+		}
+	}
+	for _, p := range cl100kPrefixes {
+		if strings.HasPrefix(base, p) {
+			return Cl100k()
+		}
+	}
+	t, _ := calibrated.LoadOrStore(base, NewCalibrating())
+	return t.(*Calibrating)
+}
@@ -0,0 +1,117 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplit_Cl100k(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello world", []string{"Hello", " world"}},
+		{"don't stop", []string{"don", "'t", " stop"}},
+		{"x := 12345", []string{"x", " :=", " ", "123", "45"}},
+		{"if (a) {\n\treturn\n}", []string{"if", " (", "a", ")", " {\n", "\treturn", "\n", "}"}},
+		{"a    b", []string{"a", "   ", " b"}},
+	}
+	for _, tt := range tests {
+		if got := Cl100k().split(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("split(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplit_O200kCamelCase(t *testing.T) {
+	got := O200k().split("parseHTTPRequest isn't")
+	want := []string{"parse", "HTTPRequest", " isn't"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBPE_MergeWithRanks(t *testing.T) {
+	var table strings.Builder
+	for i, tok := range []string{"h", "e", "l", "o", " ", "w", "r", "d", "he", "ll", "hell", "hello", " w", "or", " wor"} {
+		fmt.Fprintf(&table, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), i)
+	}
+	ranks, err := LoadRanks(strings.NewReader(table.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBPE("test", ranks, false)
+	if !b.Exact() {
+		t.Fatal("expected exact counting with ranks loaded")
+	}
+	// "hello" is one token; " world" merges to " wor" + "l" + "d"
+	if got := b.Count("hello world"); got != 4 {
+		t.Errorf("Count = %d, want 4", got)
+	}
+}
+
+func TestBPE_Estimate(t *testing.T) {
+	// 10 tokens in cl100k_base
+	if got := Cl100k().Count("The quick brown fox jumps over the lazy dog."); abs(got-10) > 1 {
+		t.Errorf("English estimate = %d, want ~10", got)
+	}
+	// cl100k spends at least a token per CJK character; len/4 would say 15
+	jp := "今日はいい天気ですね。散歩に行きましょう。"
+	if got := Cl100k().Count(jp); got < utf8.RuneCountInString(jp) {
+		t.Errorf("CJK estimate = %d, want at least %d", got, utf8.RuneCountInString(jp))
+	}
+	if Cl100k().Count(jp) <= O200k().Count(jp) {
+		t.Error("o200k should be cheaper than cl100k for CJK")
+	}
+}
+
+func TestCalibrating(t *testing.T) {
+	c := NewCalibrating()
+	if got := c.Count(strings.Repeat("a", 400)); got != 100 {
+		t.Errorf("default Count = %d, want 100", got)
+	}
+	c.Observe(300, 100) // 3 chars per token
+	if c.Ratio() != 3 {
+		t.Errorf("first observation should replace the default, ratio = %v", c.Ratio())
+	}
+	c.Observe(600, 100) // 6 chars per token pulls the ratio up, but only partly
+	if r := c.Ratio(); r <= 3 || r >= 6 {
+		t.Errorf("ratio = %v, want between 3 and 6", r)
+	}
+	c.Observe(100, 0) // Ignored
+	c.Observe(1, 1000)
+	if c.Ratio() < minCharsPerToken {
+		t.Errorf("ratio should be clamped, got %v", c.Ratio())
+	}
+}
+
+func TestForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o-mini":            "o200k_base",
+		"openai/o3-mini":         "o200k_base",
+		"gpt-4-turbo":            "cl100k_base",
+		"gpt-3.5-turbo":          "cl100k_base",
+		"claude-3-5-sonnet":      "calibrated",
+		"qwen2.5-coder:7b":       "calibrated",
+		"meta-llama/Llama-3-70B": "calibrated",
+	}
+	for model, want := range tests {
+		if got := ForModel(model).Name(); got != want {
+			t.Errorf("ForModel(%q) = %s, want %s", model, got, want)
+		}
+	}
+	if ForModel("qwen2.5-coder:7b") != ForModel("qwen2.5-coder:7b") {
+		t.Error("conversations on the same model should share calibration")
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
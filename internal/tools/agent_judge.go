@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jeanpaul/aseity/internal/provider"
 	"github.com/jeanpaul/aseity/internal/types"
 )
 
@@ -47,6 +48,16 @@ type judgeArgs struct {
 	Content      string `json:"content"`
 }
 
+// JudgeSchema constrains the critic's verdict when the provider supports it.
+var JudgeSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"status":   map[string]any{"type": "string", "enum": []string{"pass", "fail"}},
+		"feedback": map[string]any{"type": "string"},
+	},
+	"required": []string{"status", "feedback"},
+}
+
 type JudgeResult struct {
 	Status   string `json:"status"`   // "pass" or "fail"
 	Feedback string `json:"feedback"` // Detailed reasoning
@@ -91,7 +102,15 @@ If invalid, feedback must explain the specific defect.`, args.OriginalGoal, args
 	// Spawn the critic
 	// We use "Critic" as the agent name to trigger any specific persona logic if configured,
 	// but the specific task prompt above overrides the main directive.
-	id, err := j.spawner.Spawn(ctx, prompt, nil, "Critic")
+	// Verdicts should be reproducible, so pin the critic's whole run to low
+	// temperature. The verdict schema can't apply here: the critic uses
+	// tools, which providers won't combine with a response schema.
+	criticCtx := provider.WithChatOptions(ctx,
+		provider.WithTemperature(0),
+		provider.WithMaxTokens(1024),
+	)
+	criticCtx = provider.WithCallSource(criticCtx, "judge")
+	id, err := j.spawner.Spawn(criticCtx, prompt, nil, "Critic")
 	if err != nil {
 		return Result{Error: "failed to spawn critic: " + err.Error()}, nil
 	}
@@ -115,10 +134,13 @@ If invalid, feedback must explain the specific defect.`, args.OriginalGoal, args
 				continue
 			}
 			if info.Status == "done" {
-				// Parse the critic's JSON output
-				output := cleanJSON(info.Output)
-				var verdict JudgeResult
-				if err := json.Unmarshal([]byte(output), &verdict); err != nil {
+				// Parse the critic's JSON output, or have its review restated
+				// as a verdict
+				verdict, ok := parseVerdict(info.Output)
+				if !ok {
+					verdict, ok = j.structuredVerdict(ctx, info.Output)
+				}
+				if !ok {
 					// Fallback: If model didn't output JSON, treat as raw feedback
 					return Result{Output: fmt.Sprintf("Critic finished but returned malformed JSON. Raw Output:\n%s", info.Output)}, nil
 				}
@@ -134,6 +156,41 @@ If invalid, feedback must explain the specific defect.`, args.OriginalGoal, args
 	}
 }
 
+// structuredVerdict turns the critic's review into a verdict with a
+// tool-free call, where the verdict schema can be enforced.
+func (j *JudgeTool) structuredVerdict(ctx context.Context, review string) (JudgeResult, bool) {
+	completer, ok := j.spawner.(types.Completer)
+	if !ok {
+		return JudgeResult{}, false
+	}
+	ctx = provider.WithChatOptions(ctx,
+		provider.WithTemperature(0),
+		provider.WithResponseSchema("verdict", JudgeSchema),
+	)
+	ctx = provider.WithCallSource(ctx, "judge")
+	out, err := completer.Complete(ctx, fmt.Sprintf(`A reviewer wrote this review:
+"""
+%s
+"""
+
+Restate it as a JSON object {"status": "pass" | "fail", "feedback": "..."}. Use "pass" only if the review approves the content; put the defects it names in feedback.`, review))
+	if err != nil {
+		return JudgeResult{}, false
+	}
+	return parseVerdict(out)
+}
+
+// parseVerdict reads a verdict from model output, tolerating a markdown
+// fence and a capitalized status.
+func parseVerdict(output string) (JudgeResult, bool) {
+	var verdict JudgeResult
+	if err := json.Unmarshal([]byte(cleanJSON(output)), &verdict); err != nil {
+		return JudgeResult{}, false
+	}
+	verdict.Status = strings.ToLower(verdict.Status)
+	return verdict, verdict.Status == "pass" || verdict.Status == "fail"
+}
+
 // cleanJSON helper to strip potential markdown blocks from model output
 func cleanJSON(s string) string {
 	s = strings.TrimSpace(s)
@@ -0,0 +1,601 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jeanpaul/aseity/internal/checkpoint"
+)
+
+// ApplyPatchTool applies a patch to one or more files: a unified diff, as
+// produced by git diff or diff -u, or a patch envelope (*** Begin Patch).
+// Every hunk is located, with some tolerance for drift, before any file is
+// written, so a patch either applies completely or not at all.
+type ApplyPatchTool struct{}
+
+type applyPatchArgs struct {
+	Patch string `json:"patch"`
+}
+
+func (t *ApplyPatchTool) Name() string { return "apply_patch" }
+func (t *ApplyPatchTool) Description() string {
+	return "Apply a patch to one or more files. Accepts a unified diff (--- a/file, +++ b/file, @@ hunks) or an envelope:\n" +
+		"*** Begin Patch\n*** Update File: path\n@@ optional line near the change\n context\n-removed\n+added\n*** Add File: path\n+line\n*** Delete File: path\n*** End Patch\n" +
+		"Include a few unchanged context lines around each change. Nothing is written unless every hunk applies."
+}
+func (t *ApplyPatchTool) NeedsConfirmation() bool { return true }
+
+func (t *ApplyPatchTool) Paths(rawArgs string) []string {
+	var args applyPatchArgs
+	if json.Unmarshal([]byte(rawArgs), &args) != nil {
+		return nil
+	}
+	patches, err := parsePatch(args.Patch)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, p := range patches {
+		paths = append(paths, p.path)
+		if p.moveTo != "" {
+			paths = append(paths, p.moveTo)
+		}
+	}
+	return paths
+}
+
+func (t *ApplyPatchTool) Parameters() any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{"type": "string", "description": "Unified diff or *** Begin Patch envelope"},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, rawArgs string) (Result, error) {
+	var args applyPatchArgs
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return Result{Error: "invalid arguments: " + err.Error()}, nil
+	}
+	patches, err := parsePatch(args.Patch)
+	if err != nil {
+		return Result{Error: err.Error()}, nil
+	}
+	changes, err := planPatch(patches)
+	if err != nil {
+		return Result{Error: err.Error()}, nil
+	}
+	for _, c := range changes {
+		for _, path := range []string{c.moveFrom, c.path} {
+			if path == "" {
+				continue
+			}
+			if err := checkpoint.Save(ctx, t.Name(), path); err != nil {
+				return Result{Error: "checkpoint failed, no files were changed: " + err.Error()}, nil
+			}
+		}
+	}
+	if err := commitPatch(changes); err != nil {
+		return Result{Error: err.Error()}, nil
+	}
+
+	var summary, diff strings.Builder
+	var paths []string
+	for _, c := range changes {
+		d := unifiedDiff(c.path, c.before, c.after)
+		added, removed := diffStat(d)
+		switch {
+		case c.delete:
+			fmt.Fprintf(&summary, "- deleted %s\n", c.path)
+		case c.moveFrom != "":
+			fmt.Fprintf(&summary, "- moved %s to %s (+%d -%d)\n", c.moveFrom, c.path, added, removed)
+		case !c.existed:
+			fmt.Fprintf(&summary, "- created %s (+%d)\n", c.path, added)
+		default:
+			fmt.Fprintf(&summary, "- %s (+%d -%d)\n", c.path, added, removed)
+		}
+		diff.WriteString(d)
+		paths = append(paths, c.path)
+	}
+	return Result{
+		Output: fmt.Sprintf("Patch applied to %d file(s):\n%s\n```diff\n%s\n```", len(changes), summary.String(), diff.String()),
+		Data: map[string]any{
+			"type": "diff",
+			"path": strings.Join(paths, ", "),
+			"diff": diff.String(),
+		},
+	}, nil
+}
+
+// PatchFiles lists the files a patch touches, for display before it runs.
+func PatchFiles(patch string) []string {
+	patches, err := parsePatch(patch)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, p := range patches {
+		files = append(files, p.path)
+	}
+	return files
+}
+
+// filePatch is the change to one file.
+type filePatch struct {
+	path   string
+	moveTo string // Envelope "*** Move to:"
+	add    bool   // Create path; it must not exist
+	delete bool
+	hunks  []hunk
+}
+
+type hunk struct {
+	oldStart int    // 1-based line the hunk claims to start at; 0 if unknown
+	anchor   string // Envelope "@@ <line>": a line the hunk comes after
+	lines    []hunkLine
+}
+
+type hunkLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// old returns the lines the hunk expects to find.
+func (h hunk) old() []string {
+	var lines []string
+	for _, l := range h.lines {
+		if l.op != '+' {
+			lines = append(lines, l.text)
+		}
+	}
+	return lines
+}
+
+// parsePatch reads either patch format.
+func parsePatch(patch string) ([]filePatch, error) {
+	patch = strings.ReplaceAll(patch, "\r\n", "\n")
+	var patches []filePatch
+	var err error
+	if strings.Contains(patch, "*** Begin Patch") {
+		patches, err = parseEnvelope(patch)
+	} else {
+		patches, err = parseUnified(patch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file changes found in patch; expected a unified diff or a *** Begin Patch envelope")
+	}
+	return patches, nil
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+func parseUnified(patch string) ([]filePatch, error) {
+	lines := strings.Split(patch, "\n")
+	var patches []filePatch
+	var cur *filePatch
+	// Lines the current hunk header says are still to come. While any are,
+	// a removed "-- x" followed by an added "++ y" is content, not a file
+	// header; a real header is always followed by a hunk.
+	var oldLeft, newLeft int
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		inHunk := oldLeft > 0 || newLeft > 0
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") &&
+			(!inHunk || i+2 < len(lines) && strings.HasPrefix(lines[i+2], "@@")):
+			oldPath, newPath := diffPath(line[4:]), diffPath(lines[i+1][4:])
+			fp := filePatch{path: newPath}
+			switch {
+			case oldPath == "/dev/null":
+				fp.add = true
+			case newPath == "/dev/null":
+				fp.path, fp.delete = oldPath, true
+			}
+			patches = append(patches, fp)
+			cur = &patches[len(patches)-1]
+			oldLeft, newLeft = 0, 0
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before any --- / +++ file header", i+1)
+			}
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			start, _ := strconv.Atoi(m[1])
+			oldLeft, newLeft = hunkCount(m[2]), hunkCount(m[3])
+			cur.hunks = append(cur.hunks, hunk{oldStart: start})
+		case cur != nil && len(cur.hunks) > 0 && isHunkLine(line):
+			h := &cur.hunks[len(cur.hunks)-1]
+			hl := hunkLine{op: ' '}
+			if line != "" {
+				hl = hunkLine{op: line[0], text: line[1:]}
+			}
+			// Editors and models often strip the space from blank context
+			// lines, so an empty line is context.
+			h.lines = append(h.lines, hl)
+			if hl.op != '+' {
+				oldLeft--
+			}
+			if hl.op != '-' {
+				newLeft--
+			}
+		}
+		// Anything else (diff --git, index, "\ No newline") is ignored.
+	}
+	for i := range patches {
+		trimTrailingBlank(patches[i].hunks)
+	}
+	return patches, nil
+}
+
+// hunkCount reads a line count from a hunk header, where an omitted count
+// means one line.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func isHunkLine(line string) bool {
+	return line == "" || line[0] == ' ' || line[0] == '-' || line[0] == '+'
+}
+
+// trimTrailingBlank drops the blank context lines a patch gets from its
+// final newline; a trailing blank line is never real context.
+func trimTrailingBlank(hunks []hunk) {
+	for i := range hunks {
+		lines := hunks[i].lines
+		for len(lines) > 0 && lines[len(lines)-1] == (hunkLine{op: ' '}) {
+			lines = lines[:len(lines)-1]
+		}
+		hunks[i].lines = lines
+	}
+}
+
+// diffPath extracts a path from a ---/+++ header, dropping any timestamp and
+// git's a/ or b/ prefix.
+func diffPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if s != "/dev/null" && (strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/")) {
+		s = s[2:]
+	}
+	return s
+}
+
+func parseEnvelope(patch string) ([]filePatch, error) {
+	lines := strings.Split(patch, "\n")
+	var patches []filePatch
+	var cur *filePatch
+	inPatch := false
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " ")
+		switch {
+		case trimmed == "*** Begin Patch":
+			inPatch = true
+		case trimmed == "*** End Patch":
+			inPatch = false
+		case trimmed == "*** End of File":
+			// Marks a hunk at the end of the file; position comes from context.
+		case !inPatch:
+			continue
+		case strings.HasPrefix(line, "*** Add File: "):
+			patches = append(patches, filePatch{path: strings.TrimSpace(line[len("*** Add File: "):]), add: true, hunks: []hunk{{}}})
+			cur = &patches[len(patches)-1]
+		case strings.HasPrefix(line, "*** Delete File: "):
+			patches = append(patches, filePatch{path: strings.TrimSpace(line[len("*** Delete File: "):]), delete: true})
+			cur = nil
+		case strings.HasPrefix(line, "*** Update File: "):
+			patches = append(patches, filePatch{path: strings.TrimSpace(line[len("*** Update File: "):])})
+			cur = &patches[len(patches)-1]
+		case strings.HasPrefix(line, "*** Move to: "):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: *** Move to without an *** Update File", i+1)
+			}
+			cur.moveTo = strings.TrimSpace(line[len("*** Move to: "):])
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil || cur.add {
+				return nil, fmt.Errorf("line %d: @@ outside an *** Update File section", i+1)
+			}
+			cur.hunks = append(cur.hunks, hunk{anchor: strings.TrimSpace(strings.TrimPrefix(line, "@@"))})
+		case cur != nil && isHunkLine(line):
+			if len(cur.hunks) == 0 {
+				cur.hunks = append(cur.hunks, hunk{}) // The @@ line is optional for the first hunk
+			}
+			h := &cur.hunks[len(cur.hunks)-1]
+			if line == "" {
+				h.lines = append(h.lines, hunkLine{op: ' '})
+			} else {
+				h.lines = append(h.lines, hunkLine{op: line[0], text: line[1:]})
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q in patch", i+1, line)
+		}
+	}
+	for i := range patches {
+		trimTrailingBlank(patches[i].hunks)
+	}
+	return patches, nil
+}
+
+// fileChange is a validated change, ready to write.
+type fileChange struct {
+	path     string
+	moveFrom string // Set when the file was moved from here
+	existed  bool
+	delete   bool
+	before   string
+	after    string
+}
+
+// plannedFile is a file as the patches planned so far leave it.
+type plannedFile struct {
+	content string
+	exists  bool
+}
+
+// planPatch applies every hunk in memory and reports the first that doesn't
+// fit, before anything touches the disk. A path changed by an earlier patch
+// in the same envelope is planned from that result, not re-read from disk.
+func planPatch(patches []filePatch) ([]fileChange, error) {
+	planned := map[string]plannedFile{}
+	current := func(path string) (plannedFile, error) {
+		if f, ok := planned[path]; ok {
+			return f, nil
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return plannedFile{}, nil
+		}
+		return plannedFile{content: string(data), exists: err == nil}, err
+	}
+
+	var changes []fileChange
+	for _, p := range patches {
+		f, err := current(p.path)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%s: %v", p.path, err)
+		case p.add && f.exists:
+			return nil, fmt.Errorf("%s: cannot create, file already exists", p.path)
+		case !p.add && !f.exists:
+			return nil, fmt.Errorf("%s: no such file", p.path)
+		}
+		c := fileChange{path: p.path, existed: f.exists, before: f.content, delete: p.delete}
+		if !p.delete {
+			after, err := applyHunks(f.content, p.hunks)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", p.path, err)
+			}
+			c.after = after
+		}
+		if p.moveTo != "" {
+			dest, err := current(p.moveTo)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", p.moveTo, err)
+			}
+			if dest.exists {
+				return nil, fmt.Errorf("%s: cannot move to %s, file already exists", p.path, p.moveTo)
+			}
+			c.moveFrom, c.path, c.existed = p.path, p.moveTo, false
+			planned[p.path] = plannedFile{}
+		}
+		planned[c.path] = plannedFile{content: c.after, exists: !c.delete}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// applyHunks applies hunks to content in order.
+func applyHunks(content string, hunks []hunk) (string, error) {
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	from, offset := 0, 0 // Hunks apply in order; offset tracks the drift so far
+	for i, h := range hunks {
+		old := h.old()
+		hint := from
+		if h.oldStart > 0 {
+			hint = max(h.oldStart-1+offset, from)
+		}
+		if h.anchor != "" {
+			a := findAnchor(lines, h.anchor, from)
+			if a < 0 {
+				return "", fmt.Errorf("hunk %d: anchor line %q not found", i+1, h.anchor)
+			}
+			from, hint = a+1, a+1
+		}
+		at, front, back := locateHunk(lines, h, from, hint)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d does not apply; expected these lines:\n%s", i+1, strings.Join(old, "\n"))
+		}
+		// Context lines, including any dropped for fuzz, keep the file's text.
+		n := len(old) - front - back
+		var repl []string
+		k := at
+		for _, l := range h.lines[front : len(h.lines)-back] {
+			switch l.op {
+			case ' ':
+				repl = append(repl, lines[k])
+				k++
+			case '-':
+				k++
+			case '+':
+				repl = append(repl, l.text)
+			}
+		}
+		lines = append(lines[:at], append(repl, lines[at+n:]...)...)
+		if h.oldStart > 0 {
+			offset = at - (h.oldStart - 1 + front) + len(repl) - n
+		}
+		from = at + len(repl)
+	}
+
+	out := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// maxFuzz is how many context lines may be dropped from each end of a hunk
+// that doesn't match whole, as with patch's --fuzz.
+const maxFuzz = 2
+
+// locateHunk finds where h's old lines occur in lines at or after from,
+// nearest to hint. It tries an exact match, then one ignoring trailing
+// whitespace, then ignoring indentation, and then the same again with up to
+// maxFuzz context lines dropped from each end. It returns the index of the
+// match and how many lines were dropped from the front and back, or -1.
+func locateHunk(lines []string, h hunk, from, hint int) (at, front, back int) {
+	old := h.old()
+	if len(old) == 0 {
+		// A pure insertion (e.g. into an empty file) goes at the hint.
+		return min(hint, len(lines)), 0, 0
+	}
+	lead, trail := 0, 0
+	for lead < len(h.lines) && h.lines[lead].op == ' ' {
+		lead++
+	}
+	for trail < len(h.lines)-lead && h.lines[len(h.lines)-1-trail].op == ' ' {
+		trail++
+	}
+	for fuzz := 0; fuzz <= maxFuzz; fuzz++ {
+		front, back := min(fuzz, lead), min(fuzz, trail)
+		if fuzz > 0 && front+back < fuzz {
+			break // Nothing more to drop
+		}
+		core := old[front : len(old)-back]
+		if len(core) == 0 {
+			break
+		}
+		for _, eq := range lineMatchers {
+			if at := nearestMatch(lines, core, from, hint+front, eq); at >= 0 {
+				return at, front, back
+			}
+		}
+	}
+	return -1, 0, 0
+}
+
+var lineMatchers = []func(a, b string) bool{
+	func(a, b string) bool { return a == b },
+	func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+	func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) },
+}
+
+// nearestMatch searches outward from hint for block, at or after from.
+func nearestMatch(lines, block []string, from, hint int, eq func(a, b string) bool) int {
+	last := len(lines) - len(block)
+	if last < from {
+		return -1
+	}
+	hint = min(max(hint, from), last)
+	for d := 0; hint-d >= from || hint+d <= last; d++ {
+		if i := hint - d; i >= from && blockMatches(lines[i:], block, eq) {
+			return i
+		}
+		if i := hint + d; d > 0 && i <= last && blockMatches(lines[i:], block, eq) {
+			return i
+		}
+	}
+	return -1
+}
+
+func blockMatches(lines, block []string, eq func(a, b string) bool) bool {
+	for j, b := range block {
+		if !eq(lines[j], b) {
+			return false
+		}
+	}
+	return true
+}
+
+// findAnchor returns the first line at or after from containing anchor,
+// ignoring surrounding whitespace, or -1.
+func findAnchor(lines []string, anchor string, from int) int {
+	for i := from; i < len(lines); i++ {
+		if strings.Contains(strings.TrimSpace(lines[i]), anchor) {
+			return i
+		}
+	}
+	return -1
+}
+
+// commitPatch writes the planned changes. If a write fails, the files
+// already written are put back.
+func commitPatch(changes []fileChange) error {
+	var done []fileChange
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			c := done[i]
+			switch {
+			case c.moveFrom != "":
+				os.Remove(c.path)
+				writeFileAtomic(c.moveFrom, []byte(c.before))
+			case c.existed:
+				writeFileAtomic(c.path, []byte(c.before))
+			default:
+				os.Remove(c.path)
+			}
+		}
+	}
+	for _, c := range changes {
+		var err error
+		switch {
+		case c.delete:
+			err = os.Remove(c.path)
+		case c.moveFrom != "":
+			if err = writeFileAtomic(c.path, []byte(c.after)); err == nil {
+				err = os.Remove(c.moveFrom)
+			}
+		default:
+			err = writeFileAtomic(c.path, []byte(c.after))
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("%s: %v (no files were changed)", c.path, err)
+		}
+		// A deleted file is restored like an edited one.
+		if c.delete {
+			c.existed = true
+		}
+		done = append(done, c)
+	}
+	return nil
+}
+
+// diffStat counts the added and removed lines in a unified diff.
+func diffStat(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
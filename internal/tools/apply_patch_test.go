@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func applyPatch(t *testing.T, patch string) Result {
+	t.Helper()
+	args, _ := json.Marshal(map[string]string{"patch": patch})
+	res, err := (&ApplyPatchTool{}).Execute(context.Background(), string(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestApplyPatch_UnifiedMultiFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("a.txt", []byte("one\ntwo\nthree\nfour\nfive\n"), 0644)
+	os.WriteFile("b.txt", []byte("alpha\nbeta\n"), 0644)
+
+	patch := "diff --git a/a.txt b/a.txt\n" +
+		"index 5626abf..f719efd 100644\n" +
+		"--- a/a.txt\n+++ b/a.txt\n" +
+		"@@ -2,3 +2,3 @@\n two\n-three\n+THREE\n four\n" +
+		"--- b.txt\t2024-01-01 00:00:00\n+++ /dev/null\n" +
+		"@@ -1,2 +0,0 @@\n-alpha\n-beta\n" +
+		"--- /dev/null\n+++ b/c.txt\n" +
+		"@@ -0,0 +1,2 @@\n+new\n+file\n"
+
+	res := applyPatch(t, patch)
+	if res.Error != "" {
+		t.Fatalf("apply_patch: %s", res.Error)
+	}
+	if got := readFile(t, "a.txt"); got != "one\ntwo\nTHREE\nfour\nfive\n" {
+		t.Errorf("a.txt = %q", got)
+	}
+	if _, err := os.Stat("b.txt"); !os.IsNotExist(err) {
+		t.Errorf("b.txt should be deleted: %v", err)
+	}
+	if got := readFile(t, "c.txt"); got != "new\nfile\n" {
+		t.Errorf("c.txt = %q", got)
+	}
+	data, _ := res.Data.(map[string]any)
+	if data["type"] != "diff" || data["path"] != "a.txt, b.txt, c.txt" || !strings.Contains(data["diff"].(string), "+THREE") {
+		t.Errorf("data = %+v", data)
+	}
+}
+
+func TestApplyPatch_DashedContentLines(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("notes.md", []byte("title\n-- old\nend\n"), 0644)
+
+	// Removing "-- old" and adding "++ new" looks like a file header.
+	patch := "--- a/notes.md\n+++ b/notes.md\n" +
+		"@@ -1,3 +1,3 @@\n title\n--- old\n+++ new\n end\n"
+	res := applyPatch(t, patch)
+	if res.Error != "" {
+		t.Fatalf("apply_patch: %s", res.Error)
+	}
+	if got := readFile(t, "notes.md"); got != "title\n++ new\nend\n" {
+		t.Errorf("notes.md = %q", got)
+	}
+}
+
+func TestApplyPatch_SamePathTwice(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("a.txt", []byte("one\ntwo\nthree\n"), 0644)
+
+	patch := "*** Begin Patch\n" +
+		"*** Update File: a.txt\n-one\n+ONE\n" +
+		"*** Update File: a.txt\n-three\n+THREE\n" +
+		"*** Add File: b.txt\n+new\n" +
+		"*** Update File: b.txt\n-new\n+newer\n" +
+		"*** End Patch"
+	res := applyPatch(t, patch)
+	if res.Error != "" {
+		t.Fatalf("apply_patch: %s", res.Error)
+	}
+	if got := readFile(t, "a.txt"); got != "ONE\ntwo\nTHREE\n" {
+		t.Errorf("a.txt = %q; the second update lost the first", got)
+	}
+	if got := readFile(t, "b.txt"); got != "newer\n" {
+		t.Errorf("b.txt = %q", got)
+	}
+}
+
+func TestApplyPatch_Envelope(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "old.go"), filepath.Join(dir, "new.go")
+	os.WriteFile(src, []byte("package x\n\nfunc A() {\n\treturn\n}\n\nfunc B() {\n\treturn\n}\n"), 0644)
+
+	patch := "*** Begin Patch\n" +
+		"*** Update File: " + src + "\n" +
+		"*** Move to: " + dst + "\n" +
+		"@@ func B() {\n" +
+		"-\treturn\n" +
+		"+\tpanic(\"b\")\n" +
+		" }\n" +
+		"*** Add File: " + filepath.Join(dir, "sub", "doc.go") + "\n" +
+		"+// Package x does things.\n" +
+		"+package x\n" +
+		"*** End Patch\n"
+
+	if files := PatchFiles(patch); len(files) != 2 || files[0] != src {
+		t.Errorf("PatchFiles = %v", files)
+	}
+	res := applyPatch(t, patch)
+	if res.Error != "" {
+		t.Fatalf("apply_patch: %s", res.Error)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("moved file should be gone from its old path")
+	}
+	if got := readFile(t, dst); got != "package x\n\nfunc A() {\n\treturn\n}\n\nfunc B() {\n\tpanic(\"b\")\n}\n" {
+		t.Errorf("new.go = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "sub", "doc.go")); got != "// Package x does things.\npackage x\n" {
+		t.Errorf("doc.go = %q", got)
+	}
+}
+
+func TestApplyPatch_Fuzz(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("f.py", []byte("# header\n# added later\n\ndef f():\n    x = 1  \n    return x\n\ndef g():\n    pass\n"), 0644)
+
+	// Wrong line numbers, trailing whitespace lost, and one stale context line.
+	patch := "--- a/f.py\n+++ b/f.py\n" +
+		"@@ -2,4 +2,4 @@\n" +
+		" def f():\n" +
+		"     x = 1\n" +
+		"-    return x\n" +
+		"+    return x + 1\n" +
+		" \n" +
+		" def stale():\n"
+
+	res := applyPatch(t, patch)
+	if res.Error != "" {
+		t.Fatalf("apply_patch: %s", res.Error)
+	}
+	want := "# header\n# added later\n\ndef f():\n    x = 1  \n    return x + 1\n\ndef g():\n    pass\n"
+	if got := readFile(t, "f.py"); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+}
+
+func TestApplyPatch_FailingHunkWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("one\ntwo\n"), 0644)
+	os.WriteFile(b, []byte("red\ngreen\n"), 0644)
+
+	patch := "*** Begin Patch\n" +
+		"*** Update File: " + a + "\n-one\n+ONE\n" +
+		"*** Update File: " + b + "\n-blue\n+BLUE\n" +
+		"*** End Patch"
+	res := applyPatch(t, patch)
+	if !strings.Contains(res.Error, "b.txt: hunk 1 does not apply") {
+		t.Errorf("error = %q", res.Error)
+	}
+	if got := readFile(t, a); got != "one\ntwo\n" {
+		t.Errorf("a.txt = %q; nothing should be written when a hunk fails", got)
+	}
+
+	res = applyPatch(t, "*** Begin Patch\n*** Add File: "+a+"\n+x\n*** End Patch")
+	if !strings.Contains(res.Error, "already exists") {
+		t.Errorf("adding an existing file: error = %q", res.Error)
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/creack/pty"
+
+	"github.com/jeanpaul/aseity/internal/checkpoint"
 )
 
 type BashTool struct {
@@ -71,6 +76,11 @@ func (b *BashTool) ExecuteStream(ctx context.Context, rawArgs string, callback f
 		return Result{Error: err.Error()}, nil
 	}
 
+	// Best effort: a missed checkpoint shouldn't stop the command.
+	for _, path := range redirectTargets(args.Command) {
+		_ = checkpoint.Save(ctx, b.Name(), path)
+	}
+
 	// NOTE: We do NOT enforce "sudo -n" anymore because we support interactivity via PTY
 
 	timeout := 120
@@ -202,6 +212,74 @@ func extractBaseCommand(cmd string) string {
 	return cmd
 }
 
+var (
+	// > file, >> file, 2> file, &> file and >| file; not >&2.
+	redirectRe = regexp.MustCompile(`(?:^|[^<>&\d])(?:\d|&)?>>?\|?\s*("[^"]*"|'[^']*'|[^\s;&|<>()'"]+)`)
+	teeRe      = regexp.MustCompile(`\btee\b([^|;&<>]*)`)
+	wordRe     = regexp.MustCompile(`\S+`)
+)
+
+// redirectTargets finds the files a command writes through redirections or
+// tee. It reads the command as written, so targets built from variables or
+// globs, or in another directory after a cd, are missed.
+func redirectTargets(command string) []string {
+	// Match against a copy with quoted text masked, so "a -> b" is not a
+	// redirection, and take the words from the command itself.
+	masked := maskQuoted(command)
+	var words []string
+	for _, m := range redirectRe.FindAllStringSubmatchIndex(masked, -1) {
+		words = append(words, command[m[2]:m[3]])
+	}
+	for _, m := range teeRe.FindAllStringSubmatchIndex(masked, -1) {
+		for _, f := range wordRe.FindAllStringIndex(masked[m[2]:m[3]], -1) {
+			if w := command[m[2]+f[0] : m[2]+f[1]]; !strings.HasPrefix(w, "-") {
+				words = append(words, w)
+			}
+		}
+	}
+
+	var paths []string
+	for _, w := range words {
+		w = strings.Trim(w, `"'`)
+		if w == "" || strings.HasPrefix(w, "/dev/") || strings.ContainsAny(w, "$`*?") {
+			continue
+		}
+		if strings.HasPrefix(w, "~/") {
+			home, _ := os.UserHomeDir()
+			w = filepath.Join(home, w[2:])
+		}
+		paths = append(paths, w)
+	}
+	return paths
+}
+
+// maskQuoted replaces every byte between single or double quotes with 'x',
+// keeping the quotes and the length.
+func maskQuoted(command string) string {
+	b := []byte(command)
+	var quote byte
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case quote == 0:
+			if c == '\\' && i+1 < len(b) {
+				b[i+1] = 'x' // An escaped quote or > is literal
+				i++
+			} else if c == '"' || c == '\'' {
+				quote = c
+			}
+		case c == quote:
+			quote = 0
+		case c == '\\' && quote == '"' && i+1 < len(b):
+			b[i], b[i+1] = 'x', 'x'
+			i++
+		default:
+			b[i] = 'x'
+		}
+	}
+	return string(b)
+}
+
 // tryParseCommand attempts to extract a command from malformed arguments
 // Some models send Python-style lists like ['echo', 'hello'] instead of {"command": "..."}
 func tryParseCommand(rawArgs string) string {
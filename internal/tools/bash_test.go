@@ -85,3 +85,21 @@ func TestBashTool_Timeout(t *testing.T) {
 		t.Errorf("Command took %v, expected ~1s (timeout)", duration)
 	}
 }
+
+func TestRedirectTargets(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{`echo hi > out.txt`, []string{"out.txt"}},
+		{`echo hi >> "my file.txt" 2>&1`, []string{"my file.txt"}},
+		{`echo "a -> b" 'c > d'`, nil},
+		{`echo "say \"x > y\"" > log && echo a \> b`, []string{"log"}},
+		{`make | tee build.log "q > r"`, []string{"build.log", "q > r"}},
+	}
+	for _, tt := range tests {
+		if got := redirectTargets(tt.command); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("redirectTargets(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	symbolsListLimit  = 300
+	symbolsFindLimit  = 20
+	symbolsRefsLimit  = 100
+	symbolsMaxLimit   = 2000
+	symbolsMaxSrcLine = 400
+)
+
+// CodeSymbolsTool navigates code by declaration rather than by file. Go is
+// parsed with go/parser; Python, JavaScript/TypeScript, Rust, Java, C# and
+// Ruby are scanned with line patterns.
+type CodeSymbolsTool struct{}
+
+type codeSymbolsArgs struct {
+	Action       string `json:"action"`
+	Name         string `json:"name,omitempty"`
+	Path         string `json:"path,omitempty"`
+	IncludeTests bool   `json:"include_tests,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
+}
+
+// symbol is a declaration. Lines are 1-based; Start and End span the
+// declaration including its doc comment.
+type symbol struct {
+	Kind      string // func, method, type, const, var, class, ...
+	Name      string
+	Receiver  string // type or class a method belongs to
+	Package   string // Go package name
+	Signature string
+	Doc       string
+	File      string
+	Line      int
+	Start     int
+	End       int
+}
+
+// FullName returns Receiver.Name for methods and Name otherwise.
+func (s symbol) FullName() string {
+	if s.Receiver != "" {
+		return s.Receiver + "." + s.Name
+	}
+	return s.Name
+}
+
+// reference is a line that uses a name.
+type reference struct {
+	File       string
+	Line       int
+	Text       string
+	Definition bool
+}
+
+func (t *CodeSymbolsTool) Name() string { return "code_symbols" }
+func (t *CodeSymbolsTool) Description() string {
+	return "Navigate code by symbol instead of reading whole files. " +
+		"'list' shows the declarations in a file or package directory with signatures and line numbers; " +
+		"'definition' finds where a name is declared, with its doc comment; " +
+		"'source' returns a declaration's full source with its doc comment; " +
+		"'references' finds the lines using a name across the Go module (or 'path'). " +
+		"Names may be qualified: 'Registry.Execute' for a method, 'tools.NewRegistry' for a package member. " +
+		"Go is parsed exactly; Python, JS/TS, Rust, Java, C# and Ruby are matched heuristically."
+}
+func (t *CodeSymbolsTool) NeedsConfirmation() bool { return false }
+
+func (t *CodeSymbolsTool) Parameters() any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action":        map[string]any{"type": "string", "enum": []string{"list", "definition", "source", "references"}, "description": "What to do"},
+			"name":          map[string]any{"type": "string", "description": "Symbol name for definition, source and references, e.g. 'NewRegistry', 'Registry.Execute'"},
+			"path":          map[string]any{"type": "string", "description": "File or directory. For list, the file or package to list (default: current dir). Otherwise where to search; Go searches the whole enclosing module."},
+			"include_tests": map[string]any{"type": "boolean", "description": "Include test files in list, definition and source (references always do)"},
+			"limit":         map[string]any{"type": "integer", "description": "Max symbols or references to return"},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *CodeSymbolsTool) Paths(rawArgs string) []string {
+	var args codeSymbolsArgs
+	if json.Unmarshal([]byte(rawArgs), &args) != nil {
+		return nil
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+	if args.Action == "list" {
+		return []string{args.Path}
+	}
+	return []string{codeScope(args.Path)}
+}
+
+func (t *CodeSymbolsTool) Execute(ctx context.Context, rawArgs string) (Result, error) {
+	var args codeSymbolsArgs
+	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+		return Result{Error: "invalid arguments: " + err.Error()}, nil
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+	if _, err := os.Stat(args.Path); err != nil {
+		return Result{Error: err.Error()}, nil
+	}
+	if args.Action != "list" && strings.TrimSpace(args.Name) == "" {
+		return Result{Error: fmt.Sprintf("action %q needs a 'name'", args.Action)}, nil
+	}
+
+	limit := func(def int) int {
+		if args.Limit <= 0 {
+			return def
+		}
+		return min(args.Limit, symbolsMaxLimit)
+	}
+	switch args.Action {
+	case "list":
+		return listSymbols(args.Path, args.IncludeTests, limit(symbolsListLimit)), nil
+	case "definition", "source":
+		return findDefinitions(ctx, args, limit(symbolsFindLimit)), nil
+	case "references":
+		return findReferences(ctx, args, limit(symbolsRefsLimit)), nil
+	}
+	return Result{Error: fmt.Sprintf("unknown action %q (use list, definition, source or references)", args.Action)}, nil
+}
+
+func listSymbols(path string, includeTests bool, limit int) Result {
+	files := []string{path}
+	if info, _ := os.Stat(path); info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return Result{Error: err.Error()}
+		}
+		for _, e := range entries {
+			p := filepath.Join(path, e.Name())
+			if !e.IsDir() && isCodeFile(p) && (includeTests || !isTestFile(p)) {
+				files = append(files, p)
+			}
+		}
+		if len(files) == 0 {
+			return Result{Output: fmt.Sprintf("No source files in %s. Use file_ls to find the package directories.", path)}
+		}
+	} else if !isCodeFile(path) {
+		return Result{Error: fmt.Sprintf("%s is not a supported source file", path)}
+	}
+
+	var sb strings.Builder
+	count := 0
+	for _, f := range files {
+		syms, err := fileSymbols(f)
+		header := f
+		if lang := langFor(f); lang != nil {
+			header += " (" + lang.name + ")"
+		} else if len(syms) > 0 {
+			header += " (package " + syms[0].Package + ")"
+		}
+		sb.WriteString(header + "\n")
+		if err != nil {
+			fmt.Fprintf(&sb, "  parse error: %v\n", err)
+		}
+		for _, s := range syms {
+			if count == limit {
+				fmt.Fprintf(&sb, "\n[Truncated at %d symbols. List a single file or raise 'limit'.]", limit)
+				return Result{Output: sb.String()}
+			}
+			fmt.Fprintf(&sb, "%6d  %s\n", s.Line, s.Signature)
+			count++
+		}
+	}
+	return Result{Output: sb.String()}
+}
+
+func findDefinitions(ctx context.Context, args codeSymbolsArgs, limit int) Result {
+	qual, name := splitSymbolName(args.Name)
+	scope := codeScope(args.Path)
+
+	var found []symbol
+	more := false
+	walkCode(ctx, scope, args.IncludeTests, func(p string, src []byte) bool {
+		if !bytes.Contains(src, []byte(name)) {
+			return true
+		}
+		syms, _ := symbolsOf(p, src)
+		for _, s := range syms {
+			if s.Name != name || !(qual == "" || s.Receiver == qual || s.Receiver == "" && s.Package == qual) {
+				continue
+			}
+			if len(found) == limit {
+				more = true
+				return false
+			}
+			found = append(found, s)
+		}
+		return true
+	})
+	if len(found) == 0 {
+		return Result{Output: fmt.Sprintf("No definition of %s found in %s.", args.Name, displayPath(scope))}
+	}
+
+	var sb strings.Builder
+	for i, s := range found {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if args.Action == "source" {
+			writeSource(&sb, s)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:%d: %s\n", displayPath(s.File), s.Line, s.Signature)
+		for _, line := range strings.Split(strings.TrimRight(s.Doc, "\n"), "\n") {
+			if line != "" {
+				sb.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	if more {
+		fmt.Fprintf(&sb, "\n[Truncated at %d definitions. Qualify the name, e.g. Type.Method, or narrow 'path'.]", limit)
+	}
+	return Result{Output: sb.String()}
+}
+
+// writeSource writes a declaration's lines numbered like file_read.
+func writeSource(sb *strings.Builder, s symbol) {
+	data, err := os.ReadFile(s.File)
+	if err != nil {
+		fmt.Fprintf(sb, "%s: %v\n", displayPath(s.File), err)
+		return
+	}
+	lines := strings.Split(string(data), "\n")
+	end := min(s.End, len(lines))
+	fmt.Fprintf(sb, "%s:%d-%d (%s %s)\n", displayPath(s.File), s.Start, end, s.Kind, s.FullName())
+	shown := min(end, s.Start+symbolsMaxSrcLine-1)
+	for i := s.Start; i <= shown; i++ {
+		fmt.Fprintf(sb, "%4d\t%s\n", i, lines[i-1])
+	}
+	if shown < end {
+		fmt.Fprintf(sb, "... (%d more lines; use file_read with offset %d)\n", end-shown, shown)
+	}
+}
+
+func findReferences(ctx context.Context, args codeSymbolsArgs, limit int) Result {
+	qual, name := splitSymbolName(args.Name)
+	scope := codeScope(args.Path)
+
+	var refs []reference
+	files := map[string]bool{}
+	more := false
+	walkCode(ctx, scope, true, func(p string, src []byte) bool {
+		if !bytes.Contains(src, []byte(name)) {
+			return true
+		}
+		var found []reference
+		if filepath.Ext(p) == ".go" {
+			found, _ = goReferences(p, src, qual, name)
+		} else {
+			// Without a parser the qualifier can only narrow the search to
+			// files that mention it.
+			if qual != "" && !bytes.Contains(src, []byte(qual)) {
+				return true
+			}
+			defs := map[int]bool{}
+			for _, s := range heuristicSymbols(langFor(p), src) {
+				if s.Name == name {
+					defs[s.Line] = true
+				}
+			}
+			found = textReferences(src, name, defs)
+		}
+		for _, r := range found {
+			if len(refs) == limit {
+				more = true
+				return false
+			}
+			r.File = p
+			refs = append(refs, r)
+			files[p] = true
+		}
+		return true
+	})
+	if len(refs) == 0 {
+		return Result{Output: fmt.Sprintf("No references to %s found in %s.", args.Name, displayPath(scope))}
+	}
+
+	var sb strings.Builder
+	for _, r := range refs {
+		mark := ""
+		if r.Definition {
+			mark = "[definition] "
+		}
+		fmt.Fprintf(&sb, "%s:%d: %s%s\n", displayPath(r.File), r.Line, mark, truncateLine(r.Text))
+	}
+	if more {
+		fmt.Fprintf(&sb, "\n[Truncated at %d references. Narrow 'path' or raise 'limit'.]", limit)
+	} else {
+		fmt.Fprintf(&sb, "\n%d reference(s) in %d file(s).", len(refs), len(files))
+	}
+	return Result{Output: sb.String()}
+}
+
+// splitSymbolName splits "Type.Method" or "pkg.Name" into qualifier and name.
+func splitSymbolName(full string) (qual, name string) {
+	full = strings.TrimSpace(full)
+	if i := strings.LastIndex(full, "."); i > 0 && i < len(full)-1 {
+		return full[:i], full[i+1:]
+	}
+	return "", full
+}
+
+// fileSymbols reads a source file and lists its declarations.
+func fileSymbols(path string) ([]symbol, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return symbolsOf(path, src)
+}
+
+func symbolsOf(path string, src []byte) ([]symbol, error) {
+	var syms []symbol
+	var err error
+	if filepath.Ext(path) == ".go" {
+		syms, err = goSymbols(path, src)
+	} else if lang := langFor(path); lang != nil {
+		syms = heuristicSymbols(lang, src)
+	}
+	for i := range syms {
+		syms[i].File = path
+	}
+	return syms, err
+}
+
+func isCodeFile(path string) bool {
+	return filepath.Ext(path) == ".go" || langFor(path) != nil
+}
+
+func isTestFile(path string) bool {
+	base := filepath.Base(path)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	return strings.HasSuffix(stem, "_test") || strings.HasPrefix(stem, "test_") ||
+		strings.HasSuffix(stem, ".test") || strings.HasSuffix(stem, ".spec") ||
+		strings.HasSuffix(stem, "Test") || strings.HasSuffix(stem, "Tests")
+}
+
+// codeScope returns the directory searched for definitions and references:
+// the enclosing Go module's root, or path's directory outside a module.
+func codeScope(path string) string {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for d := dir; ; {
+		if exists(filepath.Join(d, "go.mod")) {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+// walkCode calls fn with each source file under root, in lexical order,
+// until it returns false. It skips what file_search skips, plus vendor,
+// testdata and nested Go modules.
+func walkCode(ctx context.Context, root string, includeTests bool, fn func(path string, src []byte) bool) {
+	var ignores ignoreList
+	ignores.loadParents(root)
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if p != root {
+				name := d.Name()
+				if strings.HasPrefix(name, ".") || searchSkipDirs[name] || name == "vendor" || name == "testdata" ||
+					exists(filepath.Join(p, "go.mod")) || ignores.ignored(p, true) {
+					return filepath.SkipDir
+				}
+			}
+			ignores.load(p)
+			return nil
+		}
+		if !d.Type().IsRegular() || !isCodeFile(p) || !includeTests && isTestFile(p) || ignores.ignored(p, false) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > searchMaxFileSize {
+			return nil
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		if !fn(p, src) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// displayPath shows p relative to the working directory when it is inside
+// it.
+func displayPath(p string) string {
+	cwd, _ := os.Getwd()
+	if rel, err := filepath.Rel(cwd, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return p
+}
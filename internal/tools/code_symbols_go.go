@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+)
+
+// goSymbols lists the top-level declarations of a Go file.
+func goSymbols(path string, src []byte) ([]symbol, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+	pkg := f.Name.Name
+
+	var syms []symbol
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			s := symbol{Kind: "func", Name: d.Name.Name, Package: pkg, Line: line(d.Name.Pos()), Start: line(d.Pos()), End: line(d.End())}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				s.Kind = "method"
+				s.Receiver = recvTypeName(d.Recv.List[0].Type)
+			}
+			if d.Doc != nil {
+				s.Start = line(d.Doc.Pos())
+				s.Doc = d.Doc.Text()
+			}
+			sig := *d
+			sig.Doc, sig.Body = nil, nil
+			s.Signature = printNode(fset, &sig)
+			syms = append(syms, s)
+
+		case *ast.GenDecl:
+			// A lone spec takes the declaration's doc comment and keyword;
+			// a spec in a group keeps its own.
+			for _, spec := range d.Specs {
+				start, end, doc := spec.Pos(), spec.End(), (*ast.CommentGroup)(nil)
+				if len(d.Specs) == 1 {
+					start, end, doc = d.Pos(), d.End(), d.Doc
+				}
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					if sp.Doc != nil {
+						doc = sp.Doc
+					}
+					syms = append(syms, withDoc(symbol{
+						Kind: "type", Name: sp.Name.Name, Package: pkg,
+						Signature: typeSignature(fset, sp),
+						Line:      line(sp.Name.Pos()), Start: line(start), End: line(end),
+					}, doc, line))
+				case *ast.ValueSpec:
+					if sp.Doc != nil {
+						doc = sp.Doc
+					}
+					for i, name := range sp.Names {
+						if name.Name == "_" {
+							continue
+						}
+						syms = append(syms, withDoc(symbol{
+							Kind: d.Tok.String(), Name: name.Name, Package: pkg,
+							Signature: valueSignature(fset, d.Tok, sp, i),
+							Line:      line(name.Pos()), Start: line(start), End: line(end),
+						}, doc, line))
+					}
+				}
+			}
+		}
+	}
+	return syms, nil
+}
+
+func withDoc(s symbol, doc *ast.CommentGroup, line func(token.Pos) int) symbol {
+	if doc != nil {
+		s.Doc = doc.Text()
+		s.Start = min(s.Start, line(doc.Pos()))
+	}
+	return s
+}
+
+// recvTypeName returns the type name of a method receiver: "Registry" for
+// "*Registry" and "List" for "List[T]".
+func recvTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// typeSignature returns "type Name[T any] struct" rather than the whole
+// field list; other types are shown in full.
+func typeSignature(fset *token.FileSet, sp *ast.TypeSpec) string {
+	ts := *sp
+	ts.Doc, ts.Comment = nil, nil
+	switch sp.Type.(type) {
+	case *ast.StructType:
+		ts.Type = ast.NewIdent("struct")
+	case *ast.InterfaceType:
+		ts.Type = ast.NewIdent("interface")
+	}
+	return printNode(fset, &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{&ts}})
+}
+
+// valueSignature returns "const Name Type = value" for the i'th name of a
+// spec, leaving out values that don't fit on a line.
+func valueSignature(fset *token.FileSet, tok token.Token, sp *ast.ValueSpec, i int) string {
+	sig := tok.String() + " " + sp.Names[i].Name
+	if sp.Type != nil {
+		sig += " " + printNode(fset, sp.Type)
+	}
+	if i < len(sp.Values) {
+		if v := printNode(fset, sp.Values[i]); len(v) <= 60 && !strings.Contains(v, "{") {
+			sig += " = " + v
+		}
+	}
+	return sig
+}
+
+// printNode prints a node on one line.
+func printNode(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// goReferences returns the lines of a Go file that use name as an
+// identifier, so comments and strings don't count. It matches by name:
+// without type checking, a method and an unrelated function of the same
+// name both match. A qualifier narrows the match: in a file that imports
+// package qual only qual.name counts, and where qual is neither the file's
+// package nor an import, it is taken as a type and only selectors x.name
+// and qual's own method declarations count.
+func goReferences(path string, src []byte, qual, name string) ([]reference, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := map[string]bool{}
+	for _, imp := range f.Imports {
+		imports[importName(imp)] = true
+	}
+	// What counts, given the qualifier: the bare name only in qual's own
+	// package, and x.name selectors unless qual is an import.
+	plain := qual == "" || f.Name.Name == qual
+	selectors := !imports[qual]
+
+	decls := map[*ast.Ident]bool{}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && plain || d.Recv != nil && (qual == "" || recvTypeName(d.Recv.List[0].Type) == qual) {
+				decls[d.Name] = true
+			}
+		case *ast.GenDecl:
+			if !plain {
+				continue
+			}
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					decls[sp.Name] = true
+				case *ast.ValueSpec:
+					for _, n := range sp.Names {
+						decls[n] = true
+					}
+				}
+			}
+		}
+	}
+
+	lines := strings.Split(string(src), "\n")
+	var refs []reference
+	seen := map[int]bool{}
+	add := func(id *ast.Ident) {
+		if ln := fset.Position(id.Pos()).Line; !seen[ln] {
+			seen[ln] = true
+			refs = append(refs, reference{Line: ln, Text: strings.TrimSpace(lines[ln-1]), Definition: decls[id]})
+		}
+	}
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if n.Sel.Name != name {
+				return true
+			}
+			if x, ok := n.X.(*ast.Ident); ok && imports[x.Name] {
+				if qual == "" || x.Name == qual {
+					add(n.Sel)
+				}
+			} else if selectors {
+				add(n.Sel)
+			}
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.Ident:
+			if n.Name == name && (plain || decls[n]) {
+				add(n)
+			}
+		}
+		return true
+	}
+	ast.Inspect(f, visit)
+	return refs, nil
+}
+
+// importName returns the name a file refers to an import by.
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	p := strings.Trim(imp.Path.Value, `"`)
+	p = p[strings.LastIndex(p, "/")+1:]
+	if i := strings.Index(p, ".v"); i > 0 {
+		p = p[:i] // gopkg.in/yaml.v3
+	}
+	return strings.TrimPrefix(p, "go-")
+}
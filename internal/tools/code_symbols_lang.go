@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// codeLang describes how to find declarations in a language without a
+// parser: line regexps with "kind" and "name" groups, and how a
+// declaration's body ends.
+type codeLang struct {
+	name     string
+	patterns []*regexp.Regexp
+	block    string   // "braces", "indent" or "end"
+	comments []string // prefixes of comment and decorator lines kept with a declaration
+}
+
+const declPrefix = `^(?P<indent>[ \t]*)`
+
+var codeLangs = map[string]*codeLang{}
+
+func init() {
+	langs := []struct {
+		lang *codeLang
+		exts []string
+	}{
+		{&codeLang{
+			name:     "python",
+			patterns: []*regexp.Regexp{regexp.MustCompile(declPrefix + `(?:async[ \t]+)?(?P<kind>def|class)[ \t]+(?P<name>\w+)`)},
+			block:    "indent",
+			comments: []string{"#", "@"},
+		}, []string{".py"}},
+		{&codeLang{
+			name: "javascript",
+			patterns: []*regexp.Regexp{
+				regexp.MustCompile(declPrefix + `(?:export[ \t]+)?(?:default[ \t]+)?(?:declare[ \t]+)?(?:abstract[ \t]+)?(?:async[ \t]+)?(?P<kind>function\*?|class|interface|type|enum)[ \t]+(?P<name>[A-Za-z_$][\w$]*)`),
+				regexp.MustCompile(declPrefix + `(?:export[ \t]+)?(?:const|let|var)[ \t]+(?P<name>[A-Za-z_$][\w$]*)[ \t]*(?::[^=]+)?=[ \t]*(?:async[ \t]*)?(?P<kind>function|\([^)]*\)[ \t]*(?::[^=]+)?=>|[A-Za-z_$][\w$]*[ \t]*=>)`),
+			},
+			block:    "braces",
+			comments: []string{"//", "/*", "*", "@"},
+		}, []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"}},
+		{&codeLang{
+			name:     "rust",
+			patterns: []*regexp.Regexp{regexp.MustCompile(declPrefix + `(?:pub(?:\([^)]*\))?[ \t]+)?(?:const[ \t]+)?(?:async[ \t]+)?(?:unsafe[ \t]+)?(?P<kind>fn|struct|enum|trait|type|mod|union)[ \t]+(?P<name>\w+)`)},
+			block:    "braces",
+			comments: []string{"//", "#["},
+		}, []string{".rs"}},
+		{&codeLang{
+			name: "java",
+			patterns: []*regexp.Regexp{
+				regexp.MustCompile(declPrefix + `(?:(?:public|private|protected|internal|static|final|abstract|sealed|partial|readonly)[ \t]+)*(?P<kind>class|interface|enum|record|struct)[ \t]+(?P<name>\w+)`),
+				regexp.MustCompile(declPrefix + `(?:(?:public|private|protected|internal|static|final|abstract|synchronized|override|virtual|async)[ \t]+)+[\w<>\[\],.? ]+?[ \t]+(?P<name>\w+)[ \t]*\((?P<kind>)`),
+			},
+			block:    "braces",
+			comments: []string{"//", "/*", "*", "@", "["},
+		}, []string{".java", ".cs"}},
+		{&codeLang{
+			name:     "ruby",
+			patterns: []*regexp.Regexp{regexp.MustCompile(declPrefix + `(?P<kind>def|class|module)[ \t]+(?P<name>(?:self\.)?[\w?!=]+)`)},
+			block:    "end",
+			comments: []string{"#"},
+		}, []string{".rb"}},
+	}
+	for _, l := range langs {
+		for _, ext := range l.exts {
+			codeLangs[ext] = l.lang
+		}
+	}
+}
+
+func langFor(path string) *codeLang {
+	return codeLangs[filepath.Ext(path)]
+}
+
+// heuristicSymbols finds declarations line by line. Python methods are named
+// Class.method after the class they are indented under.
+func heuristicSymbols(lang *codeLang, src []byte) []symbol {
+	lines := strings.Split(string(src), "\n")
+	var syms []symbol
+	type class struct {
+		name   string
+		indent int
+	}
+	var classes []class
+	for i, line := range lines {
+		for _, re := range lang.patterns {
+			m := re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			indent := len(m[re.SubexpIndex("indent")])
+			kind := normalizeKind(m[re.SubexpIndex("kind")])
+			s := symbol{Kind: kind, Name: m[re.SubexpIndex("name")], Line: i + 1, Signature: strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "{"))}
+			s.Start = docStart(lang, lines, i) + 1
+			for _, doc := range lines[s.Start-1 : i] {
+				s.Doc += strings.TrimSpace(doc) + "\n"
+			}
+			s.End = blockEnd(lang, lines, i, indent) + 1
+			if lang.block == "indent" {
+				for len(classes) > 0 && classes[len(classes)-1].indent >= indent {
+					classes = classes[:len(classes)-1]
+				}
+				if kind == "func" && len(classes) > 0 {
+					s.Kind, s.Receiver = "method", classes[len(classes)-1].name
+				}
+				if kind == "class" {
+					classes = append(classes, class{s.Name, indent})
+				}
+			}
+			syms = append(syms, s)
+			break
+		}
+	}
+	return syms
+}
+
+func normalizeKind(kind string) string {
+	switch {
+	case kind == "def", kind == "fn", strings.HasPrefix(kind, "function"), strings.Contains(kind, "=>"):
+		return "func"
+	case kind == "":
+		return "method"
+	}
+	return kind
+}
+
+// docStart returns the first line of the comments and decorators directly
+// above line i.
+func docStart(lang *codeLang, lines []string, i int) int {
+	start := i
+	for j := i - 1; j >= 0; j-- {
+		t := strings.TrimSpace(lines[j])
+		kept := false
+		for _, p := range lang.comments {
+			if t != "" && strings.HasPrefix(t, p) {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			break
+		}
+		start = j
+	}
+	return start
+}
+
+// blockEnd returns the last line of the declaration starting at line i.
+func blockEnd(lang *codeLang, lines []string, i, indent int) int {
+	const maxLines = 5000
+	last := min(len(lines)-1, i+maxLines)
+	switch lang.block {
+	case "indent":
+		end := i
+		for j := i + 1; j <= last; j++ {
+			t := strings.TrimSpace(lines[j])
+			if t == "" {
+				continue
+			}
+			if leadingSpace(lines[j]) <= indent {
+				break
+			}
+			end = j
+		}
+		return end
+	case "end":
+		for j := i + 1; j <= last; j++ {
+			if leadingSpace(lines[j]) == indent && strings.HasPrefix(strings.TrimSpace(lines[j]), "end") {
+				return j
+			}
+		}
+		return i
+	}
+
+	// Braces: count them, skipping strings and line comments, until the
+	// first one opened closes. A ';' before any '{' ends a bodiless
+	// declaration. Single quotes are left alone since Rust lifetimes use
+	// them unpaired; only brace character literals are dropped.
+	depth, opened := 0, false
+	for j := i; j <= last; j++ {
+		var quote rune
+		prev := rune(0)
+	chars:
+		for _, c := range braceLiterals.Replace(lines[j]) {
+			switch {
+			case quote != 0:
+				if c == quote && prev != '\\' {
+					quote = 0
+				}
+			case c == '"' || c == '`':
+				quote = c
+			case c == '/' && prev == '/':
+				break chars
+			case c == '{':
+				depth++
+				opened = true
+			case c == '}':
+				depth--
+			case c == ';' && !opened:
+				return j
+			}
+			prev = c
+		}
+		if opened && depth <= 0 {
+			return j
+		}
+	}
+	return i
+}
+
+var braceLiterals = strings.NewReplacer("'{'", "", "'}'", "")
+
+func leadingSpace(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// textReferences returns the lines mentioning name as a whole word. Unlike
+// Go files, comments and strings count.
+func textReferences(src []byte, name string, defs map[int]bool) []reference {
+	re := regexp.MustCompile(`(?:^|[^\w$])` + regexp.QuoteMeta(name) + `(?:[^\w$]|$)`)
+	var refs []reference
+	for i, line := range strings.Split(string(src), "\n") {
+		if re.MatchString(line) {
+			refs = append(refs, reference{Line: i + 1, Text: strings.TrimSpace(line), Definition: defs[i+1]})
+		}
+	}
+	return refs
+}
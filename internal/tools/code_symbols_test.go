@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func symbolsTree(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.22\n",
+		"cart/cart.go": `package cart
+
+// Cart holds the items being bought.
+type Cart struct {
+	Items []string
+}
+
+const (
+	MaxItems = 50 // per cart
+	minItems = 1
+)
+
+// Add puts an item in the cart.
+// It ignores duplicates.
+func (c *Cart) Add(item string) {
+	c.Items = append(c.Items, item) // Add never fails
+}
+
+func New() *Cart { return &Cart{} }
+`,
+		"cart/cart_test.go": "package cart\n\nfunc helper() { New().Add(\"x\") }\n",
+		"main.go": `package main
+
+import "example.com/shop/cart"
+
+func main() {
+	c := cart.New()
+	c.Add("apple")
+}
+
+func New() {}
+`,
+		"web/app.py": `import os
+
+# Server serves the shop.
+class Server:
+    def start(self, port):
+        if port:
+            return port
+
+        return 80
+
+def New():
+    return Server()
+`,
+		"web/ui.ts": "export function render(x: number) {\n  if (x) { return '}' }\n  return x\n}\nconst add = (a, b) => a + b;\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(name), 0755)
+		os.WriteFile(name, []byte(content), 0644)
+	}
+}
+
+func runSymbols(t *testing.T, args map[string]any) string {
+	t.Helper()
+	b, _ := json.Marshal(args)
+	res, err := (&CodeSymbolsTool{}).Execute(context.Background(), string(b))
+	if err != nil || res.Error != "" {
+		t.Fatalf("code_symbols %s: %v %s", b, err, res.Error)
+	}
+	return res.Output
+}
+
+func TestCodeSymbols_ListGo(t *testing.T) {
+	symbolsTree(t)
+	out := runSymbols(t, map[string]any{"action": "list", "path": "cart"})
+	for _, want := range []string{
+		"(package cart)",
+		"4  type Cart struct",
+		"9  const MaxItems = 50",
+		"15  func (c *Cart) Add(item string)",
+		"19  func New() *Cart",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "helper") {
+		t.Errorf("list included a test file without include_tests:\n%s", out)
+	}
+}
+
+func TestCodeSymbols_DefinitionAndSource(t *testing.T) {
+	symbolsTree(t)
+
+	out := runSymbols(t, map[string]any{"action": "definition", "name": "Cart.Add", "path": "web"})
+	want := filepath.Join("cart", "cart.go") + ":15: func (c *Cart) Add(item string)\n    Add puts an item in the cart.\n    It ignores duplicates.\n"
+	if out != want {
+		t.Errorf("definition (searching the module from a subdirectory):\n%s\nwant:\n%s", out, want)
+	}
+
+	out = runSymbols(t, map[string]any{"action": "definition", "name": "cart.New"})
+	if !strings.Contains(out, "func New() *Cart") || strings.Contains(out, "main.go") {
+		t.Errorf("package-qualified definition:\n%s", out)
+	}
+
+	out = runSymbols(t, map[string]any{"action": "source", "name": "Cart.Add"})
+	if !strings.HasPrefix(out, filepath.Join("cart", "cart.go")+":13-17 (method Cart.Add)\n  13\t// Add puts") ||
+		!strings.HasSuffix(out, "  17\t}\n") {
+		t.Errorf("source:\n%s", out)
+	}
+}
+
+func TestCodeSymbols_References(t *testing.T) {
+	symbolsTree(t)
+
+	out := runSymbols(t, map[string]any{"action": "references", "name": "cart.New"})
+	for _, want := range []string{
+		filepath.Join("cart", "cart.go") + ":19: [definition] func New()",
+		filepath.Join("cart", "cart_test.go") + ":3:",
+		"main.go:6: c := cart.New()",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("references missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "main.go:10") || strings.Contains(out, "app.py") {
+		t.Errorf("references matched another package's New:\n%s", out)
+	}
+
+	// Comments and strings don't count in Go.
+	out = runSymbols(t, map[string]any{"action": "references", "name": "Add", "path": "cart"})
+	if !strings.Contains(out, "cart.go:15: [definition]") || strings.Contains(out, "cart.go:13") {
+		t.Errorf("Add references:\n%s", out)
+	}
+}
+
+func TestCodeSymbols_Heuristic(t *testing.T) {
+	symbolsTree(t)
+	os.Remove("go.mod") // search web/ on its own
+
+	out := runSymbols(t, map[string]any{"action": "list", "path": filepath.Join("web", "app.py")})
+	for _, want := range []string{"(python)", "4  class Server:", "5  def start(self, port):", "11  def New():"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("python list missing %q:\n%s", want, out)
+		}
+	}
+
+	out = runSymbols(t, map[string]any{"action": "source", "name": "Server.start", "path": "web"})
+	if !strings.Contains(out, ":5-9 (method Server.start)") {
+		t.Errorf("python method source:\n%s", out)
+	}
+	out = runSymbols(t, map[string]any{"action": "source", "name": "Server", "path": "web"})
+	if !strings.Contains(out, ":3-9 (class Server)") || !strings.Contains(out, "# Server serves") {
+		t.Errorf("python class source with comment:\n%s", out)
+	}
+
+	out = runSymbols(t, map[string]any{"action": "source", "name": "render", "path": "web"})
+	if !strings.Contains(out, ":1-4 (func render)") {
+		t.Errorf("ts source (brace in a string):\n%s", out)
+	}
+	out = runSymbols(t, map[string]any{"action": "definition", "name": "add", "path": "web"})
+	if !strings.Contains(out, "ui.ts:5: const add = (a, b) => a + b;") {
+		t.Errorf("arrow function definition:\n%s", out)
+	}
+}
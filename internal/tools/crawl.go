@@ -2,17 +2,20 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/chromedp/chromedp"
+	"github.com/jeanpaul/aseity/internal/provider"
 	"github.com/playwright-community/playwright-go"
 )
 
@@ -78,19 +81,42 @@ func (w *WebCrawlTool) Execute(ctx context.Context, rawArgs string) (Result, err
 
 	// 1. Try Playwright (Primary Local)
 	if result, err := w.crawlBatchWithPlaywright(ctx, args); err == nil {
-		return result, nil
+		return attachScreenshots(result), nil
 	}
 
 	// 2. Try Crawl4AI (Service Batch)
 	if w.isCrawl4AIAvailable(ctx) {
 		result, err := w.crawlBatchWithService(ctx, args)
 		if err == nil {
-			return result, nil
+			return attachScreenshots(result), nil
 		}
 	}
 
 	// 3. Fallback to Concurrent Chromedp/HTTP
-	return w.crawlBatchFallback(ctx, args)
+	result, err := w.crawlBatchFallback(ctx, args)
+	return attachScreenshots(result), err
+}
+
+var screenshotMarker = regexp.MustCompile(`\[Screenshot: ([^\]]+)\]`)
+
+// attachScreenshots loads the screenshots referenced in the output as image
+// parts so vision models can see the rendered pages.
+func attachScreenshots(result Result) Result {
+	for _, m := range screenshotMarker.FindAllStringSubmatch(result.Output, -1) {
+		data, err := os.ReadFile(m[1])
+		if err != nil || len(data) == 0 || len(data) > maxImageReadSize {
+			continue
+		}
+		result.Images = append(result.Images, provider.ImagePart("image/png", data))
+	}
+	return result
+}
+
+// screenshotPath is where a page's screenshot is saved: the working
+// directory, so the user can find it.
+func screenshotPath(pageURL string) string {
+	cwd, _ := os.Getwd()
+	return filepath.Join(cwd, fmt.Sprintf("screenshot_%d_%s.png", time.Now().Unix(), sanitizeFilename(pageURL)))
 }
 
 func (w *WebCrawlTool) crawlBatchWithPlaywright(ctx context.Context, args webCrawlArgs) (Result, error) {
@@ -172,9 +198,7 @@ func (w *WebCrawlTool) crawlBatchWithPlaywright(ctx context.Context, args webCra
 
 			// Screenshot
 			if args.Screenshot {
-				cwd, _ := os.Getwd()
-				filename := fmt.Sprintf("screenshot_%d_%s.png", time.Now().Unix(), sanitizeFilename(targetUrl))
-				path := filepath.Join(cwd, filename)
+				path := screenshotPath(targetUrl)
 				if _, err := page.Screenshot(playwright.PageScreenshotOptions{
 					Path: playwright.String(path),
 				}); err == nil {
@@ -266,9 +290,10 @@ func (w *WebCrawlTool) crawlBatchWithService(ctx context.Context, args webCrawlA
 	body, _ := io.ReadAll(resp.Body)
 	var response struct {
 		Results []struct {
-			URL      string `json:"url"`
-			Markdown string `json:"markdown"`
-			HTML     string `json:"html"`
+			URL        string `json:"url"`
+			Markdown   string `json:"markdown"`
+			HTML       string `json:"html"`
+			Screenshot string `json:"screenshot"` // Base64 PNG
 		} `json:"results"`
 	}
 
@@ -284,7 +309,16 @@ func (w *WebCrawlTool) crawlBatchWithService(ctx context.Context, args webCrawlA
 		if content == "" {
 			content = htmlToText(res.HTML)
 		}
-		sb.WriteString(fmt.Sprintf("--- SOURCE: %s ---\n%s\n\n", res.URL, truncateText(content, 2000)))
+		sb.WriteString(fmt.Sprintf("--- SOURCE: %s ---\n%s\n", res.URL, truncateText(content, 2000)))
+		if args.Screenshot && res.Screenshot != "" {
+			if data, err := base64.StdEncoding.DecodeString(res.Screenshot); err == nil {
+				path := screenshotPath(res.URL)
+				if os.WriteFile(path, data, 0644) == nil {
+					sb.WriteString(fmt.Sprintf("[Screenshot: %s]\n", path))
+				}
+			}
+		}
+		sb.WriteString("\n")
 	}
 
 	return Result{Output: sb.String()}, nil
@@ -367,9 +401,7 @@ func (w *WebCrawlTool) crawlSingleFallback(ctx context.Context, urlStr, waitFor
 
 	output := fmt.Sprintf("--- SOURCE: %s (Chromedp) ---\n%s", urlStr, truncateText(textContent, 2000))
 	if screenshot && len(buf) > 0 {
-		cwd, _ := os.Getwd()
-		filename := fmt.Sprintf("screenshot_%d_%s.png", time.Now().Unix(), sanitizeFilename(urlStr))
-		path := filepath.Join(cwd, filename)
+		path := screenshotPath(urlStr)
 		os.WriteFile(path, buf, 0644)
 		output += fmt.Sprintf("\n[Screenshot: %s]", path)
 	}
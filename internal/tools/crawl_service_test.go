@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -50,11 +51,15 @@ func TestWebCrawlTool_Crawl4AIIntegration(t *testing.T) {
 			var results []map[string]interface{}
 
 			for _, u := range urls {
-				results = append(results, map[string]interface{}{
+				res := map[string]interface{}{
 					"url":      u,
 					"markdown": fmt.Sprintf("# Success %s\nContent", u),
 					"html":     "<h1>Success</h1>",
-				})
+				}
+				if reqBody["screenshot"] == true {
+					res["screenshot"] = base64.StdEncoding.EncodeToString([]byte("\x89PNG"))
+				}
+				results = append(results, res)
 			}
 			response["results"] = results
 			json.NewEncoder(w).Encode(response)
@@ -103,6 +108,17 @@ func TestWebCrawlTool_Crawl4AIIntegration(t *testing.T) {
 		!strings.Contains(resultBatch.Output, "Success https://example.com/B") {
 		t.Errorf("Expected batch output to contain both URLs, got: %s", resultBatch.Output)
 	}
+
+	// 5. Screenshots returned by the service are saved and attached
+	t.Chdir(t.TempDir())
+	argsShot, _ := json.Marshal(map[string]interface{}{"url": "https://example.com/shot", "screenshot": true})
+	resultShot, err := tool.Execute(ctx, string(argsShot))
+	if err != nil {
+		t.Fatalf("Screenshot Execution failed: %v", err)
+	}
+	if !strings.Contains(resultShot.Output, "[Screenshot: ") || len(resultShot.Images) != 1 {
+		t.Errorf("Expected a saved and attached screenshot, got %d images: %s", len(resultShot.Images), resultShot.Output)
+	}
 }
 
 func TestWebCrawlTool_Crawl4AIFallback(t *testing.T) {
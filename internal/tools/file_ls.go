@@ -23,6 +23,14 @@ func (f *FileLsTool) Description() string {
 }
 func (f *FileLsTool) NeedsConfirmation() bool { return false }
 
+func (f *FileLsTool) Paths(rawArgs string) []string {
+	var args fileLsArgs
+	if json.Unmarshal([]byte(rawArgs), &args) != nil || args.Path == "" {
+		return []string{"."}
+	}
+	return []string{args.Path}
+}
+
 func (f *FileLsTool) Parameters() any {
 	return map[string]any{
 		"type": "object",
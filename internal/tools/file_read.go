@@ -9,9 +9,22 @@ import (
 	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jeanpaul/aseity/internal/provider"
 )
 
-const maxFileReadSize = 10 * 1024 * 1024 // 10MB limit
+const (
+	maxFileReadSize  = 10 * 1024 * 1024 // 10MB limit
+	maxImageReadSize = 5 * 1024 * 1024  // Most vision APIs reject larger images
+)
+
+// imageMIMETypes lists the image formats returned as image parts for vision models.
+var imageMIMETypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
 
 type FileReadTool struct{}
 
@@ -23,10 +36,18 @@ type fileReadArgs struct {
 
 func (f *FileReadTool) Name() string { return "file_read" }
 func (f *FileReadTool) Description() string {
-	return "Read the contents of a file or multiple files using glob patterns (e.g. 'internal/**/*.go'). Supports Text, PDF, and Excel. Returns content with line numbers (text) or markdown tables (Excel)."
+	return "Read the contents of a file or multiple files using glob patterns (e.g. 'internal/**/*.go'). Supports Text, PDF, Excel, and images (PNG/JPEG/GIF/WebP, shown to vision models). Returns content with line numbers (text) or markdown tables (Excel)."
 }
 func (f *FileReadTool) NeedsConfirmation() bool { return false }
 
+func (f *FileReadTool) Paths(rawArgs string) []string {
+	var args fileReadArgs
+	if json.Unmarshal([]byte(rawArgs), &args) != nil {
+		return nil
+	}
+	return []string{globBase(args.Path)}
+}
+
 func (f *FileReadTool) Parameters() any {
 	return map[string]any{
 		"type": "object",
@@ -39,7 +60,7 @@ func (f *FileReadTool) Parameters() any {
 	}
 }
 
-func (f *FileReadTool) Execute(_ context.Context, rawArgs string) (Result, error) {
+func (f *FileReadTool) Execute(ctx context.Context, rawArgs string) (Result, error) {
 	var args fileReadArgs
 	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
 		return Result{Error: "invalid arguments: " + err.Error()}, nil
@@ -51,7 +72,20 @@ func (f *FileReadTool) Execute(_ context.Context, rawArgs string) (Result, error
 		if err != nil {
 			return Result{Error: "glob error: " + err.Error()}, nil
 		}
+		var skipped []string
+		allowed := matches[:0]
+		for _, match := range matches {
+			if err := checkFound(ctx, match); err != nil {
+				skipped = append(skipped, err.Error())
+				continue
+			}
+			allowed = append(allowed, match)
+		}
+		matches = allowed
 		if len(matches) == 0 {
+			if len(skipped) > 0 {
+				return Result{Error: fmt.Sprintf("no readable files match %s: %s", args.Path, strings.Join(skipped, "; "))}, nil
+			}
 			return Result{Error: fmt.Sprintf("no files found matching pattern: %s", args.Path)}, nil
 		}
 
@@ -61,9 +95,23 @@ func (f *FileReadTool) Execute(_ context.Context, rawArgs string) (Result, error
 		}
 
 		var sb strings.Builder
+		var images []provider.ContentPart
 		sb.WriteString(fmt.Sprintf("Found %d files:\n\n", len(matches)))
+		for _, reason := range skipped {
+			fmt.Fprintf(&sb, "## Skipped: %s\n\n", reason)
+		}
 
 		for _, match := range matches {
+			if isImageFile(match) {
+				summary, img, err := readImage(match)
+				if err != nil {
+					fmt.Fprintf(&sb, "## Error reading %s: %v\n\n", match, err)
+				} else {
+					fmt.Fprintf(&sb, "## File: %s\n%s\n\n", match, summary)
+					images = append(images, img)
+				}
+				continue
+			}
 			content, err := f.readFile(match, 0, 1000) // Default limit 1000 lines per file in batch mode
 			if err != nil {
 				fmt.Fprintf(&sb, "## Error reading %s: %v\n\n", match, err)
@@ -71,7 +119,15 @@ func (f *FileReadTool) Execute(_ context.Context, rawArgs string) (Result, error
 				fmt.Fprintf(&sb, "## File: %s\n%s\n\n", match, content)
 			}
 		}
-		return Result{Output: sb.String()}, nil
+		return Result{Output: sb.String(), Images: images}, nil
+	}
+
+	if isImageFile(args.Path) {
+		summary, img, err := readImage(args.Path)
+		if err != nil {
+			return Result{Error: err.Error()}, nil
+		}
+		return Result{Output: summary, Images: []provider.ContentPart{img}}, nil
 	}
 
 	// Single file read
@@ -86,6 +142,33 @@ func isGlob(path string) bool {
 	return strings.ContainsAny(path, "*?[{")
 }
 
+func isImageFile(path string) bool {
+	_, ok := imageMIMETypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// readImage loads an image file as a content part plus a short text summary,
+// which is all a text-only model will see.
+func readImage(path string) (string, provider.ContentPart, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", provider.ContentPart{}, err
+	}
+	if info.IsDir() {
+		return "", provider.ContentPart{}, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxImageReadSize {
+		return "", provider.ContentPart{}, fmt.Errorf("image too large (%d bytes)", info.Size())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", provider.ContentPart{}, err
+	}
+	mimeType := imageMIMETypes[strings.ToLower(filepath.Ext(path))]
+	summary := fmt.Sprintf("[Image: %s (%s, %d bytes)]", path, mimeType, len(data))
+	return summary, provider.ImagePart(mimeType, data), nil
+}
+
 func (f *FileReadTool) readFile(path string, offset, limit int) (string, error) {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -1,91 +1,424 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	searchDefaultLimit = 100
+	searchMaxLimit     = 1000
+	searchMaxLineLen   = 500
+	searchMaxFileSize  = 10 * 1024 * 1024
 )
 
+// searchSkipDirs are never searched unless no_ignore is set, whether or
+// not a .gitignore lists them. .git is never searched.
+var searchSkipDirs = map[string]bool{
+	"node_modules": true,
+	".venv":        true,
+	"__pycache__":  true,
+}
+
 type FileSearchTool struct{}
 
 type fileSearchArgs struct {
-	Pattern string `json:"pattern,omitempty"`
-	Path    string `json:"path,omitempty"`
-	Grep    string `json:"grep,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Grep        string   `json:"grep,omitempty"`
+	FixedString bool     `json:"fixed_string,omitempty"`
+	Case        string   `json:"case,omitempty"`
+	Include     []string `json:"include,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"`
+	Context     int      `json:"context,omitempty"`
+	Before      int      `json:"before,omitempty"`
+	After       int      `json:"after,omitempty"`
+	FilesOnly   bool     `json:"files_only,omitempty"`
+	NoIgnore    bool     `json:"no_ignore,omitempty"`
+	Limit       int      `json:"limit,omitempty"`
+	Cursor      string   `json:"cursor,omitempty"`
 }
 
 func (f *FileSearchTool) Name() string { return "file_search" }
 func (f *FileSearchTool) Description() string {
-	return "Search for files by glob pattern or search file contents with a text query. Provide 'pattern' for glob matching or 'grep' for content search."
+	return "Find files by glob ('pattern', e.g. '**/*.go') and/or search their contents with a regular expression ('grep', RE2 syntax). " +
+		"Skips .git, node_modules, binary files and anything in .gitignore or .aseityignore. " +
+		"Supports context lines, include/exclude globs and a files-only mode. If results are truncated, pass the returned 'cursor' to continue."
 }
 func (f *FileSearchTool) NeedsConfirmation() bool { return false }
 
+func (f *FileSearchTool) Paths(rawArgs string) []string {
+	var args fileSearchArgs
+	if json.Unmarshal([]byte(rawArgs), &args) != nil || args.Path == "" {
+		return []string{"."}
+	}
+	return []string{args.Path}
+}
+
 func (f *FileSearchTool) Parameters() any {
 	return map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"pattern": map[string]any{"type": "string", "description": "Glob pattern (e.g. '**/*.go')"},
-			"path":    map[string]any{"type": "string", "description": "Directory to search in (default: current dir)"},
-			"grep":    map[string]any{"type": "string", "description": "Text to search for in file contents"},
+			"pattern":      map[string]any{"type": "string", "description": "Glob for file paths relative to 'path' (e.g. '**/*.go', 'cmd/*/main.go'). Without a '/', it matches file names at any depth."},
+			"path":         map[string]any{"type": "string", "description": "File or directory to search in (default: current dir)"},
+			"grep":         map[string]any{"type": "string", "description": "Regular expression (RE2) to search for in file contents"},
+			"fixed_string": map[string]any{"type": "boolean", "description": "Treat 'grep' as literal text, not a regex"},
+			"case":         map[string]any{"type": "string", "enum": []string{"smart", "sensitive", "insensitive"}, "description": "Case matching for 'grep'. smart (default) ignores case unless the query has an uppercase letter."},
+			"include":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Only search files matching one of these globs"},
+			"exclude":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Skip files matching any of these globs"},
+			"context":      map[string]any{"type": "integer", "description": "Lines of context before and after each match"},
+			"before":       map[string]any{"type": "integer", "description": "Lines of context before each match (overrides 'context')"},
+			"after":        map[string]any{"type": "integer", "description": "Lines of context after each match (overrides 'context')"},
+			"files_only":   map[string]any{"type": "boolean", "description": "With 'grep', list the matching files instead of the matching lines"},
+			"no_ignore":    map[string]any{"type": "boolean", "description": "Also search ignored files and directories such as node_modules"},
+			"limit":        map[string]any{"type": "integer", "description": fmt.Sprintf("Max results (default %d, max %d)", searchDefaultLimit, searchMaxLimit)},
+			"cursor":       map[string]any{"type": "string", "description": "Continue a truncated search from the cursor it returned"},
 		},
 	}
 }
 
-func (f *FileSearchTool) Execute(_ context.Context, rawArgs string) (Result, error) {
+func (f *FileSearchTool) Execute(ctx context.Context, rawArgs string) (Result, error) {
 	var args fileSearchArgs
 	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
 		return Result{Error: "invalid arguments: " + err.Error()}, nil
 	}
+	if args.Pattern == "" && args.Grep == "" {
+		return Result{Error: "provide 'pattern', 'grep' or both"}, nil
+	}
+	for _, g := range append([]string{args.Pattern}, append(args.Include, args.Exclude...)...) {
+		if !doublestar.ValidatePattern(g) {
+			return Result{Error: fmt.Sprintf("invalid glob: %q", g)}, nil
+		}
+	}
+
+	switch args.Case {
+	case "", "smart", "sensitive", "insensitive":
+	default:
+		return Result{Error: fmt.Sprintf("unknown case mode %q; use smart, sensitive or insensitive", args.Case)}, nil
+	}
+
+	s := &searcher{ctx: ctx, args: args, limit: args.Limit, before: args.Context, after: args.Context}
+	if args.Before > 0 {
+		s.before = args.Before
+	}
+	if args.After > 0 {
+		s.after = args.After
+	}
+	if s.limit <= 0 {
+		s.limit = searchDefaultLimit
+	}
+	s.limit = min(s.limit, searchMaxLimit)
+	if args.Cursor != "" {
+		n, err := strconv.Atoi(args.Cursor)
+		if err != nil || n < 0 {
+			return Result{Error: fmt.Sprintf("invalid cursor %q; pass the cursor a previous search returned", args.Cursor)}, nil
+		}
+		s.skip = n
+	}
+	if args.Grep != "" {
+		re, err := compileSearch(args)
+		if err != nil {
+			return Result{Error: fmt.Sprintf("invalid regex: %v (set fixed_string to search for literal text)", err)}, nil
+		}
+		s.re = re
+	}
 
 	root := args.Path
 	if root == "" {
 		root = "."
 	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return Result{Error: err.Error()}, nil
+	}
+	s.walkRoot = root
+	s.root, _ = filepath.Abs(root)
+	if !args.NoIgnore && info.IsDir() {
+		s.ignores.loadParents(s.root)
+	}
 
-	if args.Pattern != "" {
-		var matches []string
-		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-			if err != nil || info.IsDir() {
-				return nil
-			}
-			matched, _ := filepath.Match(args.Pattern, filepath.Base(path))
-			if matched {
-				matches = append(matches, path)
-			}
-			if len(matches) >= 100 {
-				return filepath.SkipAll
-			}
-			return nil
-		})
-		return Result{Output: strings.Join(matches, "\n")}, nil
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable; skip it
+		}
+		if s.more || ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		return s.visit(p, d)
+	})
+	if err != nil {
+		return Result{Error: err.Error()}, nil
 	}
+	if ctx.Err() != nil {
+		return Result{Error: "search cancelled: " + ctx.Err().Error()}, nil
+	}
+	return Result{Output: s.output()}, nil
+}
 
-	if args.Grep != "" {
-		var results []string
-		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-			if err != nil || info.IsDir() || info.Size() > 1<<20 {
-				return nil
-			}
-			data, err := os.ReadFile(path)
-			if err != nil {
-				return nil
+// compileSearch turns the grep argument into a regexp, honouring
+// fixed_string and the case mode.
+func compileSearch(args fileSearchArgs) (*regexp.Regexp, error) {
+	expr := args.Grep
+	if args.FixedString {
+		expr = regexp.QuoteMeta(expr)
+	}
+	switch args.Case {
+	case "", "smart":
+		re, err := syntax.Parse(expr, syntax.Perl)
+		if err != nil {
+			return nil, err
+		}
+		if !hasUpperLiteral(re) {
+			expr = "(?i)" + expr
+		}
+	case "insensitive":
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// hasUpperLiteral reports whether re matches an uppercase letter literally.
+// Escapes such as \S or \W and the bounds of classes like [A-Z] don't count.
+func hasUpperLiteral(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpLiteral && slices.ContainsFunc(re.Rune, unicode.IsUpper) {
+		return true
+	}
+	return slices.ContainsFunc(re.Sub, hasUpperLiteral)
+}
+
+// searcher walks one search, counting results so a cursor can skip the ones
+// an earlier call already returned.
+type searcher struct {
+	ctx           context.Context
+	args          fileSearchArgs
+	walkRoot      string // path as given; results are shown under it
+	root          string // absolute
+	re            *regexp.Regexp
+	before, after int
+	limit, skip   int
+	ignores       ignoreList
+
+	out     strings.Builder
+	seen    int  // results found, including skipped ones
+	shown   int  // results written
+	more    bool // a result past the limit exists
+	grouped bool // a context group was written, so the next needs a separator
+}
+
+func (s *searcher) visit(p string, d fs.DirEntry) error {
+	rel, err := filepath.Rel(s.walkRoot, p)
+	if err != nil {
+		return nil
+	}
+	abs := filepath.Join(s.root, rel)
+	isRoot := rel == "."
+	if !isRoot && checkFound(s.ctx, p) != nil {
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
+	if d.IsDir() {
+		if !isRoot && (d.Name() == ".git" || !s.args.NoIgnore && (searchSkipDirs[d.Name()] || s.ignores.ignored(abs, true))) {
+			return filepath.SkipDir
+		}
+		if !s.args.NoIgnore {
+			s.ignores.load(abs)
+		}
+		return nil
+	}
+	if !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+		return nil
+	}
+	if !s.args.NoIgnore && s.ignores.ignored(abs, false) {
+		return nil
+	}
+	if isRoot {
+		rel = filepath.Base(abs)
+	}
+	if !s.wanted(filepath.ToSlash(rel)) {
+		return nil
+	}
+
+	if s.re == nil {
+		s.addFile(p)
+		return nil
+	}
+	// Don't read through symlinks: they can point outside the workspace.
+	if d.Type()&fs.ModeSymlink != 0 {
+		return nil
+	}
+	s.grep(p)
+	return nil
+}
+
+// wanted applies pattern, include and exclude to a slash-separated path
+// relative to the search root.
+func (s *searcher) wanted(rel string) bool {
+	if s.args.Pattern != "" && !matchSearchGlob(s.args.Pattern, rel) {
+		return false
+	}
+	if len(s.args.Include) > 0 {
+		ok := false
+		for _, g := range s.args.Include {
+			ok = ok || matchSearchGlob(g, rel)
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, g := range s.args.Exclude {
+		if matchSearchGlob(g, rel) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSearchGlob matches a glob against the whole relative path, or just
+// the file name when the glob has no slash.
+func matchSearchGlob(glob, rel string) bool {
+	if !strings.Contains(glob, "/") {
+		rel = rel[strings.LastIndex(rel, "/")+1:]
+	}
+	ok, _ := doublestar.Match(glob, rel)
+	return ok
+}
+
+// take reports whether the next result should be written, counting it
+// either way.
+func (s *searcher) take() bool {
+	if s.seen < s.skip {
+		s.seen++
+		return false
+	}
+	if s.shown >= s.limit {
+		s.more = true
+		return false
+	}
+	s.seen++
+	s.shown++
+	return true
+}
+
+func (s *searcher) addFile(p string) {
+	if s.take() {
+		s.out.WriteString(p + "\n")
+	}
+}
+
+func (s *searcher) grep(p string) {
+	info, err := os.Stat(p)
+	if err != nil || info.Size() > searchMaxFileSize {
+		return
+	}
+	data, err := os.ReadFile(p)
+	if err != nil || isBinary(data) {
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+
+	if s.args.FilesOnly {
+		for _, line := range lines {
+			if s.re.MatchString(line) {
+				s.addFile(p)
+				return
 			}
-			lines := strings.Split(string(data), "\n")
-			for i, line := range lines {
-				if strings.Contains(line, args.Grep) {
-					results = append(results, fmt.Sprintf("%s:%d: %s", path, i+1, line))
-					if len(results) >= 100 {
-						return filepath.SkipAll
-					}
-				}
+		}
+		return
+	}
+
+	var hits []int
+	for i, line := range lines {
+		if !s.re.MatchString(line) {
+			continue
+		}
+		if s.take() {
+			hits = append(hits, i)
+		}
+		if s.more {
+			break
+		}
+	}
+	s.writeHits(p, lines, hits)
+}
+
+// writeHits writes the matching lines as "path:N: text" and their context
+// as "path-N- text", with "--" between groups that aren't contiguous.
+func (s *searcher) writeHits(p string, lines []string, hits []int) {
+	if len(hits) == 0 {
+		return
+	}
+	isHit := make(map[int]bool, len(hits))
+	for _, h := range hits {
+		isHit[h] = true
+	}
+	last := -1
+	for _, h := range hits {
+		start := max(h-s.before, last+1)
+		end := min(h+s.after, len(lines)-1)
+		if start > end {
+			continue
+		}
+		if (s.before > 0 || s.after > 0) && s.grouped && start > last+1 {
+			s.out.WriteString("--\n")
+		}
+		for i := start; i <= end; i++ {
+			sep := "-"
+			if isHit[i] {
+				sep = ":"
 			}
-			return nil
-		})
-		return Result{Output: strings.Join(results, "\n")}, nil
+			fmt.Fprintf(&s.out, "%s%s%d%s %s\n", p, sep, i+1, sep, truncateLine(lines[i]))
+		}
+		last = end
+		s.grouped = true
+	}
+}
+
+func (s *searcher) output() string {
+	if s.shown == 0 {
+		if s.skip > 0 {
+			return fmt.Sprintf("No more results after cursor %d.", s.skip)
+		}
+		if s.re != nil {
+			return "No matches found."
+		}
+		return "No files found."
 	}
+	out := s.out.String()
+	if s.more {
+		next := s.skip + s.shown
+		out += fmt.Sprintf("\n[Truncated: showing results %d-%d. More remain; call again with cursor %q to continue.]", s.skip+1, next, strconv.Itoa(next))
+	}
+	return out
+}
 
-	return Result{Error: "provide either 'pattern' or 'grep'"}, nil
+// isBinary reports whether data looks binary: a NUL byte near the start,
+// the same test git uses.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+func truncateLine(line string) string {
+	if len(line) <= searchMaxLineLen {
+		return line
+	}
+	return strings.ToValidUTF8(line[:searchMaxLineLen], "") + " ..."
 }
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func searchTree(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		".gitignore":                 "build/\n*.log\n!keep.log\n",
+		"main.go":                    "package main\n\nfunc main() {\n\tRun()\n}\n",
+		"cmd/tool/main.go":           "package main\n\n// run the tool\nfunc main() {}\n",
+		"internal/app/app.go":        "package app\n\nfunc Run() {}\n",
+		"internal/app/.aseityignore": "secret.go\n",
+		"internal/app/secret.go":     "package app\n\nfunc Run() {} // hidden\n",
+		"build/out.go":               "func Run() {}\n",
+		"debug.log":                  "Run\n",
+		"keep.log":                   "Run\n",
+		"node_modules/x/index.js":    "Run()\n",
+		".git/HEAD":                  "Run\n",
+		"data.bin":                   "Run\x00\x01",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(name), 0755)
+		os.WriteFile(name, []byte(content), 0644)
+	}
+	os.Mkdir(".git", 0755)
+}
+
+func runSearch(t *testing.T, args map[string]any) Result {
+	t.Helper()
+	b, _ := json.Marshal(args)
+	res, err := (&FileSearchTool{}).Execute(context.Background(), string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestFileSearch_Doublestar(t *testing.T) {
+	searchTree(t)
+	res := runSearch(t, map[string]any{"pattern": "**/*.go"})
+	got := strings.Fields(res.Output)
+	want := []string{filepath.Join("cmd", "tool", "main.go"), filepath.Join("internal", "app", "app.go"), "main.go"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("**/*.go = %v, want %v", got, want)
+	}
+
+	res = runSearch(t, map[string]any{"pattern": "cmd/*/main.go"})
+	if strings.TrimSpace(res.Output) != filepath.Join("cmd", "tool", "main.go") {
+		t.Errorf("cmd/*/main.go = %q", res.Output)
+	}
+
+	res = runSearch(t, map[string]any{"pattern": "*.log"})
+	if strings.TrimSpace(res.Output) != "keep.log" {
+		t.Errorf("*.log honouring negation = %q", res.Output)
+	}
+}
+
+func TestFileSearch_Grep(t *testing.T) {
+	searchTree(t)
+
+	res := runSearch(t, map[string]any{"grep": `Run\(\)`, "files_only": true})
+	got := strings.Fields(res.Output)
+	if strings.Join(got, " ") != filepath.Join("internal", "app", "app.go")+" main.go" {
+		t.Errorf("files_only = %v (ignored, binary or .git files leaked?)", got)
+	}
+
+	res = runSearch(t, map[string]any{"grep": "Run()", "fixed_string": true, "no_ignore": true, "files_only": true})
+	for _, want := range []string{filepath.Join("node_modules", "x", "index.js"), filepath.Join("build", "out.go")} {
+		if !strings.Contains(res.Output, want) {
+			t.Errorf("no_ignore missed %s: %q", want, res.Output)
+		}
+	}
+	if strings.Contains(res.Output, ".git") {
+		t.Errorf("searched .git: %q", res.Output)
+	}
+
+	// Smart case: lowercase ignores case, uppercase doesn't.
+	if res := runSearch(t, map[string]any{"grep": "run the", "files_only": true}); !strings.Contains(res.Output, "cmd") {
+		t.Errorf("smart case lowercase: %q", res.Output)
+	}
+	if res := runSearch(t, map[string]any{"grep": "Run the"}); res.Output != "No matches found." {
+		t.Errorf("smart case uppercase: %q", res.Output)
+	}
+	if res := runSearch(t, map[string]any{"grep": `\Wrun\(`, "files_only": true}); !strings.Contains(res.Output, "app.go") {
+		t.Errorf("smart case treated the escape \\W as uppercase: %q", res.Output)
+	}
+	if res := runSearch(t, map[string]any{"grep": "run", "case": "loose"}); !strings.Contains(res.Error, "unknown case mode") {
+		t.Errorf("unknown case mode error = %q", res.Error)
+	}
+
+	if res := runSearch(t, map[string]any{"grep": "Run("}); !strings.Contains(res.Error, "fixed_string") {
+		t.Errorf("bad regex error = %q", res.Error)
+	}
+}
+
+func TestFileSearch_ContextAndIncludes(t *testing.T) {
+	searchTree(t)
+	res := runSearch(t, map[string]any{"grep": "func", "include": []string{"*.go"}, "exclude": []string{"cmd/**"}, "context": 1})
+	want := "internal/app/app.go-2- \ninternal/app/app.go:3: func Run() {}\n--\nmain.go-2- \nmain.go:3: func main() {\nmain.go-4- \tRun()\n"
+	if res.Output != filepath.FromSlash(want) {
+		t.Errorf("context output:\n%s\nwant:\n%s", res.Output, want)
+	}
+}
+
+func TestFileSearch_Cursor(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var content strings.Builder
+	for i := 0; i < 25; i++ {
+		content.WriteString("match\n")
+	}
+	os.WriteFile("a.txt", []byte(content.String()), 0644)
+
+	cursorRe := regexp.MustCompile(`cursor "(\d+)"`)
+	var lines []string
+	args := map[string]any{"grep": "match", "limit": 10}
+	for calls := 0; ; calls++ {
+		if calls > 3 {
+			t.Fatal("cursor never ran out")
+		}
+		res := runSearch(t, args)
+		for _, l := range strings.Split(res.Output, "\n") {
+			if strings.HasPrefix(l, "a.txt:") {
+				lines = append(lines, l)
+			}
+		}
+		m := cursorRe.FindStringSubmatch(res.Output)
+		if m == nil {
+			break
+		}
+		if !strings.Contains(res.Output, "Truncated") {
+			t.Errorf("truncation not reported: %q", res.Output)
+		}
+		args["cursor"] = m[1]
+	}
+	if len(lines) != 25 || lines[24] != "a.txt:25: match" {
+		t.Errorf("paged through %d lines, last %q", len(lines), lines[len(lines)-1])
+	}
+}
+
+func TestIgnoreList_ParentRules(t *testing.T) {
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	os.MkdirAll(filepath.Join(root, "sub", "gen"), 0755)
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("/sub/gen/\n# comment\n\\#literal\n"), 0644)
+
+	var l ignoreList
+	l.loadParents(filepath.Join(root, "sub"))
+	if !l.ignored(filepath.Join(root, "sub", "gen"), true) {
+		t.Error("anchored rule from the repository root not applied")
+	}
+	if l.ignored(filepath.Join(root, "other", "sub", "gen"), true) {
+		t.Error("anchored rule matched at the wrong depth")
+	}
+	if !l.ignored(filepath.Join(root, "#literal"), false) {
+		t.Error(`escaped "\#" pattern not applied`)
+	}
+}
@@ -11,62 +11,97 @@ import (
 	"github.com/hexops/gotextdiff"
 	"github.com/hexops/gotextdiff/myers"
 	"github.com/hexops/gotextdiff/span"
+
+	"github.com/jeanpaul/aseity/internal/checkpoint"
 )
 
 type FileWriteTool struct{}
 
 type fileWriteArgs struct {
-	Path      string `json:"path"`
-	Content   string `json:"content,omitempty"`
-	OldString string `json:"old_string,omitempty"`
-	NewString string `json:"new_string,omitempty"`
+	Path       string     `json:"path"`
+	Content    string     `json:"content,omitempty"`
+	OldString  string     `json:"old_string,omitempty"`
+	NewString  string     `json:"new_string,omitempty"`
+	ReplaceAll bool       `json:"replace_all,omitempty"`
+	Edits      []fileEdit `json:"edits,omitempty"`
+}
+
+// fileEdit is one replacement. Without ReplaceAll, OldString must occur
+// exactly once.
+type fileEdit struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
 }
 
 func (f *FileWriteTool) Name() string { return "file_write" }
 func (f *FileWriteTool) Description() string {
-	return "Write or edit a file. Provide 'content' to overwrite the entire file, or 'old_string' and 'new_string' to make a targeted replacement."
+	return "Write or edit a file. Provide 'content' to overwrite the entire file, 'old_string' and 'new_string' to make a targeted replacement, " +
+		"or 'edits' to make several replacements in one call. Edits apply in order, each to the result of the last, and none are written unless all match."
 }
 func (f *FileWriteTool) NeedsConfirmation() bool { return true }
 
+func (f *FileWriteTool) Paths(rawArgs string) []string {
+	var args fileWriteArgs
+	if json.Unmarshal([]byte(rawArgs), &args) != nil {
+		return nil
+	}
+	return []string{args.Path}
+}
+
 func (f *FileWriteTool) Parameters() any {
 	return map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"path":       map[string]any{"type": "string", "description": "File path to write to"},
-			"content":    map[string]any{"type": "string", "description": "Full file content (overwrites entire file)"},
-			"old_string": map[string]any{"type": "string", "description": "Text to find and replace"},
-			"new_string": map[string]any{"type": "string", "description": "Replacement text"},
+			"path":        map[string]any{"type": "string", "description": "File path to write to"},
+			"content":     map[string]any{"type": "string", "description": "Full file content (overwrites entire file)"},
+			"old_string":  map[string]any{"type": "string", "description": "Text to find and replace"},
+			"new_string":  map[string]any{"type": "string", "description": "Replacement text"},
+			"replace_all": map[string]any{"type": "boolean", "description": "Replace every occurrence of old_string instead of requiring a unique match"},
+			"edits": map[string]any{
+				"type":        "array",
+				"description": "Several replacements applied together, all or nothing",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"old_string":  map[string]any{"type": "string", "description": "Text to find; must be unique unless replace_all is set"},
+						"new_string":  map[string]any{"type": "string", "description": "Replacement text"},
+						"replace_all": map[string]any{"type": "boolean", "description": "Replace every occurrence"},
+					},
+					"required": []string{"old_string", "new_string"},
+				},
+			},
 		},
 		"required": []string{"path"},
 	}
 }
 
-func (f *FileWriteTool) Execute(_ context.Context, rawArgs string) (Result, error) {
+func (f *FileWriteTool) Execute(ctx context.Context, rawArgs string) (Result, error) {
 	var args fileWriteArgs
 	if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
 		return Result{Error: "invalid arguments: " + err.Error()}, nil
 	}
 
 	if args.OldString != "" {
+		args.Edits = append([]fileEdit{{OldString: args.OldString, NewString: args.NewString, ReplaceAll: args.ReplaceAll}}, args.Edits...)
+	}
+	if len(args.Edits) > 0 {
 		data, err := os.ReadFile(args.Path)
 		if err != nil {
 			return Result{Error: err.Error()}, nil
 		}
-		content := string(data)
-		if !strings.Contains(content, args.OldString) {
-			return Result{Error: "old_string not found in file"}, nil
-		}
-		count := strings.Count(content, args.OldString)
-		if count > 1 {
-			return Result{Error: "old_string matches multiple locations; provide more context to make it unique"}, nil
+		content, err := applyEdits(string(data), args.Edits)
+		if err != nil {
+			return Result{Error: err.Error()}, nil
 		}
-		content = strings.Replace(content, args.OldString, args.NewString, 1)
 
 		// Calculate diff
-		edits := myers.ComputeEdits(span.URIFromPath(args.Path), string(data), content)
-		diff := fmt.Sprint(gotextdiff.ToUnified(args.Path, args.Path, string(data), edits))
+		diff := unifiedDiff(args.Path, string(data), content)
 
-		if err := os.WriteFile(args.Path, []byte(content), 0644); err != nil {
+		if err := checkpoint.Save(ctx, f.Name(), args.Path); err != nil {
+			return Result{Error: "checkpoint failed, file not changed: " + err.Error()}, nil
+		}
+		if err := writeFileAtomic(args.Path, []byte(content)); err != nil {
 			return Result{Error: err.Error()}, nil
 		}
 		return Result{
@@ -85,6 +120,9 @@ func (f *FileWriteTool) Execute(_ context.Context, rawArgs string) (Result, erro
 		oldContent = string(data)
 	}
 
+	if err := checkpoint.Save(ctx, f.Name(), args.Path); err != nil {
+		return Result{Error: "checkpoint failed, file not changed: " + err.Error()}, nil
+	}
 	if err := os.MkdirAll(filepath.Dir(args.Path), 0755); err != nil {
 		return Result{Error: err.Error()}, nil
 	}
@@ -93,8 +131,7 @@ func (f *FileWriteTool) Execute(_ context.Context, rawArgs string) (Result, erro
 	}
 
 	// Calculate diff
-	edits := myers.ComputeEdits(span.URIFromPath(args.Path), oldContent, args.Content)
-	diff := fmt.Sprint(gotextdiff.ToUnified(args.Path, args.Path, oldContent, edits))
+	diff := unifiedDiff(args.Path, oldContent, args.Content)
 
 	return Result{
 		Output: fmt.Sprintf("File written successfully. Changes:\n\n```diff\n%s\n```", diff),
@@ -105,3 +142,68 @@ func (f *FileWriteTool) Execute(_ context.Context, rawArgs string) (Result, erro
 		},
 	}, nil
 }
+
+// applyEdits applies edits to content in order. It fails, changing nothing,
+// if any edit doesn't match.
+func applyEdits(content string, edits []fileEdit) (string, error) {
+	for i, e := range edits {
+		// Name the edit only when there are several.
+		prefix := ""
+		if len(edits) > 1 {
+			prefix = fmt.Sprintf("edit %d: ", i+1)
+		}
+		if e.OldString == "" {
+			return "", fmt.Errorf("%sold_string is empty", prefix)
+		}
+		count := strings.Count(content, e.OldString)
+		switch {
+		case count == 0:
+			return "", fmt.Errorf("%sold_string not found in file", prefix)
+		case count > 1 && !e.ReplaceAll:
+			return "", fmt.Errorf("%sold_string matches %d locations; provide more context to make it unique, or set replace_all", prefix, count)
+		}
+		content = strings.ReplaceAll(content, e.OldString, e.NewString)
+	}
+	return content, nil
+}
+
+// unifiedDiff returns the diff between two versions of path.
+func unifiedDiff(path, before, after string) string {
+	edits := myers.ComputeEdits(span.URIFromPath(path), before, after)
+	return fmt.Sprint(gotextdiff.ToUnified(path, path, before, edits))
+}
+
+// writeFileAtomic replaces path with data through a temporary file and a
+// rename, so an interrupted write never leaves it half-written. An existing
+// file keeps its permissions, and a symlink keeps pointing at the file it
+// names, which is the one written.
+func writeFileAtomic(path string, data []byte) error {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeArgs(t *testing.T, args map[string]any) string {
+	t.Helper()
+	b, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFileWriteTool_Edits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	os.WriteFile(path, []byte("package main\n\nfunc a() { log(1) }\nfunc b() { log(1) }\n"), 0600)
+
+	tool := &FileWriteTool{}
+	res, err := tool.Execute(context.Background(), writeArgs(t, map[string]any{
+		"path": path,
+		"edits": []map[string]any{
+			{"old_string": "package main", "new_string": "package app"},
+			{"old_string": "log(1)", "new_string": "log(2)", "replace_all": true},
+		},
+	}))
+	if err != nil || res.Error != "" {
+		t.Fatalf("Execute: %v %s", err, res.Error)
+	}
+	got, _ := os.ReadFile(path)
+	if want := "package app\n\nfunc a() { log(2) }\nfunc b() { log(2) }\n"; string(got) != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want the original 0600", info.Mode().Perm())
+	}
+	data, _ := res.Data.(map[string]any)
+	if diff, _ := data["diff"].(string); !strings.Contains(diff, "+package app") {
+		t.Errorf("diff = %q", diff)
+	}
+}
+
+func TestFileWriteTool_EditThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target, link := filepath.Join(dir, "real.conf"), filepath.Join(dir, "link.conf")
+	os.WriteFile(target, []byte("port = 1\n"), 0644)
+	os.Symlink(target, link)
+
+	res, err := (&FileWriteTool{}).Execute(context.Background(), writeArgs(t, map[string]any{
+		"path":  link,
+		"edits": []map[string]any{{"old_string": "port = 1", "new_string": "port = 2"}},
+	}))
+	if err != nil || res.Error != "" {
+		t.Fatalf("Execute: %v %s", err, res.Error)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("the symlink was replaced by a regular file: %v", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "port = 2\n" {
+		t.Errorf("target = %q, want the edit written through the link", got)
+	}
+}
+
+func TestFileWriteTool_EditsAllOrNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	original := "one\ntwo\ntwo\n"
+	os.WriteFile(path, []byte(original), 0644)
+
+	tool := &FileWriteTool{}
+	tests := []struct {
+		name  string
+		edits []map[string]any
+		want  string
+	}{
+		{"missing", []map[string]any{
+			{"old_string": "one", "new_string": "1"},
+			{"old_string": "three", "new_string": "3"},
+		}, "edit 2: old_string not found"},
+		{"ambiguous", []map[string]any{
+			{"old_string": "one", "new_string": "1"},
+			{"old_string": "two", "new_string": "2"},
+		}, "edit 2: old_string matches 2 locations"},
+		{"empty", []map[string]any{
+			{"old_string": "", "new_string": "x"},
+		}, "old_string is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := tool.Execute(context.Background(), writeArgs(t, map[string]any{"path": path, "edits": tt.edits}))
+			if !strings.Contains(res.Error, tt.want) {
+				t.Errorf("error = %q, want %q", res.Error, tt.want)
+			}
+			if got, _ := os.ReadFile(path); string(got) != original {
+				t.Errorf("file changed to %q by a failed edit", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// ignoreFileNames are read in every directory a search walks. .aseityignore
+// uses the .gitignore syntax and hides files from the agent without
+// touching git.
+var ignoreFileNames = []string{".gitignore", ".aseityignore"}
+
+// ignoreRule is one pattern line of an ignore file.
+type ignoreRule struct {
+	base     string // absolute directory holding the ignore file
+	pattern  string // slash-separated
+	anchored bool   // pattern had a slash, so it matches from base rather than any depth
+	negate   bool   // "!pattern" re-includes
+	dirOnly  bool   // "pattern/" matches only directories
+}
+
+// ignoreList holds the rules of every ignore file seen so far. Rules only
+// apply below their own directory, and later (deeper) rules override
+// earlier ones, as with git.
+type ignoreList struct {
+	rules []ignoreRule
+}
+
+// loadParents reads the ignore files above dir, up to the enclosing git
+// repository's root, so a search started in a subdirectory still honours
+// the repository's .gitignore.
+func (l *ignoreList) loadParents(dir string) {
+	var dirs []string
+	for d := dir; !exists(filepath.Join(d, ".git")); {
+		parent := filepath.Dir(d)
+		if parent == d {
+			return // not in a repository; parent directories don't apply
+		}
+		d = parent
+		dirs = append(dirs, d)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		l.load(dirs[i])
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// load reads the ignore files in dir, which must be absolute.
+func (l *ignoreList) load(dir string) {
+	for _, name := range ignoreFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if r, ok := parseIgnoreLine(dir, line); ok {
+				l.rules = append(l.rules, r)
+			}
+		}
+	}
+}
+
+func parseIgnoreLine(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	r := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // "\#" and "\!" are literal
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" || !doublestar.ValidatePattern(line) {
+		return ignoreRule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// ignored reports whether the absolute path is excluded.
+func (l *ignoreList) ignored(abs string, isDir bool) bool {
+	ignored := false
+	for _, r := range l.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if abs == r.base || !within(r.base, abs) {
+			continue
+		}
+		rel, _ := filepath.Rel(r.base, abs)
+		rel = filepath.ToSlash(rel)
+		if !r.anchored {
+			rel = path.Base(rel)
+		}
+		if ok, _ := doublestar.Match(r.pattern, rel); ok {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/jeanpaul/aseity/internal/provider"
 	"github.com/jeanpaul/aseity/internal/schema"
@@ -13,6 +15,7 @@ type Registry struct {
 	autoApprove map[string]bool
 	allowAll    bool
 	validator   *schema.Validator
+	workspace   *Workspace // nil leaves the file tools unconfined
 }
 
 func NewRegistry(autoApprove []string, allowAll bool) *Registry {
@@ -32,11 +35,56 @@ func (r *Registry) Register(t Tool) {
 	r.tools[t.Name()] = t
 }
 
+// SetWorkspace confines tools that implement PathTool to w.
+func (r *Registry) SetWorkspace(w *Workspace) {
+	r.workspace = w
+}
+
+// Workspace returns the policy set by SetWorkspace, or nil.
+func (r *Registry) Workspace() *Workspace { return r.workspace }
+
+// OutsidePaths returns the paths a call would touch outside the workspace
+// that the user may approve. The agent asks for that approval before
+// running the call with ApproveOutside; denied paths are left for Execute
+// to refuse.
+func (r *Registry) OutsidePaths(name, args string) []string {
+	outside, _ := r.checkPaths(name, args)
+	return outside
+}
+
+// checkPaths holds a call's paths to the workspace policy. It fails for
+// denied paths, and for outside ones when the policy blocks them.
+func (r *Registry) checkPaths(name, args string) ([]string, error) {
+	if r.workspace == nil {
+		return nil, nil
+	}
+	pt, ok := r.tools[name].(PathTool)
+	if !ok {
+		return nil, nil
+	}
+	var outside []string
+	for _, p := range pt.Paths(args) {
+		resolved, access := r.workspace.Check(p)
+		switch access {
+		case AccessDenied:
+			return nil, fmt.Errorf("access to %s is denied by the workspace policy", p)
+		case AccessOutside:
+			if r.workspace.outside == "block" {
+				return nil, fmt.Errorf("%s is outside the workspace (%s); only paths inside it can be used", p, r.workspace.root)
+			}
+			outside = append(outside, resolved)
+		}
+	}
+	return outside, nil
+}
+
 func (r *Registry) Get(name string) (Tool, bool) {
 	t, ok := r.tools[name]
 	return t, ok
 }
 
+// ToolDefs returns the tool definitions sorted by name. A stable order keeps
+// the request prefix identical between turns so providers can cache it.
 func (r *Registry) ToolDefs() []provider.ToolDef {
 	defs := make([]provider.ToolDef, 0, len(r.tools))
 	for _, t := range r.tools {
@@ -46,6 +94,7 @@ func (r *Registry) ToolDefs() []provider.ToolDef {
 			Parameters:  t.Parameters(),
 		})
 	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
 	return defs
 }
 
@@ -63,7 +112,19 @@ func (r *Registry) Execute(ctx context.Context, name, args string, callback func
 		}
 	}
 
-	// 2. Execute
+	// 2. Hold file paths to the workspace
+	outside, err := r.checkPaths(name, args)
+	if err != nil {
+		return Result{Error: err.Error()}, nil
+	}
+	if len(outside) > 0 && !outsideApproved(ctx) {
+		return Result{Error: fmt.Sprintf("%s is outside the workspace (%s) and needs the user's approval", strings.Join(outside, ", "), r.workspace.root)}, nil
+	}
+
+	// 3. Execute
+	if r.workspace != nil {
+		ctx = withWorkspace(ctx, r.workspace)
+	}
 	if callback != nil {
 		if s, ok := t.(Streamer); ok {
 			return s.ExecuteStream(ctx, args, callback)
@@ -94,8 +155,10 @@ func RegisterDefaults(r *Registry, allowedCmds, disallowedCmds []string) {
 	})
 	r.Register(&FileReadTool{})
 	r.Register(&FileWriteTool{})
+	r.Register(&ApplyPatchTool{})
 	r.Register(&FileSearchTool{})
 	r.Register(&FileLsTool{})
+	r.Register(&CodeSymbolsTool{})
 	r.Register(&WebSearchTool{})
 	r.Register(&ReadPageTool{})
 	r.Register(&WebFetchTool{})
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+
+	"github.com/jeanpaul/aseity/internal/checkpoint"
 )
 
 // RunScriptTool writes a script to a temporary file and executes it.
@@ -18,6 +20,17 @@ func NewRunScriptTool() *RunScriptTool {
 
 func (t *RunScriptTool) Name() string            { return "run_script" }
 func (t *RunScriptTool) NeedsConfirmation() bool { return true }
+
+func (t *RunScriptTool) Paths(rawArgs string) []string {
+	var args struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal([]byte(rawArgs), &args) != nil || args.Name == "" {
+		return nil
+	}
+	cwd, _ := os.Getwd()
+	return []string{filepath.Join(cwd, args.Name)}
+}
 func (t *RunScriptTool) Description() string {
 	return "Write and execute a script (bash, python, node) in one step. Useful for complex logic or loops."
 }
@@ -98,6 +111,11 @@ func (t *RunScriptTool) Execute(ctx context.Context, rawArgs string) (Result, er
 	}
 
 	// Write content
+	if args.Name != "" {
+		if err := checkpoint.Save(ctx, t.Name(), filename); err != nil {
+			return Result{Error: "checkpoint failed, script not written: " + err.Error()}, nil
+		}
+	}
 	if err := os.WriteFile(filename, []byte(args.Content), 0755); err != nil {
 		return Result{Error: "failed to write script: " + err.Error()}, nil
 	}
@@ -1,11 +1,16 @@
 package tools
 
-import "context"
+import (
+	"context"
+
+	"github.com/jeanpaul/aseity/internal/provider"
+)
 
 type Result struct {
 	Output string
 	Error  string
-	Data   any // Structured data for TUI rendering (e.g., Table, Diff)
+	Data   any                    // Structured data for TUI rendering (e.g., Table, Diff)
+	Images []provider.ContentPart // Images for vision models; dropped for text-only models
 }
 
 type Tool interface {
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jeanpaul/aseity/internal/config"
+)
+
+// PathTool is an optional interface for tools that read or write the
+// filesystem. Paths returns the paths a call would touch, so the registry
+// can hold it to the workspace policy before it runs. A glob stands for the
+// directory it starts in.
+type PathTool interface {
+	Paths(args string) []string
+}
+
+// Access is the workspace policy's verdict on a path.
+type Access int
+
+const (
+	AccessInside  Access = iota // under the root or an allow glob
+	AccessOutside               // elsewhere; the policy decides whether the user may approve it
+	AccessDenied                // matched a deny glob; never allowed
+)
+
+// Workspace confines the file tools to a root directory, plus allow globs
+// for paths outside it and deny globs for paths that are always off limits.
+type Workspace struct {
+	root    string
+	allow   []string
+	deny    []string
+	outside string // confirm, block or allow
+}
+
+// NewWorkspace builds the policy from config. An empty root means the
+// working directory.
+func NewWorkspace(cfg config.WorkspaceConfig) (*Workspace, error) {
+	root := cfg.Root
+	if root == "" {
+		root = "."
+	}
+	root, err := filepath.Abs(expandHome(root))
+	if err != nil {
+		return nil, fmt.Errorf("workspace root: %w", err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("workspace root %s is not a directory", root)
+	}
+	w := &Workspace{root: resolvePath(root), outside: cfg.Outside}
+	if w.outside == "" {
+		w.outside = "confirm"
+	}
+	if w.allow, err = w.globs(cfg.Allow); err != nil {
+		return nil, fmt.Errorf("workspace allow: %w", err)
+	}
+	if w.deny, err = w.globs(cfg.Deny); err != nil {
+		return nil, fmt.Errorf("workspace deny: %w", err)
+	}
+	return w, nil
+}
+
+// Root returns the resolved workspace root.
+func (w *Workspace) Root() string { return w.root }
+
+// Check resolves path and returns it with the policy's verdict.
+func (w *Workspace) Check(path string) (string, Access) {
+	resolved := resolvePath(path)
+	if matchesAny(w.deny, resolved) {
+		return resolved, AccessDenied
+	}
+	if w.outside == "allow" || within(w.root, resolved) || matchesAny(w.allow, resolved) {
+		return resolved, AccessInside
+	}
+	return resolved, AccessOutside
+}
+
+// globs makes each pattern absolute, relative to the root, with its literal
+// leading directories resolved the same way checked paths are.
+func (w *Workspace) globs(patterns []string) ([]string, error) {
+	var out []string
+	for _, p := range patterns {
+		p = expandHome(p)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(w.root, p)
+		}
+		if !isGlob(p) {
+			out = append(out, resolvePath(p))
+			continue
+		}
+		if !doublestar.ValidatePathPattern(p) {
+			return nil, fmt.Errorf("invalid glob %q", p)
+		}
+		base, rest := doublestar.SplitPattern(filepath.ToSlash(p))
+		base = resolvePath(filepath.FromSlash(base))
+		if rest != "" {
+			base = filepath.Join(base, filepath.FromSlash(rest))
+		}
+		out = append(out, base)
+	}
+	return out, nil
+}
+
+// resolvePath returns path as the OS will see it: absolute, with symlinks
+// and ".." resolved. Components that don't exist yet, as with a file about
+// to be created, are cleaned lexically onto the deepest existing directory;
+// a dangling symlink is followed to where a write would land.
+func resolvePath(path string) string {
+	if !filepath.IsAbs(path) {
+		cwd, _ := os.Getwd()
+		// Not filepath.Join: cleaning "link/.." would skip the link.
+		path = cwd + string(filepath.Separator) + path
+	}
+	return resolveFrom(path, 0)
+}
+
+func resolveFrom(path string, hops int) string {
+	sep := string(filepath.Separator)
+	existing, rest := path, ""
+	for {
+		if real, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(real, rest)
+		}
+		if info, err := os.Lstat(existing); err == nil && info.Mode()&os.ModeSymlink != 0 && hops < 40 {
+			target, err := os.Readlink(existing)
+			if err == nil {
+				if !filepath.IsAbs(target) {
+					target = existing[:strings.LastIndex(existing, sep)+1] + target
+				}
+				return resolveFrom(target+sep+rest, hops+1)
+			}
+		}
+		i := strings.LastIndex(existing, sep)
+		if i < 0 || existing == sep {
+			return filepath.Clean(path)
+		}
+		rest = filepath.Join(existing[i+1:], rest)
+		existing = existing[:i]
+		if existing == "" {
+			existing = sep
+		}
+	}
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// matchesAny reports whether path, or a directory above it, matches one of
+// the globs.
+func matchesAny(globs []string, path string) bool {
+	for _, g := range globs {
+		for p := path; ; p = filepath.Dir(p) {
+			if ok, _ := doublestar.PathMatch(g, p); ok {
+				return true
+			}
+			if filepath.Dir(p) == p {
+				break
+			}
+		}
+	}
+	return false
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, p[1:])
+	}
+	return p
+}
+
+// globBase returns the directory a glob starts in, or path itself when it
+// has no glob characters.
+func globBase(path string) string {
+	if !isGlob(path) {
+		return path
+	}
+	base, _ := doublestar.SplitPattern(filepath.ToSlash(path))
+	return filepath.FromSlash(base)
+}
+
+type outsideApprovalKey struct{}
+
+// ApproveOutside marks ctx as carrying the user's approval for a call to
+// touch paths outside the workspace.
+func ApproveOutside(ctx context.Context) context.Context {
+	return context.WithValue(ctx, outsideApprovalKey{}, true)
+}
+
+func outsideApproved(ctx context.Context) bool {
+	ok, _ := ctx.Value(outsideApprovalKey{}).(bool)
+	return ok
+}
+
+type workspaceKey struct{}
+
+// withWorkspace makes w available to the tool running under ctx, for the
+// files it finds itself.
+func withWorkspace(ctx context.Context, w *Workspace) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, w)
+}
+
+// checkFound holds a file a tool reached by expanding a glob or walking a
+// directory to the workspace policy. The registry only saw where the search
+// started, so a denied file, or a symlink leading out of the workspace, is
+// caught here. Outside paths pass only when the user approved the call.
+func checkFound(ctx context.Context, path string) error {
+	w, _ := ctx.Value(workspaceKey{}).(*Workspace)
+	if w == nil {
+		return nil
+	}
+	resolved, access := w.Check(path)
+	switch {
+	case access == AccessDenied:
+		return fmt.Errorf("access to %s is denied by the workspace policy", path)
+	case access == AccessOutside && (w.outside == "block" || !outsideApproved(ctx)):
+		return fmt.Errorf("%s leads outside the workspace (%s)", path, resolved)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jeanpaul/aseity/internal/config"
+)
+
+func TestWorkspace_Check(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	t.Chdir(root)
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0644)
+	os.Symlink(outside, filepath.Join(root, "escape"))
+	os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(root, "dangling"))
+
+	w, err := NewWorkspace(config.WorkspaceConfig{
+		Deny:  []string{"**/.env"},
+		Allow: []string{filepath.Join(outside, "shared")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want Access
+	}{
+		{"src/main.go", AccessInside},
+		{"new/dir/file.txt", AccessInside},
+		{filepath.Join(root, "src", "..", "README.md"), AccessInside},
+		{"src/../../x", AccessOutside},
+		{"missing/../../x", AccessOutside},
+		{filepath.Join(outside, "secret"), AccessOutside},
+		{"escape/secret", AccessOutside},
+		{"escape/../src", AccessOutside}, // ".." after a symlink leaves from its target
+		{"dangling", AccessOutside},
+		{"src/.env", AccessDenied},
+		{filepath.Join(outside, "shared", "notes.md"), AccessInside},
+	}
+	for _, tt := range tests {
+		if _, got := w.Check(tt.path); got != tt.want {
+			t.Errorf("Check(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWorkspace_DenyDirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0700)
+
+	w, err := NewWorkspace(config.WorkspaceConfig{Root: home, Deny: []string{"~/.ssh"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, got := w.Check(filepath.Join(home, ".ssh", "id_ed25519")); got != AccessDenied {
+		t.Errorf("key under denied directory: %d", got)
+	}
+	if _, got := w.Check(filepath.Join(home, ".sshrc")); got != AccessInside {
+		t.Errorf("sibling of denied directory: %d", got)
+	}
+}
+
+func TestRegistry_Workspace(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	t.Chdir(root)
+	os.WriteFile(filepath.Join(root, "in.txt"), []byte("inside"), 0644)
+	os.WriteFile(filepath.Join(outside, "out.txt"), []byte("outside"), 0644)
+	read := func(path string) string {
+		b, _ := json.Marshal(map[string]string{"path": path})
+		return string(b)
+	}
+
+	reg := NewRegistry(nil, true)
+	reg.Register(&FileReadTool{})
+	w, err := NewWorkspace(config.WorkspaceConfig{Deny: []string{"*.key"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.SetWorkspace(w)
+	ctx := context.Background()
+
+	if res, _ := reg.Execute(ctx, "file_read", read("in.txt"), nil); res.Error != "" {
+		t.Errorf("inside read failed: %s", res.Error)
+	}
+
+	outPath := filepath.Join(outside, "out.txt")
+	if got := reg.OutsidePaths("file_read", read(outPath)); len(got) != 1 {
+		t.Errorf("OutsidePaths = %v", got)
+	}
+	if got := reg.OutsidePaths("file_read", read(filepath.Join(outside, "*.txt"))); len(got) != 1 {
+		t.Errorf("OutsidePaths for glob = %v", got)
+	}
+	res, _ := reg.Execute(ctx, "file_read", read(outPath), nil)
+	if !strings.Contains(res.Error, "needs the user's approval") {
+		t.Errorf("unapproved outside read: %+v", res)
+	}
+	res, _ = reg.Execute(ApproveOutside(ctx), "file_read", read(outPath), nil)
+	if res.Error != "" || !strings.Contains(res.Output, "outside") {
+		t.Errorf("approved outside read: %+v", res)
+	}
+
+	res, _ = reg.Execute(ApproveOutside(ctx), "file_read", read("id.key"), nil)
+	if !strings.Contains(res.Error, "denied by the workspace policy") {
+		t.Errorf("denied read: %+v", res)
+	}
+
+	w.outside = "block"
+	if got := reg.OutsidePaths("file_read", read(outPath)); len(got) != 0 {
+		t.Errorf("blocked paths offered for approval: %v", got)
+	}
+	res, _ = reg.Execute(ApproveOutside(ctx), "file_read", read(outPath), nil)
+	if !strings.Contains(res.Error, "outside the workspace") {
+		t.Errorf("blocked outside read: %+v", res)
+	}
+}
+
+func TestRegistry_WorkspaceChecksGlobMatches(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	t.Chdir(root)
+	os.MkdirAll(filepath.Join(root, "app"), 0755)
+	os.WriteFile(filepath.Join(root, "app", "main.env.txt"), []byte("PORT=1"), 0644)
+	os.WriteFile(filepath.Join(root, "app", ".env"), []byte("TOKEN=hunter2"), 0644)
+	os.WriteFile(filepath.Join(outside, "id_ed25519"), []byte("PRIVATE KEY"), 0600)
+	os.Symlink(filepath.Join(outside, "id_ed25519"), filepath.Join(root, "app", "key.txt"))
+
+	reg := NewRegistry(nil, true)
+	reg.Register(&FileReadTool{})
+	reg.Register(&FileSearchTool{})
+	w, err := NewWorkspace(config.WorkspaceConfig{Deny: []string{"**/.env"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.SetWorkspace(w)
+	ctx := context.Background()
+
+	for _, pattern := range []string{"**/.env", "**/*"} {
+		res, _ := reg.Execute(ctx, "file_read", `{"path":"`+pattern+`"}`, nil)
+		if strings.Contains(res.Output+res.Error, "hunter2") {
+			t.Errorf("file_read %s read a denied file: %+v", pattern, res)
+		}
+		if strings.Contains(res.Output+res.Error, "PRIVATE KEY") {
+			t.Errorf("file_read %s followed a symlink out of the workspace: %+v", pattern, res)
+		}
+	}
+	res, _ := reg.Execute(ctx, "file_read", `{"path":"app/*.txt"}`, nil)
+	if !strings.Contains(res.Output, "PORT=1") || !strings.Contains(res.Output, "leads outside the workspace") {
+		t.Errorf("file_read glob = %+v", res)
+	}
+
+	res, _ = reg.Execute(ctx, "file_search", `{"grep":"TOKEN|PORT","no_ignore":true}`, nil)
+	if strings.Contains(res.Output, "hunter2") || !strings.Contains(res.Output, "PORT=1") {
+		t.Errorf("file_search grep = %+v", res)
+	}
+	res, _ = reg.Execute(ctx, "file_search", `{"pattern":"**/.env","no_ignore":true}`, nil)
+	if strings.Contains(res.Output, ".env") {
+		t.Errorf("file_search listed a denied file: %+v", res)
+	}
+}
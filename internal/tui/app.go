@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/jeanpaul/aseity/internal/agent"
+	"github.com/jeanpaul/aseity/internal/pricing"
 	"github.com/jeanpaul/aseity/internal/provider"
 	"github.com/jeanpaul/aseity/internal/tools"
 )
@@ -119,14 +121,16 @@ var (
 
 // Tool icons for visual distinction
 var toolIcons = map[string]string{
-	"bash":        "CMD",
-	"file_read":   "READ",
-	"file_write":  "EDIT",
-	"file_search": "FIND",
-	"web_search":  "WEB",
-	"web_fetch":   "GET",
-	"spawn_agent": "BOT",
-	"list_agents": "LIST",
+	"bash":         "CMD",
+	"file_read":    "READ",
+	"file_write":   "EDIT",
+	"apply_patch":  "PATCH",
+	"file_search":  "FIND",
+	"code_symbols": "SYMS",
+	"web_search":   "WEB",
+	"web_fetch":    "GET",
+	"spawn_agent":  "BOT",
+	"list_agents":  "LIST",
 }
 
 type agentEventMsg agent.Event
@@ -156,6 +160,7 @@ type Model struct {
 	providerName   string
 	modelName      string
 	providerOnline bool   // Track if provider is connected
+	servedBy       string // Backend that served the last turn when routing fell back
 	currentTool    string // track which tool is running for animation
 
 	agent                  *agent.Agent
@@ -178,6 +183,13 @@ type chatMessage struct {
 	content  string
 	data     any    // Structured data from tools
 	rendered string // Caches the markdown rendering to avoid re-rendering entire history on every frame
+
+	// Tool call still being generated (role "tool_draft"); content holds
+	// the raw arguments received so far.
+	toolIndex int // Position among the response's tool calls
+	toolName  string
+
+	turn int // Conversation turn a user message started, counting from 1
 }
 
 // setRendered updates content and clears cache if needed
@@ -192,7 +204,7 @@ func (c *chatMessage) appendContent(text string) {
 	c.rendered = ""
 }
 
-func NewModel(prov provider.Provider, toolReg *tools.Registry, provName, modelName string, conversation *agent.Conversation, qualityGate bool, orchConfig *agent.OrchestratorConfig) Model {
+func NewModel(prov provider.Provider, toolReg *tools.Registry, provName, modelName string, conversation *agent.Conversation, qualityGate bool, orchConfig *agent.OrchestratorConfig, costs *pricing.Tracker) Model {
 	ta := textarea.New()
 	ta.Placeholder = "Type your message..."
 	ta.Focus()
@@ -244,10 +256,10 @@ func NewModel(prov provider.Provider, toolReg *tools.Registry, provName, modelNa
 	if conversation != nil {
 		ag = agent.NewWithConversation(prov, toolReg, conversation)
 		// Rehydrate messages from conversation
-		for _, msg := range conversation.Messages() {
+		for i, msg := range conversation.Messages() {
 			switch msg.Role {
 			case provider.RoleUser:
-				m.messages = append(m.messages, chatMessage{role: "user", content: msg.Content})
+				m.messages = append(m.messages, chatMessage{role: "user", content: msg.Content, turn: conversation.TurnAt(i)})
 			case provider.RoleAssistant:
 				m.messages = append(m.messages, chatMessage{role: "assistant", content: msg.Content})
 				// TODO: Handle tool calls display if needed from history, strictly strictly rehydrating display is hard if we don't store ToolUse details in chatMessage properly.
@@ -266,6 +278,7 @@ func NewModel(prov provider.Provider, toolReg *tools.Registry, provName, modelNa
 		})
 	}
 	ag.QualityGateEnabled = qualityGate
+	ag.Conversation().SetCostTracker(costs)
 
 	// Initialize orchestrator if config provided
 	if orchConfig != nil && orchConfig.Enabled {
@@ -357,6 +370,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.confirming {
 			switch msg.String() {
 			case "y", "Y", "enter":
+				// Leaving the workspace takes an explicit y
+				if msg.String() == "enter" && m.confirmEvt != nil && len(m.confirmEvt.Outside) > 0 {
+					return m, nil
+				}
 				m.confirming = false
 				m.agent.ConfirmCh <- true
 				m.messages = append(m.messages, chatMessage{role: "confirm", content: "  Approved"})
@@ -413,6 +430,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.confirming = false
 				m.currentTool = ""
 				m.cancel()
+				m.abandonToolDrafts()
 				m.ctx, m.cancel = context.WithCancel(context.Background())
 				// Preserve conversation history!
 				oldConv := m.agent.Conversation()
@@ -472,7 +490,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m.handleSlashCommand(text)
 			}
 
-			m.messages = append(m.messages, chatMessage{role: "user", content: text})
+			// The agent numbers the turn when it starts; a message that
+			// never becomes a turn shares the number of the next one.
+			m.messages = append(m.messages, chatMessage{role: "user", content: text, turn: m.agent.Conversation().Turn() + 1})
 			m.thinking = true
 
 			// Pick a fun random animation for this turn!
@@ -518,6 +538,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.messages[len(m.messages)-1].appendContent(evt.Text)
 
+		case agent.EventRetry:
+			// Drop the partial response; the turn is being reissued from scratch.
+			for len(m.messages) > 0 {
+				role := m.messages[len(m.messages)-1].role
+				if role != "assistant" && role != "thinking" && role != "tool_draft" {
+					break
+				}
+				m.messages = m.messages[:len(m.messages)-1]
+			}
+			m.messages = append(m.messages, chatMessage{role: "system", content: "  " + evt.Text})
+
+		case agent.EventRoute:
+			m.providerOnline = true
+			m.servedBy = ""
+			if evt.Route.Fallback() {
+				m.servedBy = evt.Route.Provider + " / " + evt.Route.Model
+			}
+
 		case agent.EventDelta:
 			// Provider is responding, so it's online
 			m.providerOnline = true
@@ -528,10 +566,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.rebuildView()
 
+		case agent.EventToolCallDelta:
+			m.providerOnline = true
+			m.appendToolDraft(evt.ToolIndex, evt.ToolName, evt.ToolArgs)
+			m.rebuildView()
+
 		case agent.EventToolCall:
 			m.currentTool = evt.ToolName
 			// Switch spinner based on tool type
 			m.setSpinnerForTool(evt.ToolName)
+			m.removeToolDraft(evt.ToolIndex)
 			m.messages = append(m.messages, chatMessage{
 				role:    "tool",
 				content: formatToolCallDisplay(evt.ToolName, evt.ToolArgs),
@@ -549,6 +593,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case agent.EventConfirmRequest:
 			m.confirming = true
 			m.confirmEvt = &evt
+			if len(evt.Outside) > 0 {
+				m.messages = append(m.messages, chatMessage{
+					role:    "workspace_prompt",
+					content: fmt.Sprintf("%s wants to leave the workspace:\n%s\n  Allow access outside the workspace? [y/n]", evt.ToolName, formatPathList(evt.Outside)),
+				})
+				m.rebuildView()
+				return m, nil
+			}
 			m.messages = append(m.messages, chatMessage{
 				role:    "confirm_prompt",
 				content: fmt.Sprintf("  Allow %s? [y/n]", evt.ToolName),
@@ -588,6 +640,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case agent.EventError:
+			if evt.Done {
+				m.abandonToolDrafts()
+			} else if evt.ToolName != "" {
+				// The call was rejected before it ran; the error says why.
+				m.removeToolDraft(evt.ToolIndex)
+			}
 			m.messages = append(m.messages, chatMessage{role: "error", content: evt.Error})
 			m.thinking = false
 			// Check if it's a connection error
@@ -709,10 +767,13 @@ func (m *Model) handleSlashCommand(text string) (Model, tea.Cmd) {
     /compact     — compress conversation to save context
     /save [path] — export conversation to markdown file
     /tokens      — show estimated token usage
+    /debug       — toggle the provider debug log (on|off)
     /model       — show current model
     /status      — run git status
     /diff [full] — run git diff --stat (or full)
     /commit <m>  — run git commit -m <m>
+    /undo        — restore the files changed by the last turn
+    /rewind [n]  — list turns, or go back to before turn n (files and chat)
     /quit        — exit aseity
 
   Keyboard shortcuts:
@@ -757,20 +818,36 @@ func (m *Model) handleSlashCommand(text string) (Model, tea.Cmd) {
 	case "/tokens":
 		tokens := m.agent.Conversation().EstimatedTokens()
 		msgCount := m.agent.Conversation().Len()
+		tok := m.agent.Conversation().Tokenizer().Name()
 		m.messages = append(m.messages, chatMessage{
 			role:    "system",
-			content: fmt.Sprintf("  ~%d tokens, %d messages", tokens, msgCount),
+			content: fmt.Sprintf("  ~%d tokens (%s), %d messages", tokens, tok, msgCount),
 		})
 
 	case "/cost":
-		tokens := m.agent.Conversation().EstimatedTokens()
-		// Rough estimate using Claude 3.5 Sonnet blended rate (skewed towards input)
-		// Input: $3/1M, Output: $15/1M. Assume 80/20 split -> ~$5.40/1M blended
-		cost := float64(tokens) / 1_000_000 * 5.40
-		m.messages = append(m.messages, chatMessage{
-			role:    "system",
-			content: fmt.Sprintf("  ~%d tokens\n  Est. Cost (Sonnet 3.5): ~$%.4f", tokens, cost),
-		})
+		costs := m.agent.Conversation().CostTracker()
+		if costs == nil {
+			m.messages = append(m.messages, chatMessage{role: "system", content: "  Cost tracking is not enabled for this session."})
+			break
+		}
+		m.messages = append(m.messages, chatMessage{role: "system", content: formatCostSummary(costs.Summary())})
+
+	case "/debug":
+		log, ok := provider.DebugLogOf(m.prov)
+		if !ok {
+			m.messages = append(m.messages, chatMessage{role: "system", content: "  Provider debug logging is not available in this session."})
+			break
+		}
+		on := !log.Enabled()
+		if len(parts) > 1 {
+			on = parts[1] == "on"
+		}
+		log.SetEnabled(on)
+		content := "  Provider debug log off."
+		if on {
+			content = "  Provider debug log on: " + log.Path() + " (view with 'aseity logs')"
+		}
+		m.messages = append(m.messages, chatMessage{role: "system", content: content})
 
 	case "/init":
 		if _, err := os.Stat("ASEITY.md"); err == nil {
@@ -847,6 +924,46 @@ func (m *Model) handleSlashCommand(text string) (Model, tea.Cmd) {
 			}
 		}
 
+	case "/undo":
+		turn, paths, err := m.agent.Undo()
+		if err != nil {
+			m.messages = append(m.messages, chatMessage{role: "error", content: "Undo failed: " + err.Error()})
+			break
+		}
+		m.messages = append(m.messages, chatMessage{
+			role:    "system",
+			content: fmt.Sprintf("  ↶ Undid turn %d, restored:\n%s", turn, formatPathList(paths)),
+		})
+
+	case "/rewind":
+		if len(parts) < 2 {
+			m.messages = append(m.messages, chatMessage{role: "system", content: formatTurns(m.agent)})
+			break
+		}
+		turn, err := strconv.Atoi(parts[1])
+		if err != nil {
+			m.messages = append(m.messages, chatMessage{role: "error", content: "Usage: /rewind <turn>"})
+			break
+		}
+		paths, err := m.agent.Rewind(turn)
+		if err != nil {
+			m.messages = append(m.messages, chatMessage{role: "error", content: "Rewind failed: " + err.Error()})
+			break
+		}
+		// Drop the rewound turns from the display too, from the last message
+		// numbered turn: an earlier one never became a turn.
+		for i := len(m.messages) - 1; i >= 0; i-- {
+			if m.messages[i].role == "user" && m.messages[i].turn == turn {
+				m.messages = m.messages[:i]
+				break
+			}
+		}
+		content := fmt.Sprintf("  ↶ Rewound to before turn %d.", turn)
+		if len(paths) > 0 {
+			content += " Restored:\n" + formatPathList(paths)
+		}
+		m.messages = append(m.messages, chatMessage{role: "system", content: content})
+
 	default:
 		m.messages = append(m.messages, chatMessage{
 			role:    "error",
@@ -895,7 +1012,7 @@ func (m *Model) setSpinnerForTool(toolName string) {
 		m.spinner.Spinner = ToolSpinner
 		m.spinner.Style = BashIconStyle
 		m.spinnerState = SpinnerTool
-	case "file_read", "file_write", "file_search":
+	case "file_read", "file_write", "apply_patch", "file_search", "code_symbols":
 		m.spinner.Spinner = ProcessingSpinner
 		m.spinner.Style = FileIconStyle
 		m.spinnerState = SpinnerTool
@@ -940,8 +1057,12 @@ func (m *Model) getAnimatedStatus() string {
 			return "Reading file..."
 		case "file_write":
 			return "Writing file..."
+		case "apply_patch":
+			return "Applying patch..."
 		case "file_search":
 			return "Searching files..."
+		case "code_symbols":
+			return "Looking up symbols..."
 		case "web_search":
 			return "Searching the web..."
 		case "web_fetch":
@@ -957,6 +1078,138 @@ func (m *Model) getAnimatedStatus() string {
 	return "Thinking..."
 }
 
+// appendToolDraft adds a fragment of a tool call's arguments to its draft
+// message, starting one if this is the call's first fragment. Drafts are
+// keyed by the call's index, since early fragments may carry no ID.
+func (m *Model) appendToolDraft(index int, name, args string) {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if msg := &m.messages[i]; msg.role == "tool_draft" && msg.toolIndex == index {
+			msg.toolName = name
+			msg.appendContent(args)
+			return
+		}
+	}
+	m.messages = append(m.messages, chatMessage{role: "tool_draft", toolIndex: index, toolName: name, content: args})
+}
+
+// removeToolDraft drops the draft of a call that is now complete or was
+// rejected.
+func (m *Model) removeToolDraft(index int) {
+	for i, msg := range m.messages {
+		if msg.role == "tool_draft" && msg.toolIndex == index {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			return
+		}
+	}
+}
+
+// abandonToolDrafts marks calls that were still being written as never run,
+// keeping what the model had produced visible.
+func (m *Model) abandonToolDrafts() {
+	for i := range m.messages {
+		if msg := &m.messages[i]; msg.role == "tool_draft" {
+			msg.role = "system"
+			msg.setContent(fmt.Sprintf("%s call cancelled before it ran (%d bytes of arguments)", msg.toolName, len(msg.content)))
+		}
+	}
+}
+
+// formatToolDraft renders a tool call whose arguments are still streaming,
+// showing the part users most want to catch early: the command or the file
+// content being written.
+func formatToolDraft(name, args string) string {
+	header := fmt.Sprintf("  %s %s %s",
+		ToolCallStyle.Render("◌"),
+		ToolLabelStyle.Render(name),
+		HelpStyle.Render("generating… (Ctrl+C to cancel)"),
+	)
+	switch name {
+	case "bash":
+		if cmd, ok := partialJSONString(args, "command"); ok {
+			return header + "\n  " + CommandStyle.Render("$ "+tailLines(cmd, 6))
+		}
+	case "file_write":
+		path, _ := partialJSONString(args, "path")
+		content, ok := partialJSONString(args, "content")
+		if !ok {
+			return header + "\n  " + InfoStyle.Render(path)
+		}
+		lines := strings.Count(content, "\n") + 1
+		return header + "\n  " + InfoStyle.Render(fmt.Sprintf("%s (%d lines so far)", path, lines)) +
+			"\n" + ToolResultStyle.Render(tailLines(content, 10))
+	case "apply_patch":
+		if patch, ok := partialJSONString(args, "patch"); ok {
+			return header + "\n" + ToolResultStyle.Render(tailLines(patch, 10))
+		}
+	}
+	if len(args) > 120 {
+		args = "…" + args[len(args)-120:]
+	}
+	return header + "\n  " + InfoStyle.Render(args)
+}
+
+// tailLines returns the last n lines of s.
+func tailLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return "…\n" + strings.Join(lines[len(lines)-n:], "\n")
+}
+
+// partialJSONString extracts the string value of key from a JSON object
+// that may be cut off anywhere, returning as much of the value as has
+// arrived. It reports false when the value hasn't started yet.
+func partialJSONString(doc, key string) (string, bool) {
+	i := strings.Index(doc, `"`+key+`"`)
+	if i < 0 {
+		return "", false
+	}
+	rest := strings.TrimLeft(doc[i+len(key)+2:], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return "", false
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if !strings.HasPrefix(rest, `"`) {
+		return "", false
+	}
+	rest = rest[1:]
+
+	var sb strings.Builder
+	for j := 0; j < len(rest); j++ {
+		c := rest[j]
+		if c == '"' {
+			break
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		if j+1 >= len(rest) {
+			break // Escape split across fragments
+		}
+		j++
+		switch rest[j] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+		case 'u':
+			if j+4 >= len(rest) {
+				return sb.String(), true
+			}
+			if r, err := strconv.ParseUint(rest[j+1:j+5], 16, 32); err == nil {
+				sb.WriteRune(rune(r))
+			}
+			j += 4
+		default: // \" \\ \/ and anything unexpected
+			sb.WriteByte(rest[j])
+		}
+	}
+	return sb.String(), true
+}
+
 func formatToolCallDisplay(name, args string) string {
 	icon := toolIcons[name]
 	if icon == "" {
@@ -982,12 +1235,24 @@ func formatToolCallDisplay(name, args string) string {
 			ToolLabelStyle.Render("write"),
 			InfoStyle.Render(args),
 		)
+	case "apply_patch":
+		return fmt.Sprintf("  %s %s %s",
+			FileIconStyle.Render(icon),
+			ToolLabelStyle.Render("patch"),
+			InfoStyle.Render(args),
+		)
 	case "file_search":
 		return fmt.Sprintf("  %s %s %s",
 			FileIconStyle.Render(icon),
 			ToolLabelStyle.Render("search"),
 			InfoStyle.Render(args),
 		)
+	case "code_symbols":
+		return fmt.Sprintf("  %s %s %s",
+			FileIconStyle.Render(icon),
+			ToolLabelStyle.Render("symbols"),
+			InfoStyle.Render(args),
+		)
 	case "web_search":
 		return fmt.Sprintf("  %s %s %s",
 			WebIconStyle.Render(icon),
@@ -1077,6 +1342,9 @@ func (m *Model) rebuildView() {
 			}
 			renderedBlock = m.renderToolBlock(msg.content, result, msg.data)
 
+		case "tool_draft":
+			renderedBlock = m.renderToolBlock(formatToolDraft(msg.toolName, msg.content), "", nil)
+
 		case "tool_result":
 			// Orphaned result (shouldn't happen often if grouped above)
 			renderedBlock = m.renderToolBlock("Previous Tool", msg.content, msg.data)
@@ -1084,6 +1352,9 @@ func (m *Model) rebuildView() {
 		case "confirm_prompt":
 			renderedBlock = WarningStyle.Render("  ⚠ ") + ConfirmStyle.Render(msg.content) + "\n\n"
 
+		case "workspace_prompt":
+			renderedBlock = ErrorStyle.Render("  ⛔ ") + ConfirmStyle.Render(msg.content) + "\n\n"
+
 		case "confirm":
 			renderedBlock = SuccessStyle.Render("  ✓ "+msg.content) + "\n\n"
 
@@ -1292,6 +1563,25 @@ func (m *Model) renderToolBlock(header, result string, data any) string {
 	return ToolBlockStyle.Render(content) + "\n"
 }
 
+// breakerStatusLine describes every provider whose circuit breaker is not
+// closed, or returns "" when all are healthy.
+func breakerStatusLine() string {
+	var parts []string
+	for _, b := range provider.BreakerStatuses() {
+		switch b.State {
+		case provider.BreakerOpen:
+			part := "⚡ " + b.Name + ": circuit open"
+			if wait := time.Until(b.RetryAt); wait > 0 {
+				part += fmt.Sprintf(" (retry %s)", wait.Round(time.Second))
+			}
+			parts = append(parts, part)
+		case provider.BreakerHalfOpen:
+			parts = append(parts, "⚡ "+b.Name+": probing")
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
 func (m Model) View() string {
 	// --- Header Construction (Restored Wave Style) ---
 	// Left Column: Animated Banner
@@ -1306,11 +1596,20 @@ func (m Model) View() string {
 	}
 	statusDot := lipgloss.NewStyle().Foreground(statusColor).Render(statusIndicator)
 
+	connection := fmt.Sprintf("%s %s / %s", statusDot, m.providerName, m.modelName)
+	if m.servedBy != "" {
+		connection += lipgloss.NewStyle().Foreground(DimGreen).Render(" → " + m.servedBy)
+	}
+
 	leftContent := lipgloss.JoinVertical(lipgloss.Center,
 		logo,
-		fmt.Sprintf("%s %s / %s", statusDot, m.providerName, m.modelName),
+		connection,
 		// Add some breathing room
 	)
+	if breakers := breakerStatusLine(); breakers != "" {
+		leftContent = lipgloss.JoinVertical(lipgloss.Center, leftContent,
+			lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C")).Render(breakers))
+	}
 
 	// Right Column: Context/Tips (Vertical stack)
 	contextState := "Active"
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jeanpaul/aseity/internal/agent"
 	"github.com/jeanpaul/aseity/internal/provider"
 	"github.com/jeanpaul/aseity/internal/tools"
 )
@@ -13,7 +14,7 @@ import (
 // Mock objects for initialization
 type mockProvider struct{}
 
-func (m mockProvider) Chat(ctx context.Context, msgs []provider.Message, toolDefs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m mockProvider) Chat(ctx context.Context, msgs []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	close(ch)
 	return ch, nil
@@ -29,7 +30,7 @@ func TestMenuTrigger(t *testing.T) {
 	// Setup
 	prov := mockProvider{}
 	reg := tools.NewRegistry(nil, false) // Fixed args
-	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil)
+	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil, nil)
 
 	// Ensure menu starts inactive
 	if model.menu.active {
@@ -59,7 +60,7 @@ func TestMenuTrigger(t *testing.T) {
 func TestMenuSelection(t *testing.T) {
 	prov := mockProvider{}
 	reg := tools.NewRegistry(nil, false) // Fixed args
-	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil)
+	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil, nil)
 
 	// Activate menu manually
 	model.menu.active = true
@@ -88,7 +89,7 @@ func TestMenuSelection(t *testing.T) {
 func TestInputPromptDesign(t *testing.T) {
 	prov := mockProvider{}
 	reg := tools.NewRegistry(nil, false) // Fixed args
-	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil)
+	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil, nil)
 
 	// Check View for minimal prompt "> "
 	view := model.View()
@@ -100,7 +101,7 @@ func TestInputPromptDesign(t *testing.T) {
 func TestHeaderRendering(t *testing.T) {
 	prov := mockProvider{}
 	reg := tools.NewRegistry(nil, false) // Fixed args
-	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil)
+	model := NewModel(prov, reg, "mock-provider", "mock-model", nil, false, nil, nil)
 	model.width = 100
 	model.height = 50
 
@@ -118,3 +119,114 @@ func TestHeaderRendering(t *testing.T) {
 		t.Error("Header should contain 'Tips' section")
 	}
 }
+
+func TestPartialJSONString(t *testing.T) {
+	tests := []struct {
+		doc, key string
+		want     string
+		ok       bool
+	}{
+		{`{"command":"ls -la"}`, "command", "ls -la", true},
+		{`{"path":"a.go","content":"package main\nfunc`, "content", "package main\nfunc", true},
+		{`{"content": "say \"hi\"\té`, "content", "say \"hi\"\té", true},
+		{`{"content":"trailing \`, "content", "trailing ", true},
+		{`{"content":"\u00`, "content", "", true},
+		{`{"path":"a.go","con`, "content", "", false},
+		{`{"content":`, "content", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := partialJSONString(tt.doc, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("partialJSONString(%q, %q) = %q, %v; want %q, %v", tt.doc, tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestToolCallDraft(t *testing.T) {
+	reg := tools.NewRegistry(nil, false)
+	model := NewModel(mockProvider{}, reg, "mock-provider", "mock-model", nil, false, nil, nil)
+	updated, _ := model.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	model = updated.(Model)
+
+	for _, frag := range []string{`{"command":"echo `, `hello"}`} {
+		updated, _ = model.Update(agentEventMsg(agent.Event{Type: agent.EventToolCallDelta, ToolID: "c1", ToolName: "bash", ToolArgs: frag}))
+		model = updated.(Model)
+	}
+	last := model.messages[len(model.messages)-1]
+	if last.role != "tool_draft" || last.content != `{"command":"echo hello"}` {
+		t.Fatalf("draft = %+v", last)
+	}
+	if view := model.viewport.View(); !strings.Contains(view, "echo hello") {
+		t.Errorf("draft command not rendered:\n%s", view)
+	}
+
+	updated, _ = model.Update(agentEventMsg(agent.Event{Type: agent.EventToolCall, ToolID: "c1", ToolName: "bash", ToolArgs: "echo hello"}))
+	model = updated.(Model)
+	for _, msg := range model.messages {
+		if msg.role == "tool_draft" {
+			t.Error("draft kept after the complete call arrived")
+		}
+	}
+}
+
+func TestToolCallDraftsByIndex(t *testing.T) {
+	reg := tools.NewRegistry(nil, false)
+	model := NewModel(mockProvider{}, reg, "mock-provider", "mock-model", nil, false, nil, nil)
+
+	send := func(evt agent.Event) {
+		updated, _ := model.Update(agentEventMsg(evt))
+		model = updated.(Model)
+	}
+	drafts := func() []chatMessage {
+		var out []chatMessage
+		for _, msg := range model.messages {
+			if msg.role == "tool_draft" {
+				out = append(out, msg)
+			}
+		}
+		return out
+	}
+
+	// Two calls streamed without IDs stay separate.
+	send(agent.Event{Type: agent.EventToolCallDelta, ToolIndex: 0, ToolName: "bash", ToolArgs: `{"command":"ls"}`})
+	send(agent.Event{Type: agent.EventToolCallDelta, ToolIndex: 1, ToolName: "file_read", ToolArgs: `{"path":"a"}`})
+	if d := drafts(); len(d) != 2 || d[0].content != `{"command":"ls"}` || d[1].content != `{"path":"a"}` {
+		t.Fatalf("drafts = %+v", d)
+	}
+
+	// A rejected call drops only its own draft; other drafts survive the error.
+	send(agent.Event{Type: agent.EventError, Error: "Validation Failed: no", ToolName: "bash", ToolIndex: 0})
+	if d := drafts(); len(d) != 1 || d[0].toolName != "file_read" {
+		t.Fatalf("drafts after rejection = %+v", d)
+	}
+	send(agent.Event{Type: agent.EventError, Error: "Quality Gate Rejected: retry"})
+	if len(drafts()) != 1 {
+		t.Fatal("a non-terminal error abandoned the drafts")
+	}
+
+	send(agent.Event{Type: agent.EventError, Error: "stream failed", Done: true})
+	if len(drafts()) != 0 {
+		t.Fatal("a terminal error kept the drafts")
+	}
+}
+
+func TestRewindTrimsByTurn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	conv := agent.NewConversation()
+	for i := 0; i < 3; i++ {
+		conv.AddUserTurn("again")
+		conv.AddAssistant("ok", nil)
+	}
+	model := NewModel(mockProvider{}, tools.NewRegistry(nil, false), "mock-provider", "mock-model", conv, false, nil, nil)
+
+	model, _ = model.handleSlashCommand("/rewind 2")
+	var users int
+	for _, msg := range model.messages {
+		if msg.role == "user" {
+			users++
+		}
+	}
+	if users != 1 {
+		t.Errorf("%d prompts shown after rewinding to before turn 2, want 1", users)
+	}
+}
@@ -2,10 +2,13 @@ package tui
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/jeanpaul/aseity/internal/agent"
 	"github.com/jeanpaul/aseity/internal/agent/skillsets"
+	"github.com/jeanpaul/aseity/internal/pricing"
 )
 
 // formatProfileInfo formats the current model profile for display
@@ -128,3 +131,84 @@ func makeBar(value float64, width int) string {
 	}
 	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
 }
+
+// formatCostSummary renders /cost: session totals, then one line per call
+// source and model.
+func formatCostSummary(s pricing.Summary) string {
+	if len(s.Entries) == 0 {
+		return "  No model calls yet."
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("  Session cost: $%.4f\n", s.Cost))
+	cache := fmt.Sprintf("%d cached", s.Usage.CachedInputTokens)
+	if s.Usage.CacheWriteTokens > 0 {
+		cache += fmt.Sprintf(", %d written to cache", s.Usage.CacheWriteTokens)
+	}
+	b.WriteString(fmt.Sprintf("  Tokens: %d in (%s), %d out\n\n", s.Usage.InputTokens, cache, s.Usage.OutputTokens))
+	for _, e := range s.Entries {
+		cost := fmt.Sprintf("$%.4f", e.Cost)
+		if !e.Priced {
+			cost = "no price"
+		}
+		b.WriteString(fmt.Sprintf("  %-22s %-32s %4d calls  %8d in  %7d out  %s\n",
+			e.Source, e.Provider+"/"+e.Model, e.Calls, e.Usage.InputTokens, e.Usage.OutputTokens, cost))
+	}
+	if s.Unpriced > 0 {
+		b.WriteString(fmt.Sprintf("\n  %d call(s) used models with no known price; add them under pricing: in config.yaml.", s.Unpriced))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatTurns renders /rewind without arguments: the turns still in the
+// conversation, with the files each changed.
+func formatTurns(ag *agent.Agent) string {
+	conv := ag.Conversation()
+	changed := map[int][]string{}
+	if store := ag.Checkpoints(); store != nil {
+		turns, _ := store.Turns()
+		for _, t := range turns {
+			changed[t.N] = t.Files
+		}
+	}
+	var b strings.Builder
+	for n := 1; n <= conv.Turn(); n++ {
+		prompt, ok := conv.TurnPrompt(n)
+		if !ok {
+			continue
+		}
+		prompt = strings.Join(strings.Fields(prompt), " ")
+		if len(prompt) > 60 {
+			prompt = prompt[:60] + "..."
+		}
+		b.WriteString(fmt.Sprintf("  %3d  %s\n", n, prompt))
+		if files := changed[n]; len(files) > 0 {
+			b.WriteString(fmt.Sprintf("       %d file(s): %s\n", len(files), strings.Join(relPaths(files), ", ")))
+		}
+	}
+	if b.Len() == 0 {
+		return "  No turns to rewind to."
+	}
+	return "  Turns (/rewind <n> goes back to before turn n):\n" + strings.TrimRight(b.String(), "\n")
+}
+
+// formatPathList renders paths one per line, relative to the working
+// directory where that is shorter.
+func formatPathList(paths []string) string {
+	var b strings.Builder
+	for _, p := range relPaths(paths) {
+		b.WriteString("    " + p + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func relPaths(paths []string) []string {
+	cwd, _ := os.Getwd()
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = p
+		if rel, err := filepath.Rel(cwd, p); err == nil && !strings.HasPrefix(rel, "..") {
+			out[i] = rel
+		}
+	}
+	return out
+}
@@ -37,6 +37,8 @@ func NewMenuModel() MenuModel {
 		item{title: "/help", desc: "Show help commands"},
 		item{title: "/compact", desc: "Summarize history to save tokens"},
 		item{title: "/clear", desc: "Clear conversation history"},
+		item{title: "/undo", desc: "Restore files changed by the last turn"},
+		item{title: "/rewind", desc: "Go back to before an earlier turn"},
 		item{title: "/agents", desc: "Manage or switch agents"},
 		item{title: "/settings", desc: "Open settings menu"},
 		item{title: "/skillsets", desc: "View and manage skillsets"},
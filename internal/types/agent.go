@@ -11,6 +11,13 @@ type AgentSpawner interface {
 	List() []AgentInfo
 }
 
+// Completer is implemented by spawners that can also make a single model
+// call without tools, for answers that must follow a response schema:
+// providers can't enforce a schema while tools are offered.
+type Completer interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
 type AgentInfo struct {
 	ID     int
 	Task   string
@@ -17,7 +17,7 @@ type MockProvider struct {
 	LastMessages []provider.Message
 }
 
-func (m *MockProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProvider) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	m.LastMessages = msgs
 	ch := make(chan provider.StreamChunk)
 	close(ch)
@@ -15,7 +15,7 @@ type MockProviderFallback struct {
 	ResponseText string
 }
 
-func (m *MockProviderFallback) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderFallback) Chat(ctx context.Context, msgs []provider.Message, tools []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	go func() {
 		defer close(ch)
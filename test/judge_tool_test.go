@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jeanpaul/aseity/internal/provider"
 	"github.com/jeanpaul/aseity/internal/tools"
 	"github.com/jeanpaul/aseity/internal/types"
 )
@@ -91,3 +92,47 @@ func TestJudgeToolMarkdownHandling(t *testing.T) {
 		t.Errorf("Output should be stripped of markdown blocks: %s", res.Output)
 	}
 }
+
+// MockSpawnerForProse returns a review without JSON and restates it through
+// Complete, recording where the verdict schema was applied.
+type MockSpawnerForProse struct {
+	criticSchema  bool
+	verdictSchema bool
+}
+
+func (m *MockSpawnerForProse) Spawn(ctx context.Context, task string, files []string, name string) (int, error) {
+	m.criticSchema = provider.ResolveChatOptions(ctx, nil).ResponseFormat != nil
+	return 7, nil
+}
+
+func (m *MockSpawnerForProse) Get(id int) (types.AgentInfo, bool) {
+	return types.AgentInfo{ID: id, Status: "done", Output: "The loop is off by one, so this fails."}, true
+}
+
+func (m *MockSpawnerForProse) Complete(ctx context.Context, prompt string) (string, error) {
+	m.verdictSchema = provider.ResolveChatOptions(ctx, nil).ResponseFormat != nil
+	return `{"status": "fail", "feedback": "Off by one."}`, nil
+}
+
+func (m *MockSpawnerForProse) List() []types.AgentInfo { return nil }
+func (m *MockSpawnerForProse) Cancel(id int) error     { return nil }
+
+func TestJudgeToolVerdictCall(t *testing.T) {
+	mock := &MockSpawnerForProse{}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res, err := tools.NewJudgeTool(mock).Execute(ctx, `{"original_goal": "X", "content": "Y"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if mock.criticSchema {
+		t.Error("The critic uses tools and must run without the verdict schema")
+	}
+	if !mock.verdictSchema {
+		t.Error("The tool-free verdict call should carry the verdict schema")
+	}
+	if !strings.Contains(res.Output, `"status":"fail"`) || !strings.Contains(res.Output, "Off by one.") {
+		t.Errorf("Expected the restated verdict, got: %s", res.Output)
+	}
+}
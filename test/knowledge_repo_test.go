@@ -18,7 +18,7 @@ type MockProviderKnowledge struct {
 	LastSystemPrompt string
 }
 
-func (m *MockProviderKnowledge) Chat(ctx context.Context, msgs []provider.Message, defs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderKnowledge) Chat(ctx context.Context, msgs []provider.Message, defs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	if len(msgs) > 0 {
 		for _, msg := range msgs {
 			if msg.Role == "system" {
@@ -17,7 +17,7 @@ type MockProviderRedTeam struct {
 	LastSystemPrompt string
 }
 
-func (m *MockProviderRedTeam) Chat(ctx context.Context, msgs []provider.Message, defs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderRedTeam) Chat(ctx context.Context, msgs []provider.Message, defs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	// Capture the system prompt to verify the agent loaded the correct persona
 	if len(msgs) > 0 && msgs[0].Role == "system" {
 		m.LastSystemPrompt = msgs[0].Content
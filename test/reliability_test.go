@@ -17,7 +17,7 @@ type MockProviderForNudge struct {
 	toolName string
 }
 
-func (m *MockProviderForNudge) Chat(ctx context.Context, history []provider.Message, toolDefs []provider.ToolDef) (<-chan provider.StreamChunk, error) {
+func (m *MockProviderForNudge) Chat(ctx context.Context, history []provider.Message, toolDefs []provider.ToolDef, _ ...provider.ChatOption) (<-chan provider.StreamChunk, error) {
 	ch := make(chan provider.StreamChunk)
 	go func() {
 		defer close(ch)